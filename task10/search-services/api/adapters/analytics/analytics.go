@@ -0,0 +1,130 @@
+package analytics
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"yadro.com/course/api/core"
+)
+
+const (
+	queueSize     = 1000
+	batchSize     = 100
+	flushInterval = time.Second
+	flushTimeout  = 5 * time.Second
+)
+
+// Recorder пропускает поиск через себя и пишет в журнал каждый запрос:
+// время ответа с учётом всех шардов и сколько найдено всего.
+// Запись идёт в фоне пачками, поиск её не ждёт.
+type Recorder struct {
+	log      *slog.Logger
+	searcher core.Searcher
+	norm     core.Normalizer
+	store    core.QueryLog
+	queue    chan core.QueryRecord
+}
+
+func NewRecorder(log *slog.Logger, searcher core.Searcher, norm core.Normalizer, store core.QueryLog) *Recorder {
+	return &Recorder{
+		log:      log,
+		searcher: searcher,
+		norm:     norm,
+		store:    store,
+		queue:    make(chan core.QueryRecord, queueSize),
+	}
+}
+
+func (r *Recorder) Search(ctx context.Context, q core.SearchQuery) (core.SearchResult, error) {
+	return r.record(ctx, core.ModeSearch, q, r.searcher.Search)
+}
+
+func (r *Recorder) IndexSearch(ctx context.Context, q core.SearchQuery) (core.SearchResult, error) {
+	return r.record(ctx, core.ModeIndexSearch, q, r.searcher.IndexSearch)
+}
+
+type searchFunc func(context.Context, core.SearchQuery) (core.SearchResult, error)
+
+// record считает только первые страницы, иначе листание выдачи
+// выглядело бы как повторные запросы. Ошибочные запросы не пишутся.
+func (r *Recorder) record(ctx context.Context, mode string, q core.SearchQuery, search searchFunc) (core.SearchResult, error) {
+	start := time.Now()
+	res, err := search(ctx, q)
+	if err != nil || q.Offset != 0 {
+		return res, err
+	}
+
+	select {
+	case r.queue <- core.QueryRecord{
+		At:      start,
+		Mode:    mode,
+		Phrase:  q.Phrase,
+		Hits:    res.Total,
+		Latency: time.Since(start),
+	}:
+	default:
+		r.log.Warn("query analytics queue is full, query is not recorded")
+	}
+	return res, nil
+}
+
+// Run нормализует и пишет запросы из очереди до отмены ctx,
+// напоследок дописывая то, что успело накопиться
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]core.QueryRecord, 0, batchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		for i := range batch {
+			r.normalize(ctx, &batch[i])
+		}
+		if err := r.store.Record(ctx, batch); err != nil {
+			r.log.Error("failed to record queries", "count", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// читатель очереди один, поэтому len не врёт
+			for len(r.queue) > 0 {
+				batch = append(batch, <-r.queue)
+			}
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+			flush(ctx)
+			cancel()
+			return
+		case rec := <-r.queue:
+			batch = append(batch, rec)
+			if len(batch) == batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// normalize заполняет слова запроса. Порядок слов не важен, поэтому они
+// сортируются. Если нормализатор ничего не оставил, ключ - сам запрос:
+// такие запросы и показывают, где он ошибается.
+func (r *Recorder) normalize(ctx context.Context, rec *core.QueryRecord) {
+	terms, err := r.norm.Norm(ctx, rec.Phrase)
+	if err != nil {
+		r.log.Error("failed to normalize query for analytics", "error", err)
+	}
+	slices.Sort(terms)
+	rec.Terms = slices.Compact(terms)
+	rec.Query = strings.Join(rec.Terms, " ")
+	if rec.Query == "" {
+		rec.Query = strings.ToLower(strings.Join(strings.Fields(rec.Phrase), " "))
+	}
+}
@@ -0,0 +1,129 @@
+package analytics
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yadro.com/course/api/core"
+)
+
+type mockSearcher struct {
+	total int
+	err   error
+}
+
+func (m *mockSearcher) Search(ctx context.Context, q core.SearchQuery) (core.SearchResult, error) {
+	return core.SearchResult{Total: m.total}, m.err
+}
+
+func (m *mockSearcher) IndexSearch(ctx context.Context, q core.SearchQuery) (core.SearchResult, error) {
+	return core.SearchResult{Total: m.total}, m.err
+}
+
+type mockNormalizer struct {
+	normFn func(ctx context.Context, phrase string) ([]string, error)
+}
+
+func (m *mockNormalizer) Norm(ctx context.Context, phrase string) ([]string, error) {
+	return m.normFn(ctx, phrase)
+}
+
+type mockQueryLog struct {
+	core.QueryAnalytics
+	mu      sync.Mutex
+	records []core.QueryRecord
+}
+
+func (m *mockQueryLog) Record(ctx context.Context, records []core.QueryRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, records...)
+	return nil
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRecorder_RecordsFirstPages(t *testing.T) {
+	searcher := &mockSearcher{total: 3}
+	norm := &mockNormalizer{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		if phrase == "the" {
+			return nil, nil
+		}
+		return []string{"linux", "kernel", "linux"}, nil
+	}}
+	store := &mockQueryLog{}
+	r := NewRecorder(newTestLogger(), searcher, norm, store)
+	ctx := context.Background()
+
+	res, err := r.IndexSearch(ctx, core.SearchQuery{Phrase: "Kernel linux", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Total)
+	// следующая страница того же запроса не пишется
+	_, err = r.IndexSearch(ctx, core.SearchQuery{Phrase: "Kernel linux", Limit: 10, Offset: 10})
+	require.NoError(t, err)
+
+	searcher.total = 0
+	_, err = r.Search(ctx, core.SearchQuery{Phrase: "the", Limit: 10})
+	require.NoError(t, err)
+
+	// ошибочный запрос не пишется
+	searcher.err = core.ErrBadArguments
+	_, err = r.Search(ctx, core.SearchQuery{Phrase: "(", Limit: 10})
+	require.ErrorIs(t, err, core.ErrBadArguments)
+
+	// остановка дописывает очередь
+	runCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	r.Run(runCtx)
+
+	require.Len(t, store.records, 2)
+	rec := store.records[0]
+	assert.Equal(t, core.ModeIndexSearch, rec.Mode)
+	assert.Equal(t, "Kernel linux", rec.Phrase)
+	assert.Equal(t, []string{"kernel", "linux"}, rec.Terms)
+	assert.Equal(t, "kernel linux", rec.Query)
+	assert.Equal(t, 3, rec.Hits)
+	assert.False(t, rec.At.IsZero())
+
+	// нормализатор ничего не оставил - ключом служит сам запрос
+	rec = store.records[1]
+	assert.Equal(t, core.ModeSearch, rec.Mode)
+	assert.Empty(t, rec.Terms)
+	assert.Equal(t, "the", rec.Query)
+	assert.Zero(t, rec.Hits)
+}
+
+func TestRecorder_FlushesInBackground(t *testing.T) {
+	norm := &mockNormalizer{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{phrase}, nil
+	}}
+	store := &mockQueryLog{}
+	r := NewRecorder(newTestLogger(), &mockSearcher{total: 1}, norm, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	_, err := r.Search(context.Background(), core.SearchQuery{Phrase: "linux", Limit: 10})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.records) == 1
+	}, 3*flushInterval, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
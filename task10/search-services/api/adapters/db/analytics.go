@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"yadro.com/course/api/core"
+)
+
+// DB - журнал поисковых запросов в postgres. Таблицу query_log создают миграции
+// update: он владеет схемой общей базы, api своих миграций не запускает.
+type DB struct {
+	log  *slog.Logger
+	conn *sqlx.DB
+}
+
+func New(log *slog.Logger, address string) (*DB, error) {
+	db, err := sqlx.Connect("pgx", address)
+	if err != nil {
+		log.Error("connection problem", "address", address, "error", err)
+		return nil, err
+	}
+	return &DB{
+		log:  log,
+		conn: db,
+	}, nil
+}
+
+func (db *DB) Close() error {
+	return db.conn.Close()
+}
+
+type queryRow struct {
+	CreatedAt time.Time      `db:"created_at"`
+	Mode      string         `db:"mode"`
+	Phrase    string         `db:"phrase"`
+	Query     string         `db:"query"`
+	Terms     pq.StringArray `db:"terms"`
+	Hits      int            `db:"hits"`
+	LatencyMs float64        `db:"latency_ms"`
+}
+
+// Record пишет пачку запросов одной вставкой
+func (db *DB) Record(ctx context.Context, records []core.QueryRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	rows := make([]queryRow, 0, len(records))
+	for _, r := range records {
+		terms := r.Terms
+		if terms == nil {
+			terms = []string{}
+		}
+		rows = append(rows, queryRow{
+			CreatedAt: r.At,
+			Mode:      r.Mode,
+			Phrase:    r.Phrase,
+			Query:     r.Query,
+			Terms:     terms,
+			Hits:      r.Hits,
+			LatencyMs: float64(r.Latency) / float64(time.Millisecond),
+		})
+	}
+	_, err := db.conn.NamedExecContext(ctx, `INSERT INTO query_log
+		(created_at, mode, phrase, query, terms, hits, latency_ms)
+		VALUES (:created_at, :mode, :phrase, :query, :terms, :hits, :latency_ms)`, rows)
+	return err
+}
+
+type statRow struct {
+	Query        string         `db:"query"`
+	Terms        pq.StringArray `db:"terms"`
+	Example      string         `db:"example"`
+	Count        int            `db:"count"`
+	AvgHits      float64        `db:"avg_hits"`
+	AvgLatencyMs float64        `db:"avg_latency_ms"`
+	P95LatencyMs float64        `db:"p95_latency_ms"`
+	LastSeen     time.Time      `db:"last_seen"`
+}
+
+// queryStats группирует запросы окна по ключу, самые частые первыми
+const queryStats = `SELECT query, terms, min(phrase) AS example, count(*) AS count,
+	avg(hits) AS avg_hits, avg(latency_ms) AS avg_latency_ms,
+	percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms) AS p95_latency_ms,
+	max(created_at) AS last_seen
+	FROM query_log
+	WHERE created_at >= $1 AND created_at < $2 AND ($3 = '' OR mode = $3) %s
+	GROUP BY query, terms
+	ORDER BY count DESC, last_seen DESC
+	LIMIT $4`
+
+func (db *DB) TopQueries(ctx context.Context, filter core.AnalyticsFilter) ([]core.QueryStat, error) {
+	return db.queryStats(ctx, filter, "")
+}
+
+// ZeroResults - запросы, на которые ничего не нашлось: кандидаты в синонимы
+// и места, где ошибается нормализатор
+func (db *DB) ZeroResults(ctx context.Context, filter core.AnalyticsFilter) ([]core.QueryStat, error) {
+	return db.queryStats(ctx, filter, "AND hits = 0")
+}
+
+func (db *DB) queryStats(ctx context.Context, filter core.AnalyticsFilter, where string) ([]core.QueryStat, error) {
+	var rows []statRow
+	query := fmt.Sprintf(queryStats, where)
+	if err := db.conn.SelectContext(ctx, &rows, query, filter.From, filter.To, filter.Mode, filter.Limit); err != nil {
+		return nil, err
+	}
+	stats := make([]core.QueryStat, 0, len(rows))
+	for _, r := range rows {
+		stats = append(stats, core.QueryStat{
+			Query:      r.Query,
+			Terms:      r.Terms,
+			Example:    r.Example,
+			Count:      r.Count,
+			AvgHits:    r.AvgHits,
+			AvgLatency: msDuration(r.AvgLatencyMs),
+			P95Latency: msDuration(r.P95LatencyMs),
+			LastSeen:   r.LastSeen,
+		})
+	}
+	return stats, nil
+}
+
+func msDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+	"yadro.com/course/api/core"
+)
+
+func newMockDB(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	// под именем pgx sqlx подставляет в именованные запросы $1, $2, как в работе
+	return &DB{
+		log:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		conn: sqlx.NewDb(conn, "pgx"),
+	}, mock
+}
+
+func TestDBRecord(t *testing.T) {
+	storage, mock := newMockDB(t)
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectExec(`INSERT INTO query_log \(created_at, mode, phrase, query, terms, hits, latency_ms\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\),\(\$8,`).
+		WithArgs(at, "isearch", "Linux", "linux", "{\"linux\"}", 3, 1.5,
+			at, "search", "the", "the", "{}", 0, 2.0).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := storage.Record(context.Background(), []core.QueryRecord{
+		{At: at, Mode: "isearch", Phrase: "Linux", Query: "linux", Terms: []string{"linux"}, Hits: 3, Latency: 1500 * time.Microsecond},
+		{At: at, Mode: "search", Phrase: "the", Query: "the", Latency: 2 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	// пустую пачку писать незачем
+	require.NoError(t, storage.Record(context.Background(), nil))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBQueryStats(t *testing.T) {
+	storage, mock := newMockDB(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	filter := core.AnalyticsFilter{From: from, To: to, Mode: "isearch", Limit: 5}
+
+	columns := []string{"query", "terms", "example", "count", "avg_hits", "avg_latency_ms", "p95_latency_ms", "last_seen"}
+	mock.ExpectQuery(`SELECT query, terms, .* FROM query_log WHERE created_at >= \$1 AND created_at < \$2 AND \(\$3 = '' OR mode = \$3\) GROUP BY`).
+		WithArgs(from, to, "isearch", 5).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("linux", "{linux}", "Linux", 7, 2.5, 1.5, 4.0, to))
+
+	stats, err := storage.TopQueries(context.Background(), filter)
+	require.NoError(t, err)
+	assert.Equal(t, []core.QueryStat{{
+		Query:      "linux",
+		Terms:      []string{"linux"},
+		Example:    "Linux",
+		Count:      7,
+		AvgHits:    2.5,
+		AvgLatency: 1500 * time.Microsecond,
+		P95Latency: 4 * time.Millisecond,
+		LastSeen:   to,
+	}}, stats)
+
+	mock.ExpectQuery(`FROM query_log WHERE .* AND hits = 0 GROUP BY`).
+		WithArgs(from, to, "isearch", 5).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	stats, err = storage.ZeroResults(context.Background(), filter)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,11 +1,17 @@
 package rest
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"html"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"yadro.com/course/api/core"
 )
@@ -162,23 +168,354 @@ func NewDropHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
 	}
 }
 
+// SearchComic - карточка комикса в выдаче, published в виде YYYY-MM-DD
 type SearchComic struct {
-	ID  int    `json:"id"`
-	URL string `json:"url"`
+	ID         int            `json:"id"`
+	URL        string         `json:"url"`
+	Score      float64        `json:"score"`
+	SafeTitle  string         `json:"safe_title,omitempty"`
+	Title      string         `json:"title,omitempty"`
+	Alt        string         `json:"alt,omitempty"`
+	Transcript string         `json:"transcript,omitempty"`
+	Published  string         `json:"published,omitempty"`
+	Link       string         `json:"link,omitempty"`
+	News       string         `json:"news,omitempty"`
+	Snippet    *SearchSnippet `json:"snippet,omitempty"`
+	Explain    *SearchExplain `json:"explain,omitempty"`
 }
 
+// SearchExplain - разбор оценки комикса: слова запроса после нормализации,
+// вклад каждого совпавшего слова, итоговая оценка и признак, по которому
+// комикс оказался выше следующего (score, matches, ratio или id)
+type SearchExplain struct {
+	Terms     []string          `json:"terms"`
+	Matched   []SearchTermScore `json:"matched"`
+	Proximity float64           `json:"proximity"`
+	Matches   int               `json:"matches"`
+	Ratio     float64           `json:"ratio"`
+	Score     float64           `json:"score"`
+	TieBreak  string            `json:"tie_break,omitempty"`
+}
+
+type SearchTermScore struct {
+	Term   string  `json:"term"`
+	Field  string  `json:"field,omitempty"`
+	Dist   int     `json:"dist"`
+	Weight float64 `json:"weight"`
+	TF     int     `json:"tf"`
+	IDF    float64 `json:"idf"`
+	Score  float64 `json:"score"`
+}
+
+func newSearchExplain(e *core.Explanation) *SearchExplain {
+	if e == nil {
+		return nil
+	}
+	res := &SearchExplain{
+		Terms:     e.Terms,
+		Matched:   make([]SearchTermScore, 0, len(e.Matched)),
+		Proximity: e.Proximity,
+		Matches:   e.Matches,
+		Ratio:     e.Ratio,
+		Score:     e.Score,
+		TieBreak:  e.TieBreak,
+	}
+	if res.Terms == nil {
+		res.Terms = []string{}
+	}
+	for _, t := range e.Matched {
+		res.Matched = append(res.Matched, SearchTermScore(t))
+	}
+	return res
+}
+
+// SearchSnippet - кусок текста, где нашлись слова запроса. Highlights - байтовые
+// отрезки [start, end) найденных слов в text, в marked они обёрнуты в <em>,
+// а остальной текст экранирован для вставки в HTML.
+type SearchSnippet struct {
+	Field      string   `json:"field"`
+	Text       string   `json:"text"`
+	Highlights [][2]int `json:"highlights"`
+	Marked     string   `json:"marked"`
+}
+
+func newSearchSnippet(s *core.Snippet) *SearchSnippet {
+	if s == nil {
+		return nil
+	}
+	res := &SearchSnippet{
+		Field:      s.Field,
+		Text:       s.Text,
+		Highlights: make([][2]int, 0, len(s.Highlights)),
+	}
+	var b strings.Builder
+	last := 0
+	for _, h := range s.Highlights {
+		if h.Start < last || h.End < h.Start || h.End > len(s.Text) {
+			continue
+		}
+		res.Highlights = append(res.Highlights, [2]int{h.Start, h.End})
+		b.WriteString(html.EscapeString(s.Text[last:h.Start]))
+		b.WriteString("<em>" + html.EscapeString(s.Text[h.Start:h.End]) + "</em>")
+		last = h.End
+	}
+	b.WriteString(html.EscapeString(s.Text[last:]))
+	res.Marked = b.String()
+	return res
+}
+
+// SearchShards - сколько шардов поиска опрошено и сколько не ответило
+type SearchShards struct {
+	Total  int `json:"total"`
+	Failed int `json:"failed"`
+}
+
+// SearchResponse - страница выдачи. Total - сколько всего нашлось,
+// курсоры можно передать в ?cursor= для соседних страниц. Курсор помнит
+// поколение индекса: если индекс с тех пор изменился, страницы сдвинулись бы,
+// поэтому такой курсор отклоняется с 409 и листать надо с первой страницы.
+// Полнотекстовый поиск postgres поколений не знает, там курсор - просто смещение.
 type SearchResponse struct {
-	Comics []SearchComic `json:"comics"`
-	Total  int           `json:"total"`
+	Comics     []SearchComic `json:"comics"`
+	Total      int           `json:"total"`
+	Page       int           `json:"page"`
+	Pages      int           `json:"pages"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+	Shards     *SearchShards `json:"shards,omitempty"`
+}
+
+func newSearchComic(cmt core.Comics) SearchComic {
+	comic := SearchComic{
+		ID:         cmt.ID,
+		URL:        cmt.URL,
+		Score:      cmt.Score,
+		SafeTitle:  cmt.SafeTitle,
+		Title:      cmt.Title,
+		Alt:        cmt.Alt,
+		Transcript: cmt.Transcript,
+		Link:       cmt.Link,
+		News:       cmt.News,
+		Snippet:    newSearchSnippet(cmt.Snippet),
+		Explain:    newSearchExplain(cmt.Explain),
+	}
+	if !cmt.Published.IsZero() {
+		comic.Published = cmt.Published.Format(time.DateOnly)
+	}
+	return comic
+}
+
+func newSearchResponse(query core.SearchQuery, res core.SearchResult) SearchResponse {
+	reply := SearchResponse{
+		Comics: make([]SearchComic, 0, len(res.Comics)),
+		Total:  res.Total,
+		Page:   query.Offset/query.Limit + 1,
+		Pages:  (res.Total + query.Limit - 1) / query.Limit,
+	}
+	for _, cmt := range res.Comics {
+		reply.Comics = append(reply.Comics, newSearchComic(cmt))
+	}
+	if res.Shards > 0 {
+		reply.Shards = &SearchShards{Total: res.Shards, Failed: res.FailedShards}
+	}
+
+	if next := query.Offset + query.Limit; next < res.Total {
+		reply.NextCursor = encodeCursor(next, res.Generation)
+	}
+	if query.Offset > 0 {
+		reply.PrevCursor = encodeCursor(max(query.Offset-query.Limit, 0), res.Generation)
+	}
+	return reply
+}
+
+// курсор - закодированные смещение от начала выдачи и поколение индекса
+// через точку. Курсоры без поколения из прежних ответов тоже принимаются.
+func encodeCursor(offset int, generation uint64) string {
+	raw := strconv.Itoa(offset) + "." + strconv.FormatUint(generation, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (int, uint64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, err
+	}
+	o, g, hasGeneration := strings.Cut(string(raw), ".")
+	offset, err := strconv.Atoi(o)
+	if err != nil || offset < 0 {
+		return 0, 0, errors.New("bad offset")
+	}
+	var generation uint64
+	if hasGeneration {
+		if generation, err = strconv.ParseUint(g, 10, 64); err != nil {
+			return 0, 0, errors.New("bad generation")
+		}
+	}
+	return offset, generation, nil
+}
+
+// errStaleCursor - индекс изменился после выдачи курсора
+var errStaleCursor = errors.New("stale cursor: search results have changed, start from the first page")
+
+// checkCursor сверяет поколение из курсора с поколением выдачи, 0 - не проверять
+func checkCursor(generation uint64, res core.SearchResult) error {
+	if generation != 0 && res.Generation != 0 && generation != res.Generation {
+		return errStaleCursor
+	}
+	return nil
+}
+
+// WantsExplain - запрошен ли разбор оценки. Разбор доступен только
+// администратору, поэтому такие запросы проходят через middleware.Auth.
+func WantsExplain(r *http.Request) bool {
+	explain, err := strconv.ParseBool(r.URL.Query().Get("explain"))
+	return err == nil && explain
+}
+
+// parseSearchQuery читает phrase, limit, fuzziness (число опечаток или auto),
+// explain и страницу: номер ?page= с единицы или курсор из предыдущего ответа.
+// Вторым значением возвращается поколение индекса из курсора, 0 - без курсора.
+func parseSearchQuery(r *http.Request) (core.SearchQuery, uint64, error) {
+	const defaultLimit = 10
+	query := core.SearchQuery{
+		Phrase: r.URL.Query().Get("phrase"),
+		Limit:  defaultLimit,
+	}
+	var generation uint64
+	if query.Phrase == "" {
+		return query, 0, errors.New("empty phrase")
+	}
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		val, err := strconv.Atoi(l)
+		if err != nil || val <= 0 {
+			return query, 0, errors.New("invalid limit")
+		}
+		query.Limit = val
+	}
+
+	page, cursor := r.URL.Query().Get("page"), r.URL.Query().Get("cursor")
+	switch {
+	case page != "" && cursor != "":
+		return query, 0, errors.New("page and cursor are mutually exclusive")
+	case page != "":
+		val, err := strconv.Atoi(page)
+		if err != nil || val <= 0 {
+			return query, 0, errors.New("invalid page")
+		}
+		query.Offset = (val - 1) * query.Limit
+	case cursor != "":
+		offset, gen, err := decodeCursor(cursor)
+		if err != nil {
+			return query, 0, errors.New("invalid cursor")
+		}
+		query.Offset, generation = offset, gen
+	}
+
+	switch f := r.URL.Query().Get("fuzziness"); f {
+	case "":
+	case "auto":
+		query.Fuzziness = core.FuzzinessAuto
+	default:
+		val, err := strconv.Atoi(f)
+		if err != nil || val < 0 {
+			return query, 0, errors.New("invalid fuzziness")
+		}
+		query.Fuzziness = val
+	}
+
+	if e := r.URL.Query().Get("explain"); e != "" {
+		val, err := strconv.ParseBool(e)
+		if err != nil {
+			return query, 0, errors.New("invalid explain")
+		}
+		query.Explain = val
+	}
+	return query, generation, nil
 }
 
 func NewSearchHandler(log *slog.Logger, search core.Searcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		phrase := r.URL.Query().Get("phrase")
-		if phrase == "" {
-			log.Error("missing phrase")
-			http.Error(w, "empty phrase", http.StatusBadRequest)
+		query, generation, err := parseSearchQuery(r)
+		if err != nil {
+			log.Error("bad search request", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := search.Search(r.Context(), query)
+		if err != nil {
+			if errors.Is(err, core.ErrBadArguments) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Error("search failed", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := checkCursor(generation, res); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		reply := newSearchResponse(query, res)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+func NewIndexSearchHandler(log *slog.Logger, search core.Searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		query, generation, err := parseSearchQuery(r)
+		if err != nil {
+			log.Error("bad search request", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := search.IndexSearch(r.Context(), query)
+		if err != nil {
+			if errors.Is(err, core.ErrBadArguments) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Error("index search failed", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := checkCursor(generation, res); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		reply := newSearchResponse(query, res)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+// SimilarResponse - похожие комиксы со всех шардов, score - похожесть от 0 до 1.
+// Каждый шард считает её по своим частотам слов, между шардами оценки приближённые.
+type SimilarResponse struct {
+	Comics []SearchComic `json:"comics"`
+}
+
+func NewSimilarHandler(log *slog.Logger, finder core.SimilarFinder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid id", http.StatusBadRequest)
 			return
 		}
 
@@ -194,27 +531,25 @@ func NewSearchHandler(log *slog.Logger, search core.Searcher) http.HandlerFunc {
 			limit = val
 		}
 
-		comics, err := search.Search(r.Context(), phrase, limit)
+		comics, err := finder.Similar(r.Context(), id, limit)
 		if err != nil {
-			if errors.Is(err, core.ErrBadArguments) {
+			switch {
+			case errors.Is(err, core.ErrBadArguments):
 				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
+			case errors.Is(err, core.ErrNotFound):
+				http.Error(w, "comic not found", http.StatusNotFound)
+			default:
+				log.Error("similar failed", "error", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
 			}
-			log.Error("search failed", "error", err)
-			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
 
-		reply := SearchResponse{
+		reply := SimilarResponse{
 			Comics: make([]SearchComic, 0, len(comics)),
-			Total:  len(comics),
 		}
-
 		for _, cmt := range comics {
-			reply.Comics = append(reply.Comics, SearchComic{
-				ID:  cmt.ID,
-				URL: cmt.URL,
-			})
+			reply.Comics = append(reply.Comics, newSearchComic(cmt))
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -224,13 +559,21 @@ func NewSearchHandler(log *slog.Logger, search core.Searcher) http.HandlerFunc {
 	}
 }
 
-func NewIndexSearchHandler(log *slog.Logger, search core.Searcher) http.HandlerFunc {
+type SuggestItem struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+type SuggestResponse struct {
+	Suggestions []SuggestItem `json:"suggestions"`
+}
+
+func NewSuggestHandler(log *slog.Logger, suggester core.Suggester) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		phrase := r.URL.Query().Get("phrase")
-		if phrase == "" {
-			log.Error("missing phrase")
-			http.Error(w, "empty phrase", http.StatusBadRequest)
+		prefix := r.URL.Query().Get("prefix")
+		if prefix == "" {
+			http.Error(w, "empty prefix", http.StatusBadRequest)
 			return
 		}
 
@@ -246,26 +589,89 @@ func NewIndexSearchHandler(log *slog.Logger, search core.Searcher) http.HandlerF
 			limit = val
 		}
 
-		comics, err := search.IndexSearch(r.Context(), phrase, limit)
+		suggestions, err := suggester.Suggest(r.Context(), prefix, limit)
 		if err != nil {
 			if errors.Is(err, core.ErrBadArguments) {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
-			log.Error("index search failed", "error", err)
+			log.Error("suggest failed", "error", err)
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
 
-		reply := SearchResponse{
-			Comics: make([]SearchComic, 0, len(comics)),
-			Total:  len(comics),
+		reply := SuggestResponse{
+			Suggestions: make([]SuggestItem, 0, len(suggestions)),
+		}
+		for _, sg := range suggestions {
+			reply.Suggestions = append(reply.Suggestions, SuggestItem{
+				Word:  sg.Word,
+				Count: sg.Count,
+			})
 		}
 
-		for _, cmt := range comics {
-			reply.Comics = append(reply.Comics, SearchComic{
-				ID:  cmt.ID,
-				URL: cmt.URL,
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+// SearchStatsResponse - состояние индексов по шардам поиска, comics - сумма по ответившим
+type SearchStatsResponse struct {
+	Comics int                `json:"comics"`
+	Shards []SearchShardStats `json:"shards"`
+}
+
+type SearchShardStats struct {
+	Address           string           `json:"address"`
+	Comics            int              `json:"comics"`
+	Words             int              `json:"words"`
+	Postings          int              `json:"postings"`
+	Generation        uint64           `json:"generation"`
+	LastRebuild       string           `json:"last_rebuild,omitempty"`
+	RebuildDurationMs int64            `json:"rebuild_duration_ms"`
+	LastError         string           `json:"last_error,omitempty"`
+	LastErrorAt       string           `json:"last_error_at,omitempty"`
+	Cache             SearchCacheStats `json:"cache"`
+	// шард не ответил, остальные поля пустые
+	Error string `json:"error,omitempty"`
+}
+
+type SearchCacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+func NewSearchStatsHandler(log *slog.Logger, provider core.SearchStatsProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := provider.SearchStats(r.Context())
+		if err != nil {
+			log.Error("search stats failed", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		reply := SearchStatsResponse{Shards: make([]SearchShardStats, 0, len(stats))}
+		for _, st := range stats {
+			reply.Comics += st.Comics
+			reply.Shards = append(reply.Shards, SearchShardStats{
+				Address:           st.Address,
+				Comics:            st.Comics,
+				Words:             st.Words,
+				Postings:          st.Postings,
+				Generation:        st.Generation,
+				LastRebuild:       formatTime(st.LastRebuild),
+				RebuildDurationMs: st.RebuildDuration.Milliseconds(),
+				LastError:         st.LastError,
+				LastErrorAt:       formatTime(st.LastErrorAt),
+				Cache: SearchCacheStats{
+					Hits:    st.CacheHits,
+					Misses:  st.CacheMisses,
+					Entries: st.CacheEntries,
+				},
+				Error: st.Error,
 			})
 		}
 
@@ -275,3 +681,186 @@ func NewIndexSearchHandler(log *slog.Logger, search core.Searcher) http.HandlerF
 		}
 	}
 }
+
+// maxSynonymsSize - предел тела PUT словаря синонимов
+const maxSynonymsSize = 1 << 20
+
+func NewGetSynonymsHandler(log *slog.Logger, editor core.SynonymEditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		text, err := editor.Synonyms(r.Context())
+		if err != nil {
+			log.Error("get synonyms failed", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if _, err := w.Write([]byte(text)); err != nil {
+			log.Error("cannot write synonyms", "error", err)
+		}
+	}
+}
+
+// NewSetSynonymsHandler заменяет словарь целиком текстом из тела запроса
+func NewSetSynonymsHandler(log *slog.Logger, editor core.SynonymEditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		defer func() {
+			if err := r.Body.Close(); err != nil {
+				log.Error("cannot close request body", "error", err)
+			}
+		}()
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSynonymsSize))
+		if err != nil {
+			http.Error(w, "synonyms are too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if err := editor.SetSynonyms(r.Context(), string(body)); err != nil {
+			log.Error("set synonyms failed", "error", err)
+			if errors.Is(err, core.ErrBadArguments) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// formatTime - RFC 3339, нулевое время - пустая строка
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// QueryStatsResponse - сводка запросов за окно [from, to)
+type QueryStatsResponse struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Queries []QueryStatItem `json:"queries"`
+}
+
+type QueryStatItem struct {
+	Query        string   `json:"query"`
+	Terms        []string `json:"terms"`
+	Example      string   `json:"example"`
+	Count        int      `json:"count"`
+	AvgHits      float64  `json:"avg_hits"`
+	AvgLatencyMs float64  `json:"avg_latency_ms"`
+	P95LatencyMs float64  `json:"p95_latency_ms"`
+	LastSeen     string   `json:"last_seen"`
+}
+
+const (
+	defaultAnalyticsWindow = 24 * time.Hour
+	defaultAnalyticsLimit  = 20
+	maxAnalyticsLimit      = 1000
+)
+
+// parseAnalyticsFilter читает окно из from и to в RFC 3339 или из window
+// (длительность до текущего момента, например 1h), по умолчанию - последние сутки
+func parseAnalyticsFilter(r *http.Request, now time.Time) (core.AnalyticsFilter, error) {
+	q := r.URL.Query()
+	filter := core.AnalyticsFilter{To: now, Mode: q.Get("mode"), Limit: defaultAnalyticsLimit}
+
+	switch filter.Mode {
+	case "", core.ModeSearch, core.ModeIndexSearch:
+	default:
+		return filter, errors.New("invalid mode")
+	}
+
+	if to := q.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, errors.New("invalid to")
+		}
+		filter.To = t
+	}
+	filter.From = filter.To.Add(-defaultAnalyticsWindow)
+	if window := q.Get("window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			return filter, errors.New("invalid window")
+		}
+		filter.From = filter.To.Add(-d)
+	}
+	if from := q.Get("from"); from != "" {
+		if q.Has("window") {
+			return filter, errors.New("from and window are mutually exclusive")
+		}
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, errors.New("invalid from")
+		}
+		filter.From = t
+	}
+	if !filter.From.Before(filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+
+	if l := q.Get("limit"); l != "" {
+		val, err := strconv.Atoi(l)
+		if err != nil || val <= 0 || val > maxAnalyticsLimit {
+			return filter, errors.New("invalid limit")
+		}
+		filter.Limit = val
+	}
+	return filter, nil
+}
+
+type queryStatsFunc func(context.Context, core.AnalyticsFilter) ([]core.QueryStat, error)
+
+func newQueryStatsHandler(log *slog.Logger, stats queryStatsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseAnalyticsFilter(r, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		queries, err := stats(r.Context(), filter)
+		if err != nil {
+			log.Error("query analytics failed", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		reply := QueryStatsResponse{
+			From:    formatTime(filter.From),
+			To:      formatTime(filter.To),
+			Queries: make([]QueryStatItem, 0, len(queries)),
+		}
+		for _, st := range queries {
+			reply.Queries = append(reply.Queries, QueryStatItem{
+				Query:        st.Query,
+				Terms:        st.Terms,
+				Example:      st.Example,
+				Count:        st.Count,
+				AvgHits:      st.AvgHits,
+				AvgLatencyMs: float64(st.AvgLatency) / float64(time.Millisecond),
+				P95LatencyMs: float64(st.P95Latency) / float64(time.Millisecond),
+				LastSeen:     formatTime(st.LastSeen),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+// NewTopQueriesHandler - самые частые запросы за окно
+func NewTopQueriesHandler(log *slog.Logger, analytics core.QueryAnalytics) http.HandlerFunc {
+	return newQueryStatsHandler(log, analytics.TopQueries)
+}
+
+// NewZeroResultsHandler - частые запросы, на которые ничего не нашлось
+func NewZeroResultsHandler(log *slog.Logger, analytics core.QueryAnalytics) http.HandlerFunc {
+	return newQueryStatsHandler(log, analytics.ZeroResults)
+}
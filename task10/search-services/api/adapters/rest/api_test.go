@@ -5,14 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"yadro.com/course/api/adapters/rest/middleware"
 	"yadro.com/course/api/core"
 )
 
@@ -77,22 +80,22 @@ func (m *mockUpdater) Drop(ctx context.Context) error {
 }
 
 type mockSearcher struct {
-	searchFn      func(ctx context.Context, phrase string, limit int) ([]core.Comics, error)
-	indexSearchFn func(ctx context.Context, phrase string, limit int) ([]core.Comics, error)
+	searchFn      func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error)
+	indexSearchFn func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error)
 }
 
-func (m *mockSearcher) Search(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
+func (m *mockSearcher) Search(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
 	if m.searchFn == nil {
-		return nil, nil
+		return core.SearchResult{}, nil
 	}
-	return m.searchFn(ctx, phrase, limit)
+	return m.searchFn(ctx, query)
 }
 
-func (m *mockSearcher) IndexSearch(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
+func (m *mockSearcher) IndexSearch(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
 	if m.indexSearchFn == nil {
-		return nil, nil
+		return core.SearchResult{}, nil
 	}
-	return m.indexSearchFn(ctx, phrase, limit)
+	return m.indexSearchFn(ctx, query)
 }
 
 func TestNewPingHandler_MixedReplies(t *testing.T) {
@@ -372,8 +375,8 @@ func TestNewSearchHandler_InvalidLimit(t *testing.T) {
 func TestNewSearchHandler_BadArgumentsFromService(t *testing.T) {
 	log := newTestLogger()
 	searcher := &mockSearcher{
-		searchFn: func(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
-			return nil, core.ErrBadArguments
+		searchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{}, core.ErrBadArguments
 		},
 	}
 
@@ -392,8 +395,8 @@ func TestNewSearchHandler_InternalError(t *testing.T) {
 	log := newTestLogger()
 	expErr := errors.New("search failed")
 	searcher := &mockSearcher{
-		searchFn: func(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
-			return nil, expErr
+		searchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{}, expErr
 		},
 	}
 
@@ -435,3 +438,562 @@ func TestNewIndexSearchHandler_InvalidLimit(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
 }
+
+func TestNewIndexSearchHandler_QueryError(t *testing.T) {
+	log := newTestLogger()
+	searcher := &mockSearcher{
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{}, fmt.Errorf("%w: bad query at position 7: unexpected ')'", core.ErrBadArguments)
+		},
+	}
+
+	h := NewIndexSearchHandler(log, searcher)
+
+	req := httptest.NewRequest(http.MethodGet, "/indexsearch?phrase=linux+)", nil)
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, req)
+
+	// клиент должен увидеть, где в запросе ошибка
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "position 7")
+}
+
+func TestNewIndexSearchHandler_Fuzziness(t *testing.T) {
+	log := newTestLogger()
+
+	testCases := []struct {
+		param     string
+		code      int
+		fuzziness int
+	}{
+		{"", http.StatusOK, 0},
+		{"&fuzziness=2", http.StatusOK, 2},
+		{"&fuzziness=auto", http.StatusOK, core.FuzzinessAuto},
+		{"&fuzziness=-1", http.StatusBadRequest, 0},
+		{"&fuzziness=many", http.StatusBadRequest, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.param, func(t *testing.T) {
+			var got core.SearchQuery
+			searcher := &mockSearcher{
+				indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+					got = query
+					return core.SearchResult{}, nil
+				},
+			}
+			h := NewIndexSearchHandler(log, searcher)
+
+			req := httptest.NewRequest(http.MethodGet, "/indexsearch?phrase=raptr"+tc.param, nil)
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.code, rr.Code)
+			if tc.code == http.StatusOK {
+				assert.Equal(t, core.SearchQuery{Phrase: "raptr", Limit: 10, Fuzziness: tc.fuzziness}, got)
+			}
+		})
+	}
+}
+
+type mockSuggester struct {
+	suggestFn func(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error)
+}
+
+func (m *mockSuggester) Suggest(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error) {
+	return m.suggestFn(ctx, prefix, limit)
+}
+
+func TestNewSuggestHandler_Success(t *testing.T) {
+	log := newTestLogger()
+	suggester := &mockSuggester{
+		suggestFn: func(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error) {
+			assert.Equal(t, "rap", prefix)
+			assert.Equal(t, 10, limit)
+			return []core.Suggestion{{Word: "raptor", Count: 7}}, nil
+		},
+	}
+
+	h := NewSuggestHandler(log, suggester)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/suggest?prefix=rap", nil)
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var resp SuggestResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []SuggestItem{{Word: "raptor", Count: 7}}, resp.Suggestions)
+}
+
+func TestNewSuggestHandler_BadRequest(t *testing.T) {
+	log := newTestLogger()
+	suggester := &mockSuggester{}
+
+	h := NewSuggestHandler(log, suggester)
+
+	for _, url := range []string{"/api/suggest", "/api/suggest?prefix=rap&limit=0"} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rr := httptest.NewRecorder()
+
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, url)
+	}
+}
+
+func TestNewIndexSearchHandler_Pagination(t *testing.T) {
+	log := newTestLogger()
+
+	var got core.SearchQuery
+	searcher := &mockSearcher{
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			got = query
+			return core.SearchResult{
+				Comics: []core.Comics{{ID: 21, URL: "u21"}, {ID: 22, URL: "u22"}},
+				Total:  45,
+			}, nil
+		},
+	}
+	h := NewIndexSearchHandler(log, searcher)
+
+	search := func(params string) SearchResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/isearch?phrase=foo&limit=10"+params, nil)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp SearchResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := search("&page=3")
+	assert.Equal(t, 20, got.Offset)
+	assert.Equal(t, 45, resp.Total) // всего, а не на странице
+	assert.Equal(t, 3, resp.Page)
+	assert.Equal(t, 5, resp.Pages)
+	require.NotEmpty(t, resp.NextCursor)
+	require.NotEmpty(t, resp.PrevCursor)
+
+	// курсоры ведут на соседние страницы
+	next := search("&cursor=" + resp.NextCursor)
+	assert.Equal(t, 30, got.Offset)
+	assert.Equal(t, 4, next.Page)
+
+	search("&cursor=" + resp.PrevCursor)
+	assert.Equal(t, 10, got.Offset)
+
+	// первая страница - без предыдущей
+	first := search("")
+	assert.Equal(t, 0, got.Offset)
+	assert.Empty(t, first.PrevCursor)
+}
+
+func TestNewIndexSearchHandler_BadPagination(t *testing.T) {
+	log := newTestLogger()
+	h := NewIndexSearchHandler(log, &mockSearcher{})
+
+	for _, params := range []string{"&page=0", "&page=x", "&cursor=!!!", "&page=2&cursor=MTA"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/isearch?phrase=foo"+params, nil)
+		rr := httptest.NewRecorder()
+
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, params)
+	}
+}
+
+func TestNewIndexSearchHandler_PartialShards(t *testing.T) {
+	log := newTestLogger()
+	searcher := &mockSearcher{
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{
+				Comics:       []core.Comics{{ID: 7, URL: "u7", Score: 2.5}},
+				Total:        1,
+				Shards:       3,
+				FailedShards: 1,
+			}, nil
+		},
+	}
+	h := NewIndexSearchHandler(log, searcher)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/isearch?phrase=foo", nil)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp SearchResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []SearchComic{{ID: 7, URL: "u7", Score: 2.5}}, resp.Comics)
+	// клиент видит, что выдача собрана не со всех шардов
+	assert.Equal(t, &SearchShards{Total: 3, Failed: 1}, resp.Shards)
+}
+
+func TestNewSearchSnippet(t *testing.T) {
+	s := newSearchSnippet(&core.Snippet{
+		Field:      "alt",
+		Text:       "<b>Bobby</b> Tables",
+		Highlights: []core.Span{{Start: 3, End: 8}, {Start: 13, End: 19}},
+	})
+	require.NotNil(t, s)
+	assert.Equal(t, [][2]int{{3, 8}, {13, 19}}, s.Highlights)
+	// разметка из самого текста экранируется, найденные слова - в <em>
+	assert.Equal(t, "&lt;b&gt;<em>Bobby</em>&lt;/b&gt; <em>Tables</em>", s.Marked)
+
+	assert.Nil(t, newSearchSnippet(nil))
+}
+
+type mockSimilarFinder struct {
+	similarFn func(ctx context.Context, id, limit int) ([]core.Comics, error)
+}
+
+func (m *mockSimilarFinder) Similar(ctx context.Context, id, limit int) ([]core.Comics, error) {
+	return m.similarFn(ctx, id, limit)
+}
+
+func TestNewSimilarHandler(t *testing.T) {
+	log := newTestLogger()
+	finder := &mockSimilarFinder{
+		similarFn: func(ctx context.Context, id, limit int) ([]core.Comics, error) {
+			if id == 404 {
+				return nil, core.ErrNotFound
+			}
+			assert.Equal(t, 3, limit)
+			return []core.Comics{{ID: 8, URL: "u8", Score: 0.75}}, nil
+		},
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/comics/{id}/similar", NewSimilarHandler(log, finder))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, url, nil))
+		return rr
+	}
+
+	rr := get("/api/comics/42/similar?limit=3")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp SimilarResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []SearchComic{{ID: 8, URL: "u8", Score: 0.75}}, resp.Comics)
+
+	assert.Equal(t, http.StatusNotFound, get("/api/comics/404/similar?limit=3").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/comics/abc/similar").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/comics/42/similar?limit=0").Code)
+}
+
+type mockVerifier struct{}
+
+func (mockVerifier) Verify(token string) error {
+	if token != "admin" {
+		return errors.New("bad token")
+	}
+	return nil
+}
+
+func TestNewIndexSearchHandler_Explain(t *testing.T) {
+	log := newTestLogger()
+	var got core.SearchQuery
+	searcher := &mockSearcher{
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			got = query
+			res := core.SearchResult{Comics: []core.Comics{{ID: 1, URL: "u1", Score: 2}}, Total: 1}
+			if query.Explain {
+				res.Comics[0].Explain = &core.Explanation{
+					Terms:     []string{"foo"},
+					Matched:   []core.TermScore{{Term: "foo", Weight: 1, TF: 1, IDF: 2, Score: 2}},
+					Proximity: 1,
+					Matches:   1,
+					Ratio:     1,
+					Score:     2,
+				}
+			}
+			return res, nil
+		},
+	}
+	// как в main: разбор только с токеном администратора
+	h := middleware.AuthIf(NewIndexSearchHandler(log, searcher), mockVerifier{}, WantsExplain)
+
+	testCases := []struct {
+		name    string
+		params  string
+		token   string
+		code    int
+		explain bool
+	}{
+		{"without explain", "", "", http.StatusOK, false},
+		{"explain=false", "&explain=false", "", http.StatusOK, false},
+		{"no token", "&explain=true", "", http.StatusUnauthorized, false},
+		{"bad token", "&explain=true", "user", http.StatusUnauthorized, false},
+		{"admin", "&explain=true", "admin", http.StatusOK, true},
+		{"invalid", "&explain=maybe", "", http.StatusBadRequest, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got = core.SearchQuery{}
+			req := httptest.NewRequest(http.MethodGet, "/api/isearch?phrase=foo"+tc.params, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Token "+tc.token)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.code, rr.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tc.explain, got.Explain)
+
+			var resp SearchResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Len(t, resp.Comics, 1)
+			if !tc.explain {
+				assert.Nil(t, resp.Comics[0].Explain)
+				return
+			}
+			assert.Equal(t, &SearchExplain{
+				Terms:     []string{"foo"},
+				Matched:   []SearchTermScore{{Term: "foo", Weight: 1, TF: 1, IDF: 2, Score: 2}},
+				Proximity: 1,
+				Matches:   1,
+				Ratio:     1,
+				Score:     2,
+			}, resp.Comics[0].Explain)
+		})
+	}
+}
+
+type mockSearchStatsProvider struct {
+	statsFn func(ctx context.Context) ([]core.SearchStats, error)
+}
+
+func (m *mockSearchStatsProvider) SearchStats(ctx context.Context) ([]core.SearchStats, error) {
+	return m.statsFn(ctx)
+}
+
+func TestNewSearchStatsHandler(t *testing.T) {
+	log := newTestLogger()
+	provider := &mockSearchStatsProvider{
+		statsFn: func(ctx context.Context) ([]core.SearchStats, error) {
+			return []core.SearchStats{
+				{
+					Address:         "search-1:83",
+					Comics:          3,
+					Words:           10,
+					Postings:        12,
+					Generation:      2,
+					LastRebuild:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+					RebuildDuration: 250 * time.Millisecond,
+					CacheHits:       1,
+				},
+				{Address: "search-2:83", Error: "unavailable"},
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	NewSearchStatsHandler(log, provider).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/search/stats", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp SearchStatsResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Comics)
+	require.Len(t, resp.Shards, 2)
+	assert.Equal(t, SearchShardStats{
+		Address:           "search-1:83",
+		Comics:            3,
+		Words:             10,
+		Postings:          12,
+		Generation:        2,
+		LastRebuild:       "2024-01-02T03:04:05Z",
+		RebuildDurationMs: 250,
+		Cache:             SearchCacheStats{Hits: 1},
+	}, resp.Shards[0])
+	assert.Equal(t, "unavailable", resp.Shards[1].Error)
+	assert.Empty(t, resp.Shards[1].LastRebuild)
+
+	provider.statsFn = func(ctx context.Context) ([]core.SearchStats, error) {
+		return nil, errors.New("all shards down")
+	}
+	rr = httptest.NewRecorder()
+	NewSearchStatsHandler(log, provider).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/search/stats", nil))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+type mockSynonymEditor struct {
+	text   string
+	setErr error
+}
+
+func (m *mockSynonymEditor) Synonyms(ctx context.Context) (string, error) {
+	return m.text, nil
+}
+
+func (m *mockSynonymEditor) SetSynonyms(ctx context.Context, text string) error {
+	if m.setErr != nil {
+		return m.setErr
+	}
+	m.text = text
+	return nil
+}
+
+func TestSynonymsHandlers(t *testing.T) {
+	log := newTestLogger()
+	editor := &mockSynonymEditor{}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/synonyms", bytes.NewBufferString("pc, computer\n"))
+	NewSetSynonymsHandler(log, editor).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	NewGetSynonymsHandler(log, editor).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/admin/synonyms", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "pc, computer\n", rr.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+
+	editor.setErr = fmt.Errorf("%w: line 1: group needs at least two synonyms", core.ErrBadArguments)
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/api/admin/synonyms", bytes.NewBufferString("pc"))
+	NewSetSynonymsHandler(log, editor).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "line 1")
+
+	editor.setErr = errors.New("shard down")
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/api/admin/synonyms", bytes.NewBufferString("pc, computer"))
+	NewSetSynonymsHandler(log, editor).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func TestParseAnalyticsFilter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	parse := func(query string) (core.AnalyticsFilter, error) {
+		return parseAnalyticsFilter(httptest.NewRequest(http.MethodGet, "/api/admin/analytics/top-queries?"+query, nil), now)
+	}
+
+	filter, err := parse("")
+	require.NoError(t, err)
+	assert.Equal(t, core.AnalyticsFilter{From: now.Add(-24 * time.Hour), To: now, Limit: 20}, filter)
+
+	filter, err = parse("window=1h&mode=isearch&limit=5")
+	require.NoError(t, err)
+	assert.Equal(t, core.AnalyticsFilter{From: now.Add(-time.Hour), To: now, Mode: "isearch", Limit: 5}, filter)
+
+	filter, err = parse("from=2023-12-01T00:00:00Z&to=2023-12-02T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), filter.From)
+	assert.Equal(t, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), filter.To)
+
+	for _, query := range []string{
+		"window=day", "window=-1h", "from=yesterday", "to=now",
+		"from=2024-01-03T00:00:00Z", "from=2024-01-01T00:00:00Z&window=1h",
+		"mode=fts", "limit=0", "limit=100000",
+	} {
+		_, err := parse(query)
+		assert.Error(t, err, query)
+	}
+}
+
+type mockQueryAnalytics struct {
+	topFn  func(ctx context.Context, filter core.AnalyticsFilter) ([]core.QueryStat, error)
+	zeroFn func(ctx context.Context, filter core.AnalyticsFilter) ([]core.QueryStat, error)
+}
+
+func (m *mockQueryAnalytics) TopQueries(ctx context.Context, filter core.AnalyticsFilter) ([]core.QueryStat, error) {
+	return m.topFn(ctx, filter)
+}
+
+func (m *mockQueryAnalytics) ZeroResults(ctx context.Context, filter core.AnalyticsFilter) ([]core.QueryStat, error) {
+	return m.zeroFn(ctx, filter)
+}
+
+func TestAnalyticsHandlers(t *testing.T) {
+	log := newTestLogger()
+	seen := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var gotFilter core.AnalyticsFilter
+	analytics := &mockQueryAnalytics{
+		topFn: func(ctx context.Context, filter core.AnalyticsFilter) ([]core.QueryStat, error) {
+			gotFilter = filter
+			return []core.QueryStat{{
+				Query: "linux", Terms: []string{"linux"}, Example: "Linux", Count: 7,
+				AvgHits: 2.5, AvgLatency: 1500 * time.Microsecond, P95Latency: 4 * time.Millisecond, LastSeen: seen,
+			}}, nil
+		},
+		zeroFn: func(ctx context.Context, filter core.AnalyticsFilter) ([]core.QueryStat, error) {
+			return nil, errors.New("db is down")
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	NewTopQueriesHandler(log, analytics).ServeHTTP(rr,
+		httptest.NewRequest(http.MethodGet, "/api/admin/analytics/top-queries?window=2h&limit=3", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 2*time.Hour, gotFilter.To.Sub(gotFilter.From))
+	assert.Equal(t, 3, gotFilter.Limit)
+
+	var resp QueryStatsResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []QueryStatItem{{
+		Query: "linux", Terms: []string{"linux"}, Example: "Linux", Count: 7,
+		AvgHits: 2.5, AvgLatencyMs: 1.5, P95LatencyMs: 4, LastSeen: "2024-01-02T03:04:05Z",
+	}}, resp.Queries)
+
+	rr = httptest.NewRecorder()
+	NewTopQueriesHandler(log, analytics).ServeHTTP(rr,
+		httptest.NewRequest(http.MethodGet, "/api/admin/analytics/top-queries?limit=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	NewZeroResultsHandler(log, analytics).ServeHTTP(rr,
+		httptest.NewRequest(http.MethodGet, "/api/admin/analytics/zero-results", nil))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func TestNewIndexSearchHandler_StaleCursor(t *testing.T) {
+	log := newTestLogger()
+
+	generation := uint64(7)
+	searcher := &mockSearcher{
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{
+				Comics:     []core.Comics{{ID: 1, URL: "u1"}},
+				Total:      30,
+				Generation: generation,
+			}, nil
+		},
+	}
+	h := NewIndexSearchHandler(log, searcher)
+
+	search := func(params string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/isearch?phrase=foo&limit=10"+params, nil)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := search("")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp SearchResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	// индекс тот же - курсор работает
+	assert.Equal(t, http.StatusOK, search("&cursor="+resp.NextCursor).Code)
+
+	// индекс изменился - страницы сдвинулись, курсор отклоняется
+	generation = 8
+	assert.Equal(t, http.StatusConflict, search("&cursor="+resp.NextCursor).Code)
+
+	// курсор без поколения из прежних ответов и номер страницы не проверяются
+	assert.Equal(t, http.StatusOK, search("&cursor=MTA").Code)
+	assert.Equal(t, http.StatusOK, search("&page=2").Code)
+}
@@ -41,3 +41,17 @@ func Auth(next http.HandlerFunc, verifier TokenVerifier) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// AuthIf требует токен только у запросов, для которых need вернул true,
+// остальные проходят к next как есть
+func AuthIf(next http.HandlerFunc, verifier TokenVerifier, need func(*http.Request) bool) http.HandlerFunc {
+	protected := Auth(next, verifier)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if need(r) {
+			protected(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
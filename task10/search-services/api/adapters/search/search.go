@@ -1,8 +1,13 @@
 package search
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
@@ -15,91 +20,432 @@ import (
 	searchpb "yadro.com/course/proto/search"
 )
 
+// Client рассылает запросы всем шардам поиска и сливает их ответы
 type Client struct {
 	log    *slog.Logger
-	client searchpb.SearchClient
-	conn   *grpc.ClientConn
-}
-
-func NewClient(address string, log *slog.Logger) (*Client, error) {
-	conn, err := grpc.NewClient(
-		address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithConnectParams(grpc.ConnectParams{
-			Backoff: backoff.Config{
-				BaseDelay:  1 * time.Second,
-				Multiplier: 1.6,
-				MaxDelay:   10 * time.Second,
-			},
-			MinConnectTimeout: 10 * time.Second,
-		}),
-	)
-	if err != nil {
-		return nil, err
-	}
-	conn.Connect()
-
-	return &Client{
-		client: searchpb.NewSearchClient(conn),
-		log:    log,
-		conn:   conn,
-	}, nil
+	shards []shard
 }
 
+type shard struct {
+	address string
+	client  searchpb.SearchClient
+	conn    *grpc.ClientConn
+}
+
+func NewClient(addresses []string, log *slog.Logger) (*Client, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no search addresses")
+	}
+	c := &Client{log: log}
+	for _, address := range addresses {
+		conn, err := grpc.NewClient(
+			address,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithConnectParams(grpc.ConnectParams{
+				Backoff: backoff.Config{
+					BaseDelay:  1 * time.Second,
+					Multiplier: 1.6,
+					MaxDelay:   10 * time.Second,
+				},
+				MinConnectTimeout: 10 * time.Second,
+			}),
+		)
+		if err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+		conn.Connect()
+
+		c.shards = append(c.shards, shard{
+			address: address,
+			client:  searchpb.NewSearchClient(conn),
+			conn:    conn,
+		})
+	}
+	return c, nil
+}
+
+// fanOut параллельно вызывает call на каждом шарде, ответы и ошибки по номеру шарда
+func fanOut[T any](c *Client, call func(searchpb.SearchClient) (T, error)) ([]T, []error) {
+	replies := make([]T, len(c.shards))
+	errs := make([]error, len(c.shards))
+	var wg sync.WaitGroup
+	for i, sh := range c.shards {
+		wg.Go(func() {
+			replies[i], errs[i] = call(sh.client)
+		})
+	}
+	wg.Wait()
+	return replies, errs
+}
+
+// Ping успешен, только если отвечают все шарды
 func (c *Client) Ping(ctx context.Context) error {
-	_, err := c.client.Ping(ctx, &emptypb.Empty{})
-	return err
+	_, errs := fanOut(c, func(client searchpb.SearchClient) (*emptypb.Empty, error) {
+		return client.Ping(ctx, &emptypb.Empty{})
+	})
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("search shard %s: %w", c.shards[i].address, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func searchRequest(query core.SearchQuery) *searchpb.SearchRequest {
+	return &searchpb.SearchRequest{
+		Phrase:    query.Phrase,
+		Limit:     int64(query.Limit),
+		Offset:    int64(query.Offset),
+		Fuzziness: int32(query.Fuzziness),
+		Explain:   query.Explain,
+	}
 }
 
-func (c *Client) Search(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
+func searchResult(resp *searchpb.SearchReply) core.SearchResult {
+	res := core.SearchResult{
+		Comics:     make([]core.Comics, 0, len(resp.Comics)),
+		Total:      int(resp.Total),
+		Generation: resp.Generation,
+	}
+	for _, cmt := range resp.Comics {
+		// дата из поиска всегда в этом формате, пустая - неизвестна
+		published, _ := time.Parse(time.DateOnly, cmt.Published)
+		res.Comics = append(res.Comics, core.Comics{
+			ID:         int(cmt.Id),
+			URL:        cmt.Url,
+			Score:      cmt.Score,
+			SafeTitle:  cmt.SafeTitle,
+			Title:      cmt.Title,
+			Alt:        cmt.Alt,
+			Transcript: cmt.Transcript,
+			Published:  published,
+			Link:       cmt.Link,
+			News:       cmt.News,
+			Snippet:    snippet(cmt.Snippet),
+			Explain:    explanation(cmt.Explanation),
+		})
+	}
+	return res
+}
 
-	resp, err := c.client.Search(ctx, &searchpb.SearchRequest{
-		Phrase: phrase,
-		Limit:  int64(limit),
+func snippet(s *searchpb.Snippet) *core.Snippet {
+	if s == nil {
+		return nil
+	}
+	res := &core.Snippet{
+		Field:      s.Field,
+		Text:       s.Text,
+		Highlights: make([]core.Span, 0, len(s.Highlights)),
+	}
+	for _, h := range s.Highlights {
+		res.Highlights = append(res.Highlights, core.Span{Start: int(h.Start), End: int(h.End)})
+	}
+	return res
+}
+
+func explanation(e *searchpb.Explanation) *core.Explanation {
+	if e == nil {
+		return nil
+	}
+	res := &core.Explanation{
+		Terms:     e.Terms,
+		Matched:   make([]core.TermScore, 0, len(e.Matched)),
+		Proximity: e.Proximity,
+		Matches:   int(e.Matches),
+		Ratio:     e.Ratio,
+		Score:     e.Score,
+		TieBreak:  e.TieBreak,
+	}
+	for _, t := range e.Matched {
+		res.Matched = append(res.Matched, core.TermScore{
+			Term:   t.Term,
+			Field:  t.Field,
+			Dist:   int(t.Dist),
+			Weight: t.Weight,
+			TF:     int(t.Tf),
+			IDF:    t.Idf,
+			Score:  t.Score,
+		})
+	}
+	return res
+}
+
+type searchCall func(searchpb.SearchClient, context.Context, *searchpb.SearchRequest, ...grpc.CallOption) (*searchpb.SearchReply, error)
+
+// scatter отправляет запрос всем шардам и собирает из их лучших комиксов
+// общую страницу. Ответ без части шардов считается неполным, но не ошибкой.
+func (c *Client) scatter(ctx context.Context, query core.SearchQuery, call searchCall) (core.SearchResult, error) {
+	req := searchRequest(query)
+	if len(c.shards) > 1 {
+		// страница вырезается после слияния, поэтому с каждого шарда нужно всё до её конца
+		req.Offset, req.Limit = 0, int64(query.Offset+query.Limit)
+	}
+
+	replies, errs := fanOut(c, func(client searchpb.SearchClient) (*searchpb.SearchReply, error) {
+		return call(client, ctx, req)
 	})
 
-	if err != nil {
-		if status.Code(err) == codes.ResourceExhausted {
-			return nil, core.ErrBadArguments
+	res := core.SearchResult{
+		Comics: make([]core.Comics, 0, query.Limit),
+		Shards: len(c.shards),
+	}
+	var lastErr error
+	for i, err := range errs {
+		if err != nil {
+			// неверный запрос отвергнут бы всеми шардами
+			if status.Code(err) == codes.InvalidArgument {
+				return core.SearchResult{}, convertError(err)
+			}
+			c.log.Error("search shard failed", "address", c.shards[i].address, "error", err)
+			res.FailedShards++
+			lastErr = err
+			continue
 		}
-		return nil, err
+		part := searchResult(replies[i])
+		res.Comics = append(res.Comics, part.Comics...)
+		res.Total += part.Total
+		// поколения шардов только растут, так что сумма меняется с правкой любого из них
+		res.Generation += part.Generation
+	}
+	if res.FailedShards == len(c.shards) {
+		return core.SearchResult{}, convertError(lastErr)
+	}
+	if res.FailedShards > 0 {
+		// сумма без упавших шардов изменится, когда они поднимутся, и курсоры
+		// станут ложно устаревшими: неполная выдача поколение не отслеживает
+		res.Generation = 0
 	}
 
-	comics := make([]core.Comics, 0, len(resp.Comics))
-	for _, cmt := range resp.Comics {
-		comics = append(comics, core.Comics{
-			ID:  int(cmt.Id),
-			URL: cmt.Url,
+	if len(c.shards) > 1 {
+		// как и внутри шарда, при равной оценке выше комикс с меньшим id
+		slices.SortFunc(res.Comics, func(a, b core.Comics) int {
+			if a.Score != b.Score {
+				return cmp.Compare(b.Score, a.Score)
+			}
+			return cmp.Compare(a.ID, b.ID)
 		})
+		// порядок между шардами задаёт сортировка выше, в разборе - её признак
+		for i := range res.Comics {
+			if e := res.Comics[i].Explain; e != nil {
+				e.TieBreak = ""
+				if i+1 < len(res.Comics) {
+					e.TieBreak = "id"
+					if res.Comics[i].Score != res.Comics[i+1].Score {
+						e.TieBreak = "score"
+					}
+				}
+			}
+		}
+		from := min(query.Offset, len(res.Comics))
+		to := min(query.Offset+query.Limit, len(res.Comics))
+		res.Comics = res.Comics[from:to]
 	}
-	return comics, err
+	return res, nil
+}
+
+func (c *Client) Search(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+	return c.scatter(ctx, query, searchpb.SearchClient.Search)
+}
 
+func (c *Client) IndexSearch(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+	return c.scatter(ctx, query, searchpb.SearchClient.IndexSearch)
 }
 
-func (c *Client) IndexSearch(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
+// Suggest складывает частоты слов по шардам: комиксы в шардах не пересекаются
+func (c *Client) Suggest(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error) {
 
-	resp, err := c.client.IndexSearch(ctx, &searchpb.SearchRequest{
-		Phrase: phrase,
-		Limit:  int64(limit),
+	replies, errs := fanOut(c, func(client searchpb.SearchClient) (*searchpb.SuggestReply, error) {
+		return client.Suggest(ctx, &searchpb.SuggestRequest{
+			Prefix: prefix,
+			Limit:  int64(limit),
+		})
 	})
 
-	if err != nil {
-		if status.Code(err) == codes.ResourceExhausted {
-			return nil, core.ErrBadArguments
+	counts := make(map[string]int)
+	failed := 0
+	var lastErr error
+	for i, err := range errs {
+		if err != nil {
+			if status.Code(err) == codes.InvalidArgument {
+				return nil, convertError(err)
+			}
+			c.log.Error("suggest shard failed", "address", c.shards[i].address, "error", err)
+			failed++
+			lastErr = err
+			continue
+		}
+		for _, sg := range replies[i].Suggestions {
+			counts[sg.Word] += int(sg.Count)
 		}
-		return nil, err
+	}
+	if failed == len(c.shards) {
+		return nil, convertError(lastErr)
 	}
 
-	comics := make([]core.Comics, 0, len(resp.Comics))
-	for _, cmt := range resp.Comics {
-		comics = append(comics, core.Comics{
-			ID:  int(cmt.Id),
-			URL: cmt.Url,
+	suggestions := make([]core.Suggestion, 0, len(counts))
+	for word, count := range counts {
+		suggestions = append(suggestions, core.Suggestion{Word: word, Count: count})
+	}
+	slices.SortFunc(suggestions, func(a, b core.Suggestion) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return cmp.Compare(a.Word, b.Word)
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// Similar опрашивает все шарды: шард, где комикса нет, читает его слова
+// из базы и ищет похожие среди своих. Косинус каждый шард считает по своим
+// IDF, поэтому оценки разных шардов сравнимы приближённо, как и в поиске.
+func (c *Client) Similar(ctx context.Context, id, limit int) ([]core.Comics, error) {
+
+	replies, errs := fanOut(c, func(client searchpb.SearchClient) (*searchpb.SearchReply, error) {
+		return client.Similar(ctx, &searchpb.SimilarRequest{
+			Id:    int64(id),
+			Limit: int64(limit),
 		})
+	})
+
+	var comics []core.Comics
+	found := false
+	var lastErr error
+	for i, err := range errs {
+		switch status.Code(err) {
+		case codes.OK:
+			found = true
+			comics = append(comics, searchResult(replies[i]).Comics...)
+		case codes.NotFound:
+		case codes.InvalidArgument:
+			return nil, convertError(err)
+		default:
+			c.log.Error("similar shard failed", "address", c.shards[i].address, "error", err)
+			lastErr = err
+		}
+	}
+	if !found {
+		if lastErr != nil {
+			return nil, convertError(lastErr)
+		}
+		return nil, core.ErrNotFound
+	}
+
+	slices.SortFunc(comics, func(a, b core.Comics) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(comics) > limit {
+		comics = comics[:limit]
 	}
 	return comics, nil
 }
 
+// SearchStats опрашивает все шарды: упавший шард попадает в ответ с ошибкой,
+// чтобы было видно, какой из них не работает. Ошибка - только если не ответил никто.
+func (c *Client) SearchStats(ctx context.Context) ([]core.SearchStats, error) {
+
+	replies, errs := fanOut(c, func(client searchpb.SearchClient) (*searchpb.StatsReply, error) {
+		return client.Stats(ctx, &emptypb.Empty{})
+	})
+
+	stats := make([]core.SearchStats, 0, len(c.shards))
+	failed := 0
+	var lastErr error
+	for i, err := range errs {
+		st := core.SearchStats{Address: c.shards[i].address}
+		if err != nil {
+			c.log.Error("stats shard failed", "address", c.shards[i].address, "error", err)
+			failed++
+			lastErr = err
+			st.Error = err.Error()
+			stats = append(stats, st)
+			continue
+		}
+		r := replies[i]
+		st.Comics = int(r.Comics)
+		st.Words = int(r.Words)
+		st.Postings = int(r.Postings)
+		st.Generation = r.Generation
+		st.LastRebuild = parseTime(r.LastRebuild)
+		st.RebuildDuration = time.Duration(r.RebuildDurationMs) * time.Millisecond
+		st.LastError = r.LastError
+		st.LastErrorAt = parseTime(r.LastErrorAt)
+		if r.Cache != nil {
+			st.CacheHits = r.Cache.Hits
+			st.CacheMisses = r.Cache.Misses
+			st.CacheEntries = int(r.Cache.Entries)
+		}
+		stats = append(stats, st)
+	}
+	if failed == len(c.shards) {
+		return nil, convertError(lastErr)
+	}
+	return stats, nil
+}
+
+// Synonyms - словарь первого ответившего шарда, у всех шардов он один
+func (c *Client) Synonyms(ctx context.Context) (string, error) {
+
+	replies, errs := fanOut(c, func(client searchpb.SearchClient) (*searchpb.SynonymsReply, error) {
+		return client.GetSynonyms(ctx, &emptypb.Empty{})
+	})
+
+	for i, err := range errs {
+		if err == nil {
+			return replies[i].Text, nil
+		}
+		c.log.Error("synonyms shard failed", "address", c.shards[i].address, "error", err)
+	}
+	return "", convertError(errs[0])
+}
+
+// SetSynonyms заменяет словарь на всех шардах. Ошибка хотя бы одного
+// возвращается, чтобы словарь переложили ещё раз и шарды не расходились.
+func (c *Client) SetSynonyms(ctx context.Context, text string) error {
+
+	_, errs := fanOut(c, func(client searchpb.SearchClient) (*emptypb.Empty, error) {
+		return client.SetSynonyms(ctx, &searchpb.SynonymsRequest{Text: text})
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			c.log.Error("set synonyms shard failed", "address", c.shards[i].address, "error", err)
+			return convertError(err)
+		}
+	}
+	return nil
+}
+
+// parseTime - время из поиска всегда в RFC 3339, пустое - события не было
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+// convertError сохраняет текст ошибки разбора запроса, чтобы клиент видел, где она
+func convertError(err error) error {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %s", core.ErrBadArguments, st.Message())
+	case codes.ResourceExhausted:
+		return core.ErrBadArguments
+	case codes.NotFound:
+		return core.ErrNotFound
+	}
+	return err
+}
+
 func (c *Client) Close() error {
-	return c.conn.Close()
+	var errs []error
+	for _, sh := range c.shards {
+		errs = append(errs, sh.conn.Close())
+	}
+	return errors.Join(errs...)
 }
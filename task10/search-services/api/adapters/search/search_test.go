@@ -0,0 +1,347 @@
+package search
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"yadro.com/course/api/core"
+	searchpb "yadro.com/course/proto/search"
+)
+
+// mockShard - один шард поиска, не переопределённые методы паникуют
+type mockShard struct {
+	searchpb.SearchClient
+	indexSearchFn func(req *searchpb.SearchRequest) (*searchpb.SearchReply, error)
+	suggestFn     func(req *searchpb.SuggestRequest) (*searchpb.SuggestReply, error)
+	similarFn     func(req *searchpb.SimilarRequest) (*searchpb.SearchReply, error)
+	statsFn       func() (*searchpb.StatsReply, error)
+	synonymsFn    func(req *searchpb.SynonymsRequest) (*searchpb.SynonymsReply, error)
+}
+
+func (m *mockShard) GetSynonyms(_ context.Context, _ *emptypb.Empty, _ ...grpc.CallOption) (*searchpb.SynonymsReply, error) {
+	return m.synonymsFn(nil)
+}
+
+func (m *mockShard) SetSynonyms(_ context.Context, req *searchpb.SynonymsRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	if _, err := m.synonymsFn(req); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (m *mockShard) Stats(_ context.Context, _ *emptypb.Empty, _ ...grpc.CallOption) (*searchpb.StatsReply, error) {
+	return m.statsFn()
+}
+
+func (m *mockShard) Similar(_ context.Context, req *searchpb.SimilarRequest, _ ...grpc.CallOption) (*searchpb.SearchReply, error) {
+	return m.similarFn(req)
+}
+
+func (m *mockShard) IndexSearch(_ context.Context, req *searchpb.SearchRequest, _ ...grpc.CallOption) (*searchpb.SearchReply, error) {
+	return m.indexSearchFn(req)
+}
+
+func (m *mockShard) Suggest(_ context.Context, req *searchpb.SuggestRequest, _ ...grpc.CallOption) (*searchpb.SuggestReply, error) {
+	return m.suggestFn(req)
+}
+
+func newTestClient(shards ...*mockShard) *Client {
+	c := &Client{log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	for _, m := range shards {
+		c.shards = append(c.shards, shard{address: "test", client: m})
+	}
+	return c
+}
+
+// replyShard отвечает заданными комиксами с учётом limit, как настоящий шард
+func replyShard(total int64, comics ...*searchpb.Comic) *mockShard {
+	return &mockShard{indexSearchFn: func(req *searchpb.SearchRequest) (*searchpb.SearchReply, error) {
+		return &searchpb.SearchReply{Comics: comics[:min(int(req.Limit), len(comics))], Total: total}, nil
+	}}
+}
+
+func failShard(code codes.Code) *mockShard {
+	return &mockShard{indexSearchFn: func(req *searchpb.SearchRequest) (*searchpb.SearchReply, error) {
+		return nil, status.Error(code, "shard error")
+	}}
+}
+
+func ids(comics []core.Comics) []int {
+	res := make([]int, 0, len(comics))
+	for _, c := range comics {
+		res = append(res, c.ID)
+	}
+	return res
+}
+
+func TestClientIndexSearch_MergesShards(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got []*searchpb.SearchRequest
+	)
+	first := replyShard(3,
+		&searchpb.Comic{Id: 1, Score: 5},
+		&searchpb.Comic{Id: 3, Score: 2},
+		&searchpb.Comic{Id: 5, Score: 1},
+	)
+	second := replyShard(2,
+		&searchpb.Comic{Id: 4, Score: 3},
+		&searchpb.Comic{Id: 2, Score: 2},
+	)
+	for _, m := range []*mockShard{first, second} {
+		fn := m.indexSearchFn
+		m.indexSearchFn = func(req *searchpb.SearchRequest) (*searchpb.SearchReply, error) {
+			// шарды опрашиваются параллельно
+			mu.Lock()
+			got = append(got, req)
+			mu.Unlock()
+			return fn(req)
+		}
+	}
+	c := newTestClient(first, second)
+
+	res, err := c.IndexSearch(context.Background(), core.SearchQuery{Phrase: "foo", Limit: 2, Offset: 1})
+	require.NoError(t, err)
+
+	// у шардов просят всё до конца страницы
+	for _, req := range got {
+		assert.Equal(t, int64(0), req.Offset)
+		assert.Equal(t, int64(3), req.Limit)
+	}
+	// общий порядок 1, 4, 2, 3: при равной оценке меньший id выше
+	assert.Equal(t, []int{4, 2}, ids(res.Comics))
+	assert.Equal(t, 5, res.Total)
+	assert.Equal(t, 2, res.Shards)
+	assert.Zero(t, res.FailedShards)
+}
+
+func TestClientIndexSearch_SingleShardPassesQuery(t *testing.T) {
+	var got *searchpb.SearchRequest
+	c := newTestClient(&mockShard{indexSearchFn: func(req *searchpb.SearchRequest) (*searchpb.SearchReply, error) {
+		got = req
+		return &searchpb.SearchReply{Comics: []*searchpb.Comic{{Id: 9}}, Total: 20}, nil
+	}})
+
+	res, err := c.IndexSearch(context.Background(), core.SearchQuery{Phrase: "foo", Limit: 5, Offset: 10})
+	require.NoError(t, err)
+
+	// единственный шард сам отдаёт нужную страницу
+	assert.Equal(t, int64(10), got.Offset)
+	assert.Equal(t, int64(5), got.Limit)
+	assert.Equal(t, []int{9}, ids(res.Comics))
+	assert.Equal(t, 20, res.Total)
+}
+
+func TestClientIndexSearch_SumsGenerations(t *testing.T) {
+	shard := func(generation uint64) *mockShard {
+		return &mockShard{indexSearchFn: func(req *searchpb.SearchRequest) (*searchpb.SearchReply, error) {
+			return &searchpb.SearchReply{Total: 1, Generation: generation}, nil
+		}}
+	}
+	c := newTestClient(shard(3), shard(4))
+
+	res, err := c.IndexSearch(context.Background(), core.SearchQuery{Phrase: "foo", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), res.Generation)
+}
+
+func TestClientIndexSearch_PartialFailure(t *testing.T) {
+	c := newTestClient(
+		replyShard(1, &searchpb.Comic{Id: 1, Score: 1}),
+		failShard(codes.Unavailable),
+	)
+
+	res, err := c.IndexSearch(context.Background(), core.SearchQuery{Phrase: "foo", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, ids(res.Comics))
+	assert.Equal(t, 2, res.Shards)
+	assert.Equal(t, 1, res.FailedShards)
+
+	// без части шардов сумма поколений неполная - курсоры не проверяются
+	c = newTestClient(
+		&mockShard{indexSearchFn: func(req *searchpb.SearchRequest) (*searchpb.SearchReply, error) {
+			return &searchpb.SearchReply{Total: 1, Generation: 3}, nil
+		}},
+		failShard(codes.Unavailable),
+	)
+	res, err = c.IndexSearch(context.Background(), core.SearchQuery{Phrase: "foo", Limit: 10})
+	require.NoError(t, err)
+	assert.Zero(t, res.Generation)
+}
+
+func TestClientIndexSearch_Errors(t *testing.T) {
+	// не ответил ни один шард
+	c := newTestClient(failShard(codes.Unavailable), failShard(codes.Internal))
+	_, err := c.IndexSearch(context.Background(), core.SearchQuery{Phrase: "foo", Limit: 10})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, core.ErrBadArguments)
+
+	// ошибка в запросе отдаётся клиенту, даже если другие шарды ответили
+	c = newTestClient(replyShard(0), failShard(codes.InvalidArgument))
+	_, err = c.IndexSearch(context.Background(), core.SearchQuery{Phrase: "foo", Limit: 10})
+	require.ErrorIs(t, err, core.ErrBadArguments)
+}
+
+func TestClientSuggest_SumsCounts(t *testing.T) {
+	reply := func(sgs ...*searchpb.Suggestion) *mockShard {
+		return &mockShard{suggestFn: func(req *searchpb.SuggestRequest) (*searchpb.SuggestReply, error) {
+			return &searchpb.SuggestReply{Suggestions: sgs}, nil
+		}}
+	}
+	c := newTestClient(
+		reply(&searchpb.Suggestion{Word: "foo", Count: 2}, &searchpb.Suggestion{Word: "food", Count: 1}),
+		reply(&searchpb.Suggestion{Word: "fork", Count: 2}, &searchpb.Suggestion{Word: "food", Count: 2}),
+		&mockShard{suggestFn: func(req *searchpb.SuggestRequest) (*searchpb.SuggestReply, error) {
+			return nil, status.Error(codes.Unavailable, "down")
+		}},
+	)
+
+	sg, err := c.Suggest(context.Background(), "fo", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []core.Suggestion{{Word: "food", Count: 3}, {Word: "foo", Count: 2}}, sg)
+}
+
+func TestClientSimilar_OwnerShardAnswers(t *testing.T) {
+	notFound := &mockShard{similarFn: func(req *searchpb.SimilarRequest) (*searchpb.SearchReply, error) {
+		return nil, status.Error(codes.NotFound, "comic is not found")
+	}}
+	owner := &mockShard{similarFn: func(req *searchpb.SimilarRequest) (*searchpb.SearchReply, error) {
+		return &searchpb.SearchReply{Comics: []*searchpb.Comic{{Id: 3, Score: 0.2}, {Id: 2, Score: 0.9}}}, nil
+	}}
+
+	c := newTestClient(notFound, owner)
+	res, err := c.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, ids(res))
+
+	// комикса нет ни в одном шарде
+	c = newTestClient(notFound, notFound)
+	_, err = c.Similar(context.Background(), 1, 10)
+	require.ErrorIs(t, err, core.ErrNotFound)
+}
+
+func TestClientSimilar_MergesShards(t *testing.T) {
+	// похожие комиксы есть в обоих шардах, лучшие берутся из общей выдачи
+	first := &mockShard{similarFn: func(req *searchpb.SimilarRequest) (*searchpb.SearchReply, error) {
+		return &searchpb.SearchReply{Comics: []*searchpb.Comic{{Id: 3, Score: 0.7}, {Id: 5, Score: 0.1}}}, nil
+	}}
+	second := &mockShard{similarFn: func(req *searchpb.SimilarRequest) (*searchpb.SearchReply, error) {
+		return &searchpb.SearchReply{Comics: []*searchpb.Comic{{Id: 2, Score: 0.9}, {Id: 4, Score: 0.3}}}, nil
+	}}
+
+	c := newTestClient(first, second)
+	res, err := c.Similar(context.Background(), 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4}, ids(res))
+}
+
+func TestClientIndexSearch_ExplainTieBreakAfterMerge(t *testing.T) {
+	explained := func(id int64, score float64, tieBreak string) *searchpb.Comic {
+		return &searchpb.Comic{Id: id, Score: score, Explanation: &searchpb.Explanation{Score: score, TieBreak: tieBreak}}
+	}
+	// внутри шардов порядок решили matches, после слияния - оценка и id
+	c := newTestClient(
+		replyShard(2, explained(1, 5, "matches"), explained(3, 2, "")),
+		replyShard(1, explained(2, 2, "")),
+	)
+
+	res, err := c.IndexSearch(context.Background(), core.SearchQuery{Phrase: "foo", Limit: 10, Explain: true})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, ids(res.Comics))
+
+	var tieBreaks []string
+	for _, cmt := range res.Comics {
+		require.NotNil(t, cmt.Explain)
+		tieBreaks = append(tieBreaks, cmt.Explain.TieBreak)
+	}
+	assert.Equal(t, []string{"score", "id", ""}, tieBreaks)
+}
+
+func TestClientSearchStats(t *testing.T) {
+	ok := &mockShard{statsFn: func() (*searchpb.StatsReply, error) {
+		return &searchpb.StatsReply{
+			Comics:            3,
+			Words:             10,
+			Postings:          12,
+			Generation:        2,
+			LastRebuild:       "2024-01-02T03:04:05Z",
+			RebuildDurationMs: 250,
+			Cache:             &searchpb.CacheStats{Hits: 1, Misses: 2, Entries: 1},
+		}, nil
+	}}
+	down := &mockShard{statsFn: func() (*searchpb.StatsReply, error) {
+		return nil, status.Error(codes.Unavailable, "down")
+	}}
+
+	// упавший шард виден в ответе, а не роняет его
+	stats, err := newTestClient(ok, down).SearchStats(context.Background())
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, core.SearchStats{
+		Address:         "test",
+		Comics:          3,
+		Words:           10,
+		Postings:        12,
+		Generation:      2,
+		LastRebuild:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		RebuildDuration: 250 * time.Millisecond,
+		CacheHits:       1,
+		CacheMisses:     2,
+		CacheEntries:    1,
+	}, stats[0])
+	assert.NotEmpty(t, stats[1].Error)
+	assert.Zero(t, stats[1].Comics)
+
+	_, err = newTestClient(down).SearchStats(context.Background())
+	require.Error(t, err)
+}
+
+// synonymShard хранит словарь как настоящий шард, nil req - чтение
+func synonymShard(text *string, err error) *mockShard {
+	return &mockShard{synonymsFn: func(req *searchpb.SynonymsRequest) (*searchpb.SynonymsReply, error) {
+		if err != nil {
+			return nil, err
+		}
+		if req != nil {
+			*text = req.Text
+		}
+		return &searchpb.SynonymsReply{Text: *text}, nil
+	}}
+}
+
+func TestClientSynonyms(t *testing.T) {
+	var first, second string
+	c := newTestClient(synonymShard(&first, nil), synonymShard(&second, nil))
+
+	require.NoError(t, c.SetSynonyms(context.Background(), "pc, computer"))
+	assert.Equal(t, "pc, computer", first)
+	assert.Equal(t, "pc, computer", second)
+
+	text, err := c.Synonyms(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "pc, computer", text)
+
+	// читается с любого живого шарда
+	down := synonymShard(nil, status.Error(codes.Unavailable, "down"))
+	text, err = newTestClient(down, synonymShard(&first, nil)).Synonyms(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "pc, computer", text)
+
+	// запись должна дойти до всех
+	require.Error(t, newTestClient(synonymShard(&first, nil), down).SetSynonyms(context.Background(), "a, b"))
+
+	bad := synonymShard(nil, status.Error(codes.InvalidArgument, "line 1: group needs at least two synonyms"))
+	err = newTestClient(bad).SetSynonyms(context.Background(), "pc")
+	require.ErrorIs(t, err, core.ErrBadArguments)
+	assert.Contains(t, err.Error(), "line 1")
+}
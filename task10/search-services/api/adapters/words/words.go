@@ -21,6 +21,10 @@ type Client struct {
 	conn   *grpc.ClientConn
 }
 
+// language - комиксы и запросы нормализуются одинаково:
+// язык выбирается по алфавиту каждого слова
+const language = "auto"
+
 func NewClient(address string, log *slog.Logger) (*Client, error) {
 
 	conn, err := grpc.NewClient(
@@ -50,7 +54,7 @@ func NewClient(address string, log *slog.Logger) (*Client, error) {
 
 func (c *Client) Norm(ctx context.Context, phrase string) ([]string, error) {
 
-	resp, err := c.client.Norm(ctx, &wordspb.WordsRequest{Phrase: phrase})
+	resp, err := c.client.Norm(ctx, &wordspb.WordsRequest{Phrase: phrase, Language: language})
 	if err != nil {
 		if status.Code(err) == codes.ResourceExhausted {
 			return nil, core.ErrBadArguments
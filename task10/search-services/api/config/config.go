@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"log"
+	"os"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -12,6 +14,9 @@ type HTTPConfig struct {
 	Timeout time.Duration `yaml:"timeout" env:"API_TIMEOUT" env-default:"5s"`
 }
 
+// Шарды поиска считают BM25 по своей части комиксов: IDF слова у каждого
+// свой, и при слиянии выдачи оценки разных шардов сравниваются приближённо.
+// Чем ровнее комиксы разложены по шардам (режим hash), тем меньше разница.
 type Config struct {
 	LogLevel          string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"DEBUG"`
 	SearchConcurrency int           `yaml:"search_concurrency" env:"SEARCH_CONCURRENCY" env-default:"1"`
@@ -19,8 +24,10 @@ type Config struct {
 	HTTPConfig        HTTPConfig    `yaml:"api_server"`
 	WordsAddress      string        `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"words:81"`
 	UpdateAddress     string        `yaml:"update_address" env:"UPDATE_ADDRESS" env-default:"update:82"`
-	SearchAddress     string        `yaml:"search_address" env:"SEARCH_ADDRESS" env-default:"search:83"`
+	SearchAddresses   []string      `yaml:"search_addresses" env:"SEARCH_ADDRESSES"` // по адресу на шард
+	SearchAddress     string        `yaml:"search_address" env:"SEARCH_ADDRESS"`     // устарел: адрес единственного шарда вместо SEARCH_ADDRESSES
 	TokenTTL          time.Duration `yaml:"token_ttl" env:"TOKEN_TTL" env-default:"24h"`
+	DBAddress         string        `yaml:"db_address" env:"DB_ADDRESS"` // журнал запросов, пусто - аналитика отключена
 }
 
 func MustLoad(configPath string) Config {
@@ -28,5 +35,24 @@ func MustLoad(configPath string) Config {
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
 		log.Fatalf("cannot read config %q: %s", configPath, err)
 	}
+	if err := cfg.resolveSearchAddresses(); err != nil {
+		log.Fatalf("bad config %q: %s", configPath, err)
+	}
 	return cfg
 }
+
+// resolveSearchAddresses поддерживает старый SEARCH_ADDRESS с одним адресом поиска.
+// Он переопределяет search_addresses из файла, как любая переменная окружения.
+func (cfg *Config) resolveSearchAddresses() error {
+	if cfg.SearchAddress != "" {
+		if _, ok := os.LookupEnv("SEARCH_ADDRESSES"); ok {
+			return errors.New("both SEARCH_ADDRESSES and deprecated SEARCH_ADDRESS are set, keep only SEARCH_ADDRESSES")
+		}
+		log.Printf("SEARCH_ADDRESS is deprecated, use SEARCH_ADDRESSES")
+		cfg.SearchAddresses = []string{cfg.SearchAddress}
+	}
+	if len(cfg.SearchAddresses) == 0 {
+		cfg.SearchAddresses = []string{"search:83"}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSearchAddresses(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		cfg := Config{}
+		require.NoError(t, cfg.resolveSearchAddresses())
+		assert.Equal(t, []string{"search:83"}, cfg.SearchAddresses)
+	})
+
+	t.Run("shards", func(t *testing.T) {
+		cfg := Config{SearchAddresses: []string{"a:83", "b:83"}}
+		require.NoError(t, cfg.resolveSearchAddresses())
+		assert.Equal(t, []string{"a:83", "b:83"}, cfg.SearchAddresses)
+	})
+
+	// старый SEARCH_ADDRESS - один шард, перекрывает список из файла
+	t.Run("legacy address", func(t *testing.T) {
+		cfg := Config{SearchAddresses: []string{"localhost:83"}, SearchAddress: "search:8080"}
+		require.NoError(t, cfg.resolveSearchAddresses())
+		assert.Equal(t, []string{"search:8080"}, cfg.SearchAddresses)
+	})
+
+	t.Run("both in env", func(t *testing.T) {
+		t.Setenv("SEARCH_ADDRESSES", "a:83,b:83")
+		cfg := Config{SearchAddresses: []string{"a:83", "b:83"}, SearchAddress: "search:8080"}
+		require.Error(t, cfg.resolveSearchAddresses())
+	})
+}
@@ -1,5 +1,7 @@
 package core
 
+import "time"
+
 type UpdateStatus string
 
 const (
@@ -15,8 +17,137 @@ type UpdateStats struct {
 	ComicsTotal   int
 }
 
+// FuzzinessAuto - число допустимых опечаток зависит от длины слова
+const FuzzinessAuto = -1
+
+type SearchQuery struct {
+	Phrase    string
+	Limit     int
+	Offset    int
+	Fuzziness int
+	Explain   bool // разбор оценки, только для администратора
+}
+
+// SearchResult - страница выдачи. Shards - сколько шардов поиска опрошено,
+// FailedShards - сколько из них не ответило, тогда выдача неполная.
+// Generation меняется с каждой правкой индекса любого шарда, 0 - поиск
+// поколения не отслеживает (полнотекстовый поиск postgres) или выдача неполная.
+type SearchResult struct {
+	Comics       []Comics
+	Total        int
+	Shards       int
+	FailedShards int
+	Generation   uint64
+}
+
+type Suggestion struct {
+	Word  string
+	Count int
+}
+
 type Comics struct {
-	ID    int
-	URL   string
-	Score int
+	ID         int
+	URL        string
+	Score      float64
+	SafeTitle  string
+	Title      string
+	Alt        string
+	Transcript string
+	Published  time.Time // нулевое время, если дата неизвестна
+	Link       string
+	News       string
+	Snippet    *Snippet     // почему комикс нашёлся, может отсутствовать
+	Explain    *Explanation // разбор оценки, если запрошен
+}
+
+// Snippet - кусок текста комикса из поля Field с найденными словами.
+// Highlights - байтовые отрезки [Start, End) найденных слов в Text.
+type Snippet struct {
+	Field      string
+	Text       string
+	Highlights []Span
+}
+
+type Span struct {
+	Start int
+	End   int
+}
+
+// Explanation - из чего сложилась оценка комикса. TieBreak - признак
+// (score, matches, ratio или id), по которому комикс выше следующего в выдаче.
+type Explanation struct {
+	Terms     []string
+	Matched   []TermScore
+	Proximity float64
+	Matches   int
+	Ratio     float64
+	Score     float64
+	TieBreak  string
+}
+
+// TermScore - вклад совпавшего слова запроса в оценку
+type TermScore struct {
+	Term   string
+	Field  string
+	Dist   int
+	Weight float64
+	TF     int
+	IDF    float64
+	Score  float64
+}
+
+// SearchStats - состояние индекса одного шарда поиска. Если шард не ответил,
+// заполнены только Address и Error.
+type SearchStats struct {
+	Address         string
+	Comics          int
+	Words           int
+	Postings        int
+	Generation      uint64
+	LastRebuild     time.Time // нулевое, если индекс не перестраивался
+	RebuildDuration time.Duration
+	LastError       string
+	LastErrorAt     time.Time
+	CacheHits       uint64
+	CacheMisses     uint64
+	CacheEntries    int
+	Error           string
+}
+
+// режимы поиска в аналитике - по ручкам api
+const (
+	ModeSearch      = "search"
+	ModeIndexSearch = "isearch"
+)
+
+// QueryRecord - один поисковый запрос для аналитики. Query - ключ группировки:
+// нормализованные слова через пробел или сам запрос, если слов не осталось.
+type QueryRecord struct {
+	At      time.Time
+	Mode    string
+	Phrase  string
+	Query   string
+	Terms   []string
+	Hits    int
+	Latency time.Duration
+}
+
+// AnalyticsFilter - окно [From, To), Mode пустой - все режимы
+type AnalyticsFilter struct {
+	From  time.Time
+	To    time.Time
+	Mode  string
+	Limit int
+}
+
+// QueryStat - сводка по одинаковым запросам за окно, Example - один из исходных текстов
+type QueryStat struct {
+	Query      string
+	Terms      []string
+	Example    string
+	Count      int
+	AvgHits    float64
+	AvgLatency time.Duration
+	P95Latency time.Duration
+	LastSeen   time.Time
 }
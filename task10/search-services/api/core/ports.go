@@ -18,6 +18,35 @@ type Updater interface {
 }
 
 type Searcher interface {
-	Search(context.Context, string, int) ([]Comics, error)
-	IndexSearch(context.Context, string, int) ([]Comics, error)
+	Search(context.Context, SearchQuery) (SearchResult, error)
+	IndexSearch(context.Context, SearchQuery) (SearchResult, error)
+}
+
+type SimilarFinder interface {
+	Similar(ctx context.Context, id, limit int) ([]Comics, error)
+}
+
+type SearchStatsProvider interface {
+	SearchStats(ctx context.Context) ([]SearchStats, error)
+}
+
+// SynonymEditor - словарь синонимов поиска в текстовом виде
+type SynonymEditor interface {
+	Synonyms(ctx context.Context) (string, error)
+	SetSynonyms(ctx context.Context, text string) error
+}
+
+// QueryLog - журнал поисковых запросов
+type QueryLog interface {
+	Record(ctx context.Context, records []QueryRecord) error
+	QueryAnalytics
+}
+
+type QueryAnalytics interface {
+	TopQueries(ctx context.Context, filter AnalyticsFilter) ([]QueryStat, error)
+	ZeroResults(ctx context.Context, filter AnalyticsFilter) ([]QueryStat, error)
+}
+
+type Suggester interface {
+	Suggest(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
 }
@@ -8,9 +8,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"yadro.com/course/api/adapters/aaa"
+	"yadro.com/course/api/adapters/analytics"
+	"yadro.com/course/api/adapters/db"
 	"yadro.com/course/api/adapters/rest"
 	"yadro.com/course/api/adapters/rest/middleware"
 	"yadro.com/course/api/adapters/search"
@@ -65,7 +68,7 @@ func run() error {
 		}
 	}()
 
-	searchClient, err := search.NewClient(cfg.SearchAddress, log)
+	searchClient, err := search.NewClient(cfg.SearchAddresses, log)
 	if err != nil {
 		log.Error("cannot init search adapter", "error", err)
 		return err
@@ -76,6 +79,31 @@ func run() error {
 		}
 	}()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// аналитика запросов: поиск идёт через Recorder, который пишет журнал
+	var searcher core.Searcher = searchClient
+	var storage *db.DB
+	var tasks []func(context.Context)
+	if cfg.DBAddress != "" {
+		storage, err = db.New(log, cfg.DBAddress)
+		if err != nil {
+			log.Error("cannot init db adapter", "error", err)
+			return err
+		}
+		defer func() {
+			if err := storage.Close(); err != nil {
+				log.Error("cannot close db adapter", "error", err)
+			}
+		}()
+
+		recorder := analytics.NewRecorder(log, searchClient, wordsClient, storage)
+		// журнал дописывается до закрытия базы: serve дожидается recorder.Run
+		tasks = append(tasks, recorder.Run)
+		searcher = recorder
+	}
+
 	mux := http.NewServeMux()
 
 	// login
@@ -94,12 +122,40 @@ func run() error {
 	mux.Handle("DELETE /api/db",
 		middleware.Auth(rest.NewDropHandler(log, updateClient), aaaService))
 
-	// search endpoint
+	// search endpoint; разбор оценки (?explain=true) - только для администратора
 	mux.Handle("GET /api/search",
-		middleware.Concurrency(rest.NewSearchHandler(log, searchClient), cfg.SearchConcurrency))
+		middleware.Concurrency(middleware.AuthIf(rest.NewSearchHandler(log, searcher),
+			aaaService, rest.WantsExplain), cfg.SearchConcurrency))
 
 	mux.Handle("GET /api/isearch",
-		middleware.Rate(rest.NewIndexSearchHandler(log, searchClient), cfg.SearchRate))
+		middleware.Rate(middleware.AuthIf(rest.NewIndexSearchHandler(log, searcher),
+			aaaService, rest.WantsExplain), cfg.SearchRate))
+
+	// похожие комиксы, считаются по всему индексу - ограничиваем как isearch
+	mux.Handle("GET /api/comics/{id}/similar",
+		middleware.Rate(rest.NewSimilarHandler(log, searchClient), cfg.SearchRate))
+
+	// состояние индексов поиска по шардам
+	mux.Handle("GET /api/search/stats", rest.NewSearchStatsHandler(log, searchClient))
+
+	// словарь синонимов поиска, правит только администратор
+	mux.Handle("GET /api/admin/synonyms",
+		middleware.Auth(rest.NewGetSynonymsHandler(log, searchClient), aaaService))
+
+	mux.Handle("PUT /api/admin/synonyms",
+		middleware.Auth(rest.NewSetSynonymsHandler(log, searchClient), aaaService))
+
+	// аналитика запросов за окно (?from=&to= или ?window=), только для администратора
+	if storage != nil {
+		mux.Handle("GET /api/admin/analytics/top-queries",
+			middleware.Auth(rest.NewTopQueriesHandler(log, storage), aaaService))
+
+		mux.Handle("GET /api/admin/analytics/zero-results",
+			middleware.Auth(rest.NewZeroResultsHandler(log, storage), aaaService))
+	}
+
+	// подсказки для поиска по мере набора
+	mux.Handle("GET /api/suggest", rest.NewSuggestHandler(log, searchClient))
 
 	// ping: words + update + search
 	mux.Handle("GET /api/ping", rest.NewPingHandler(log, map[string]core.Pinger{
@@ -114,8 +170,21 @@ func run() error {
 		Handler:     mux,
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
+	return serve(ctx, log, server, tasks...)
+}
+
+// serve запускает фоновые задачи и HTTP сервер. Задачи останавливаются
+// и дожидаются вместе с сервером: и по сигналу, и если сервер не поднялся.
+func serve(ctx context.Context, log *slog.Logger, server *http.Server, tasks ...func(context.Context)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	for _, task := range tasks {
+		wg.Go(func() { task(ctx) })
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -125,7 +194,7 @@ func run() error {
 		}
 	}()
 
-	log.Info("Running HTTP server", "address", cfg.HTTPConfig.Address)
+	log.Info("Running HTTP server", "address", server.Addr)
 	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Error("server closed unexpectedly", "error", err)
 		return err
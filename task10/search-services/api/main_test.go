@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServe_StopsTasksOnServerError(t *testing.T) {
+	// порт занят - сервер не поднимается
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	stopped := make(chan struct{})
+	task := func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+		done <- serve(context.Background(), log, &http.Server{Addr: busy.Addr().String()}, task)
+	}()
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after server error")
+	}
+	// задача остановлена до возврата из serve
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("task is still running")
+	}
+}
@@ -23,9 +23,16 @@ const (
 )
 
 type SearchRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Phrase        string                 `protobuf:"bytes,1,opt,name=phrase,proto3" json:"phrase,omitempty"`
-	Limit         int64                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Phrase string                 `protobuf:"bytes,1,opt,name=phrase,proto3" json:"phrase,omitempty"`
+	Limit  int64                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// допустимое число опечаток в слове: 0 - только точные совпадения,
+	// 1 или 2 - не больше стольких, -1 - в зависимости от длины слова
+	Fuzziness int32 `protobuf:"varint,3,opt,name=fuzziness,proto3" json:"fuzziness,omitempty"`
+	// сколько первых найденных комиксов пропустить
+	Offset int64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	// приложить к комиксам разбор оценки
+	Explain       bool `protobuf:"varint,5,opt,name=explain,proto3" json:"explain,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -74,10 +81,45 @@ func (x *SearchRequest) GetLimit() int64 {
 	return 0
 }
 
+func (x *SearchRequest) GetFuzziness() int32 {
+	if x != nil {
+		return x.Fuzziness
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetExplain() bool {
+	if x != nil {
+		return x.Explain
+	}
+	return false
+}
+
 type Comic struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url   string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	// оценка комикса в выдаче, по ней шлюз сливает ответы шардов
+	Score      float64 `protobuf:"fixed64,3,opt,name=score,proto3" json:"score,omitempty"`
+	SafeTitle  string  `protobuf:"bytes,4,opt,name=safe_title,json=safeTitle,proto3" json:"safe_title,omitempty"`
+	Title      string  `protobuf:"bytes,5,opt,name=title,proto3" json:"title,omitempty"`
+	Alt        string  `protobuf:"bytes,6,opt,name=alt,proto3" json:"alt,omitempty"`
+	Transcript string  `protobuf:"bytes,7,opt,name=transcript,proto3" json:"transcript,omitempty"`
+	// дата выхода в виде YYYY-MM-DD, пусто если неизвестна
+	Published string `protobuf:"bytes,8,opt,name=published,proto3" json:"published,omitempty"`
+	Link      string `protobuf:"bytes,9,opt,name=link,proto3" json:"link,omitempty"`
+	News      string `protobuf:"bytes,10,opt,name=news,proto3" json:"news,omitempty"`
+	// где и почему комикс нашёлся, может отсутствовать
+	Snippet *Snippet `protobuf:"bytes,11,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	// разбор оценки, только если он запрошен
+	Explanation   *Explanation `protobuf:"bytes,12,opt,name=explanation,proto3" json:"explanation,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -126,27 +168,107 @@ func (x *Comic) GetUrl() string {
 	return ""
 }
 
-type SearchReply struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Comics        []*Comic               `protobuf:"bytes,1,rep,name=comics,proto3" json:"comics,omitempty"`
+func (x *Comic) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Comic) GetSafeTitle() string {
+	if x != nil {
+		return x.SafeTitle
+	}
+	return ""
+}
+
+func (x *Comic) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Comic) GetAlt() string {
+	if x != nil {
+		return x.Alt
+	}
+	return ""
+}
+
+func (x *Comic) GetTranscript() string {
+	if x != nil {
+		return x.Transcript
+	}
+	return ""
+}
+
+func (x *Comic) GetPublished() string {
+	if x != nil {
+		return x.Published
+	}
+	return ""
+}
+
+func (x *Comic) GetLink() string {
+	if x != nil {
+		return x.Link
+	}
+	return ""
+}
+
+func (x *Comic) GetNews() string {
+	if x != nil {
+		return x.News
+	}
+	return ""
+}
+
+func (x *Comic) GetSnippet() *Snippet {
+	if x != nil {
+		return x.Snippet
+	}
+	return nil
+}
+
+func (x *Comic) GetExplanation() *Explanation {
+	if x != nil {
+		return x.Explanation
+	}
+	return nil
+}
+
+// Explanation - из чего сложилась оценка комикса. tie_break - признак
+// (score, matches, ratio или id), по которому комикс выше следующего в выдаче
+type Explanation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// слова запроса после нормализации, с найденными опечатками
+	Terms   []string     `protobuf:"bytes,1,rep,name=terms,proto3" json:"terms,omitempty"`
+	Matched []*TermScore `protobuf:"bytes,2,rep,name=matched,proto3" json:"matched,omitempty"`
+	// множитель за близость совпавших слов
+	Proximity     float64 `protobuf:"fixed64,3,opt,name=proximity,proto3" json:"proximity,omitempty"`
+	Matches       int64   `protobuf:"varint,4,opt,name=matches,proto3" json:"matches,omitempty"`
+	Ratio         float64 `protobuf:"fixed64,5,opt,name=ratio,proto3" json:"ratio,omitempty"`
+	Score         float64 `protobuf:"fixed64,6,opt,name=score,proto3" json:"score,omitempty"`
+	TieBreak      string  `protobuf:"bytes,7,opt,name=tie_break,json=tieBreak,proto3" json:"tie_break,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SearchReply) Reset() {
-	*x = SearchReply{}
+func (x *Explanation) Reset() {
+	*x = Explanation{}
 	mi := &file_proto_search_search_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SearchReply) String() string {
+func (x *Explanation) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SearchReply) ProtoMessage() {}
+func (*Explanation) ProtoMessage() {}
 
-func (x *SearchReply) ProtoReflect() protoreflect.Message {
+func (x *Explanation) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_search_search_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -158,35 +280,898 @@ func (x *SearchReply) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SearchReply.ProtoReflect.Descriptor instead.
-func (*SearchReply) Descriptor() ([]byte, []int) {
+// Deprecated: Use Explanation.ProtoReflect.Descriptor instead.
+func (*Explanation) Descriptor() ([]byte, []int) {
 	return file_proto_search_search_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *SearchReply) GetComics() []*Comic {
+func (x *Explanation) GetTerms() []string {
 	if x != nil {
-		return x.Comics
+		return x.Terms
 	}
 	return nil
 }
 
-var File_proto_search_search_proto protoreflect.FileDescriptor
+func (x *Explanation) GetMatched() []*TermScore {
+	if x != nil {
+		return x.Matched
+	}
+	return nil
+}
 
-const file_proto_search_search_proto_rawDesc = "" +
-	"\n" +
-	"\x19proto/search/search.proto\x12\x06search\x1a\x1bgoogle/protobuf/empty.proto\"=\n" +
-	"\rSearchRequest\x12\x16\n" +
-	"\x06phrase\x18\x01 \x01(\tR\x06phrase\x12\x14\n" +
-	"\x05limit\x18\x02 \x01(\x03R\x05limit\")\n" +
-	"\x05Comic\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x10\n" +
-	"\x03url\x18\x02 \x01(\tR\x03url\"4\n" +
-	"\vSearchReply\x12%\n" +
-	"\x06comics\x18\x01 \x03(\v2\r.search.ComicR\x06comics2\xb7\x01\n" +
+func (x *Explanation) GetProximity() float64 {
+	if x != nil {
+		return x.Proximity
+	}
+	return 0
+}
+
+func (x *Explanation) GetMatches() int64 {
+	if x != nil {
+		return x.Matches
+	}
+	return 0
+}
+
+func (x *Explanation) GetRatio() float64 {
+	if x != nil {
+		return x.Ratio
+	}
+	return 0
+}
+
+func (x *Explanation) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Explanation) GetTieBreak() string {
+	if x != nil {
+		return x.TieBreak
+	}
+	return ""
+}
+
+// TermScore - вклад совпавшего слова в оценку по BM25
+type TermScore struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          string                 `protobuf:"bytes,1,opt,name=term,proto3" json:"term,omitempty"`
+	Field         string                 `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	Dist          int32                  `protobuf:"varint,3,opt,name=dist,proto3" json:"dist,omitempty"`
+	Weight        float64                `protobuf:"fixed64,4,opt,name=weight,proto3" json:"weight,omitempty"`
+	Tf            int64                  `protobuf:"varint,5,opt,name=tf,proto3" json:"tf,omitempty"`
+	Idf           float64                `protobuf:"fixed64,6,opt,name=idf,proto3" json:"idf,omitempty"`
+	Score         float64                `protobuf:"fixed64,7,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TermScore) Reset() {
+	*x = TermScore{}
+	mi := &file_proto_search_search_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TermScore) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TermScore) ProtoMessage() {}
+
+func (x *TermScore) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TermScore.ProtoReflect.Descriptor instead.
+func (*TermScore) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TermScore) GetTerm() string {
+	if x != nil {
+		return x.Term
+	}
+	return ""
+}
+
+func (x *TermScore) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *TermScore) GetDist() int32 {
+	if x != nil {
+		return x.Dist
+	}
+	return 0
+}
+
+func (x *TermScore) GetWeight() float64 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *TermScore) GetTf() int64 {
+	if x != nil {
+		return x.Tf
+	}
+	return 0
+}
+
+func (x *TermScore) GetIdf() float64 {
+	if x != nil {
+		return x.Idf
+	}
+	return 0
+}
+
+func (x *TermScore) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+// Snippet - кусок названия (title), alt или расшифровки (transcript)
+// с найденными словами запроса
+type Snippet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Highlights    []*Highlight           `protobuf:"bytes,3,rep,name=highlights,proto3" json:"highlights,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Snippet) Reset() {
+	*x = Snippet{}
+	mi := &file_proto_search_search_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Snippet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Snippet) ProtoMessage() {}
+
+func (x *Snippet) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Snippet.ProtoReflect.Descriptor instead.
+func (*Snippet) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Snippet) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *Snippet) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Snippet) GetHighlights() []*Highlight {
+	if x != nil {
+		return x.Highlights
+	}
+	return nil
+}
+
+// Highlight - найденное слово, байтовый отрезок [start, end) в тексте сниппета
+type Highlight struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int32                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int32                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Highlight) Reset() {
+	*x = Highlight{}
+	mi := &file_proto_search_search_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Highlight) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Highlight) ProtoMessage() {}
+
+func (x *Highlight) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Highlight.ProtoReflect.Descriptor instead.
+func (*Highlight) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Highlight) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Highlight) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+type SearchReply struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Comics []*Comic               `protobuf:"bytes,1,rep,name=comics,proto3" json:"comics,omitempty"`
+	// сколько всего комиксов нашлось без учёта limit и offset
+	Total int64 `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	// поколение индекса, по которому посчитана выдача: с другим поколением
+	// страницы могут сдвинуться. 0 - поиск его не отслеживает
+	Generation    uint64 `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchReply) Reset() {
+	*x = SearchReply{}
+	mi := &file_proto_search_search_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchReply) ProtoMessage() {}
+
+func (x *SearchReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchReply.ProtoReflect.Descriptor instead.
+func (*SearchReply) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SearchReply) GetComics() []*Comic {
+	if x != nil {
+		return x.Comics
+	}
+	return nil
+}
+
+func (x *SearchReply) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *SearchReply) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+type SimilarRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Limit         int64                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SimilarRequest) Reset() {
+	*x = SimilarRequest{}
+	mi := &file_proto_search_search_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SimilarRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SimilarRequest) ProtoMessage() {}
+
+func (x *SimilarRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SimilarRequest.ProtoReflect.Descriptor instead.
+func (*SimilarRequest) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SimilarRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SimilarRequest) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SuggestRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Limit         int64                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuggestRequest) Reset() {
+	*x = SuggestRequest{}
+	mi := &file_proto_search_search_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuggestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuggestRequest) ProtoMessage() {}
+
+func (x *SuggestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuggestRequest.ProtoReflect.Descriptor instead.
+func (*SuggestRequest) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SuggestRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *SuggestRequest) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type Suggestion struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Word  string                 `protobuf:"bytes,1,opt,name=word,proto3" json:"word,omitempty"`
+	// в скольких комиксах встречается слово
+	Count         int64 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Suggestion) Reset() {
+	*x = Suggestion{}
+	mi := &file_proto_search_search_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Suggestion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Suggestion) ProtoMessage() {}
+
+func (x *Suggestion) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Suggestion.ProtoReflect.Descriptor instead.
+func (*Suggestion) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Suggestion) GetWord() string {
+	if x != nil {
+		return x.Word
+	}
+	return ""
+}
+
+func (x *Suggestion) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type SuggestReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Suggestions   []*Suggestion          `protobuf:"bytes,1,rep,name=suggestions,proto3" json:"suggestions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuggestReply) Reset() {
+	*x = SuggestReply{}
+	mi := &file_proto_search_search_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuggestReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuggestReply) ProtoMessage() {}
+
+func (x *SuggestReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuggestReply.ProtoReflect.Descriptor instead.
+func (*SuggestReply) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SuggestReply) GetSuggestions() []*Suggestion {
+	if x != nil {
+		return x.Suggestions
+	}
+	return nil
+}
+
+// StatsReply - состояние индекса шарда. Время в формате RFC 3339,
+// пустая строка - события ещё не было
+type StatsReply struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Comics int64                  `protobuf:"varint,1,opt,name=comics,proto3" json:"comics,omitempty"`
+	// размер словаря
+	Words int64 `protobuf:"varint,2,opt,name=words,proto3" json:"words,omitempty"`
+	// сколько всего пар слово-комикс
+	Postings int64 `protobuf:"varint,3,opt,name=postings,proto3" json:"postings,omitempty"`
+	// растёт при каждой замене или правке индекса
+	Generation        uint64 `protobuf:"varint,4,opt,name=generation,proto3" json:"generation,omitempty"`
+	LastRebuild       string `protobuf:"bytes,5,opt,name=last_rebuild,json=lastRebuild,proto3" json:"last_rebuild,omitempty"`
+	RebuildDurationMs int64  `protobuf:"varint,6,opt,name=rebuild_duration_ms,json=rebuildDurationMs,proto3" json:"rebuild_duration_ms,omitempty"`
+	// ошибка последней неудачной перестройки или правки индекса
+	LastError     string      `protobuf:"bytes,7,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LastErrorAt   string      `protobuf:"bytes,8,opt,name=last_error_at,json=lastErrorAt,proto3" json:"last_error_at,omitempty"`
+	Cache         *CacheStats `protobuf:"bytes,9,opt,name=cache,proto3" json:"cache,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsReply) Reset() {
+	*x = StatsReply{}
+	mi := &file_proto_search_search_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsReply) ProtoMessage() {}
+
+func (x *StatsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsReply.ProtoReflect.Descriptor instead.
+func (*StatsReply) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StatsReply) GetComics() int64 {
+	if x != nil {
+		return x.Comics
+	}
+	return 0
+}
+
+func (x *StatsReply) GetWords() int64 {
+	if x != nil {
+		return x.Words
+	}
+	return 0
+}
+
+func (x *StatsReply) GetPostings() int64 {
+	if x != nil {
+		return x.Postings
+	}
+	return 0
+}
+
+func (x *StatsReply) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *StatsReply) GetLastRebuild() string {
+	if x != nil {
+		return x.LastRebuild
+	}
+	return ""
+}
+
+func (x *StatsReply) GetRebuildDurationMs() int64 {
+	if x != nil {
+		return x.RebuildDurationMs
+	}
+	return 0
+}
+
+func (x *StatsReply) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *StatsReply) GetLastErrorAt() string {
+	if x != nil {
+		return x.LastErrorAt
+	}
+	return ""
+}
+
+func (x *StatsReply) GetCache() *CacheStats {
+	if x != nil {
+		return x.Cache
+	}
+	return nil
+}
+
+type CacheStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hits          uint64                 `protobuf:"varint,1,opt,name=hits,proto3" json:"hits,omitempty"`
+	Misses        uint64                 `protobuf:"varint,2,opt,name=misses,proto3" json:"misses,omitempty"`
+	Entries       int64                  `protobuf:"varint,3,opt,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheStats) Reset() {
+	*x = CacheStats{}
+	mi := &file_proto_search_search_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheStats) ProtoMessage() {}
+
+func (x *CacheStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheStats.ProtoReflect.Descriptor instead.
+func (*CacheStats) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CacheStats) GetHits() uint64 {
+	if x != nil {
+		return x.Hits
+	}
+	return 0
+}
+
+func (x *CacheStats) GetMisses() uint64 {
+	if x != nil {
+		return x.Misses
+	}
+	return 0
+}
+
+func (x *CacheStats) GetEntries() int64 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
+}
+
+// словарь синонимов: строка - группа через запятую, # - комментарий
+type SynonymsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SynonymsRequest) Reset() {
+	*x = SynonymsRequest{}
+	mi := &file_proto_search_search_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SynonymsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SynonymsRequest) ProtoMessage() {}
+
+func (x *SynonymsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SynonymsRequest.ProtoReflect.Descriptor instead.
+func (*SynonymsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SynonymsRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type SynonymsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SynonymsReply) Reset() {
+	*x = SynonymsReply{}
+	mi := &file_proto_search_search_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SynonymsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SynonymsReply) ProtoMessage() {}
+
+func (x *SynonymsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_search_search_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SynonymsReply.ProtoReflect.Descriptor instead.
+func (*SynonymsReply) Descriptor() ([]byte, []int) {
+	return file_proto_search_search_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SynonymsReply) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+var File_proto_search_search_proto protoreflect.FileDescriptor
+
+const file_proto_search_search_proto_rawDesc = "" +
+	"\n" +
+	"\x19proto/search/search.proto\x12\x06search\x1a\x1bgoogle/protobuf/empty.proto\"\x8d\x01\n" +
+	"\rSearchRequest\x12\x16\n" +
+	"\x06phrase\x18\x01 \x01(\tR\x06phrase\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x03R\x05limit\x12\x1c\n" +
+	"\tfuzziness\x18\x03 \x01(\x05R\tfuzziness\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\x12\x18\n" +
+	"\aexplain\x18\x05 \x01(\bR\aexplain\"\xce\x02\n" +
+	"\x05Comic\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x01R\x05score\x12\x1d\n" +
+	"\n" +
+	"safe_title\x18\x04 \x01(\tR\tsafeTitle\x12\x14\n" +
+	"\x05title\x18\x05 \x01(\tR\x05title\x12\x10\n" +
+	"\x03alt\x18\x06 \x01(\tR\x03alt\x12\x1e\n" +
+	"\n" +
+	"transcript\x18\a \x01(\tR\n" +
+	"transcript\x12\x1c\n" +
+	"\tpublished\x18\b \x01(\tR\tpublished\x12\x12\n" +
+	"\x04link\x18\t \x01(\tR\x04link\x12\x12\n" +
+	"\x04news\x18\n" +
+	" \x01(\tR\x04news\x12)\n" +
+	"\asnippet\x18\v \x01(\v2\x0f.search.SnippetR\asnippet\x125\n" +
+	"\vexplanation\x18\f \x01(\v2\x13.search.ExplanationR\vexplanation\"\xd1\x01\n" +
+	"\vExplanation\x12\x14\n" +
+	"\x05terms\x18\x01 \x03(\tR\x05terms\x12+\n" +
+	"\amatched\x18\x02 \x03(\v2\x11.search.TermScoreR\amatched\x12\x1c\n" +
+	"\tproximity\x18\x03 \x01(\x01R\tproximity\x12\x18\n" +
+	"\amatches\x18\x04 \x01(\x03R\amatches\x12\x14\n" +
+	"\x05ratio\x18\x05 \x01(\x01R\x05ratio\x12\x14\n" +
+	"\x05score\x18\x06 \x01(\x01R\x05score\x12\x1b\n" +
+	"\ttie_break\x18\a \x01(\tR\btieBreak\"\x99\x01\n" +
+	"\tTermScore\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\tR\x04term\x12\x14\n" +
+	"\x05field\x18\x02 \x01(\tR\x05field\x12\x12\n" +
+	"\x04dist\x18\x03 \x01(\x05R\x04dist\x12\x16\n" +
+	"\x06weight\x18\x04 \x01(\x01R\x06weight\x12\x0e\n" +
+	"\x02tf\x18\x05 \x01(\x03R\x02tf\x12\x10\n" +
+	"\x03idf\x18\x06 \x01(\x01R\x03idf\x12\x14\n" +
+	"\x05score\x18\a \x01(\x01R\x05score\"f\n" +
+	"\aSnippet\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x121\n" +
+	"\n" +
+	"highlights\x18\x03 \x03(\v2\x11.search.HighlightR\n" +
+	"highlights\"3\n" +
+	"\tHighlight\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x05R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x05R\x03end\"j\n" +
+	"\vSearchReply\x12%\n" +
+	"\x06comics\x18\x01 \x03(\v2\r.search.ComicR\x06comics\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x04R\n" +
+	"generation\"6\n" +
+	"\x0eSimilarRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x03R\x05limit\">\n" +
+	"\x0eSuggestRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x03R\x05limit\"6\n" +
+	"\n" +
+	"Suggestion\x12\x12\n" +
+	"\x04word\x18\x01 \x01(\tR\x04word\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"D\n" +
+	"\fSuggestReply\x124\n" +
+	"\vsuggestions\x18\x01 \x03(\v2\x12.search.SuggestionR\vsuggestions\"\xb6\x02\n" +
+	"\n" +
+	"StatsReply\x12\x16\n" +
+	"\x06comics\x18\x01 \x01(\x03R\x06comics\x12\x14\n" +
+	"\x05words\x18\x02 \x01(\x03R\x05words\x12\x1a\n" +
+	"\bpostings\x18\x03 \x01(\x03R\bpostings\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x04 \x01(\x04R\n" +
+	"generation\x12!\n" +
+	"\flast_rebuild\x18\x05 \x01(\tR\vlastRebuild\x12.\n" +
+	"\x13rebuild_duration_ms\x18\x06 \x01(\x03R\x11rebuildDurationMs\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\a \x01(\tR\tlastError\x12\"\n" +
+	"\rlast_error_at\x18\b \x01(\tR\vlastErrorAt\x12(\n" +
+	"\x05cache\x18\t \x01(\v2\x12.search.CacheStatsR\x05cache\"R\n" +
+	"\n" +
+	"CacheStats\x12\x12\n" +
+	"\x04hits\x18\x01 \x01(\x04R\x04hits\x12\x16\n" +
+	"\x06misses\x18\x02 \x01(\x04R\x06misses\x12\x18\n" +
+	"\aentries\x18\x03 \x01(\x03R\aentries\"%\n" +
+	"\x0fSynonymsRequest\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\"#\n" +
+	"\rSynonymsReply\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text2\xe5\x03\n" +
 	"\x06Search\x128\n" +
 	"\x04Ping\x12\x16.google.protobuf.Empty\x1a\x16.google.protobuf.Empty\"\x00\x126\n" +
 	"\x06Search\x12\x15.search.SearchRequest\x1a\x13.search.SearchReply\"\x00\x12;\n" +
-	"\vIndexSearch\x12\x15.search.SearchRequest\x1a\x13.search.SearchReply\"\x00B\x1fZ\x1dyadro.com/course/proto/searchb\x06proto3"
+	"\vIndexSearch\x12\x15.search.SearchRequest\x1a\x13.search.SearchReply\"\x00\x129\n" +
+	"\aSuggest\x12\x16.search.SuggestRequest\x1a\x14.search.SuggestReply\"\x00\x128\n" +
+	"\aSimilar\x12\x16.search.SimilarRequest\x1a\x13.search.SearchReply\"\x00\x125\n" +
+	"\x05Stats\x12\x16.google.protobuf.Empty\x1a\x12.search.StatsReply\"\x00\x12>\n" +
+	"\vGetSynonyms\x12\x16.google.protobuf.Empty\x1a\x15.search.SynonymsReply\"\x00\x12@\n" +
+	"\vSetSynonyms\x12\x17.search.SynonymsRequest\x1a\x16.google.protobuf.Empty\"\x00B\x1fZ\x1dyadro.com/course/proto/searchb\x06proto3"
 
 var (
 	file_proto_search_search_proto_rawDescOnce sync.Once
@@ -200,26 +1185,54 @@ func file_proto_search_search_proto_rawDescGZIP() []byte {
 	return file_proto_search_search_proto_rawDescData
 }
 
-var file_proto_search_search_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_proto_search_search_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_proto_search_search_proto_goTypes = []any{
-	(*SearchRequest)(nil), // 0: search.SearchRequest
-	(*Comic)(nil),         // 1: search.Comic
-	(*SearchReply)(nil),   // 2: search.SearchReply
-	(*empty.Empty)(nil),   // 3: google.protobuf.Empty
+	(*SearchRequest)(nil),   // 0: search.SearchRequest
+	(*Comic)(nil),           // 1: search.Comic
+	(*Explanation)(nil),     // 2: search.Explanation
+	(*TermScore)(nil),       // 3: search.TermScore
+	(*Snippet)(nil),         // 4: search.Snippet
+	(*Highlight)(nil),       // 5: search.Highlight
+	(*SearchReply)(nil),     // 6: search.SearchReply
+	(*SimilarRequest)(nil),  // 7: search.SimilarRequest
+	(*SuggestRequest)(nil),  // 8: search.SuggestRequest
+	(*Suggestion)(nil),      // 9: search.Suggestion
+	(*SuggestReply)(nil),    // 10: search.SuggestReply
+	(*StatsReply)(nil),      // 11: search.StatsReply
+	(*CacheStats)(nil),      // 12: search.CacheStats
+	(*SynonymsRequest)(nil), // 13: search.SynonymsRequest
+	(*SynonymsReply)(nil),   // 14: search.SynonymsReply
+	(*empty.Empty)(nil),     // 15: google.protobuf.Empty
 }
 var file_proto_search_search_proto_depIdxs = []int32{
-	1, // 0: search.SearchReply.comics:type_name -> search.Comic
-	3, // 1: search.Search.Ping:input_type -> google.protobuf.Empty
-	0, // 2: search.Search.Search:input_type -> search.SearchRequest
-	0, // 3: search.Search.IndexSearch:input_type -> search.SearchRequest
-	3, // 4: search.Search.Ping:output_type -> google.protobuf.Empty
-	2, // 5: search.Search.Search:output_type -> search.SearchReply
-	2, // 6: search.Search.IndexSearch:output_type -> search.SearchReply
-	4, // [4:7] is the sub-list for method output_type
-	1, // [1:4] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	4,  // 0: search.Comic.snippet:type_name -> search.Snippet
+	2,  // 1: search.Comic.explanation:type_name -> search.Explanation
+	3,  // 2: search.Explanation.matched:type_name -> search.TermScore
+	5,  // 3: search.Snippet.highlights:type_name -> search.Highlight
+	1,  // 4: search.SearchReply.comics:type_name -> search.Comic
+	9,  // 5: search.SuggestReply.suggestions:type_name -> search.Suggestion
+	12, // 6: search.StatsReply.cache:type_name -> search.CacheStats
+	15, // 7: search.Search.Ping:input_type -> google.protobuf.Empty
+	0,  // 8: search.Search.Search:input_type -> search.SearchRequest
+	0,  // 9: search.Search.IndexSearch:input_type -> search.SearchRequest
+	8,  // 10: search.Search.Suggest:input_type -> search.SuggestRequest
+	7,  // 11: search.Search.Similar:input_type -> search.SimilarRequest
+	15, // 12: search.Search.Stats:input_type -> google.protobuf.Empty
+	15, // 13: search.Search.GetSynonyms:input_type -> google.protobuf.Empty
+	13, // 14: search.Search.SetSynonyms:input_type -> search.SynonymsRequest
+	15, // 15: search.Search.Ping:output_type -> google.protobuf.Empty
+	6,  // 16: search.Search.Search:output_type -> search.SearchReply
+	6,  // 17: search.Search.IndexSearch:output_type -> search.SearchReply
+	10, // 18: search.Search.Suggest:output_type -> search.SuggestReply
+	6,  // 19: search.Search.Similar:output_type -> search.SearchReply
+	11, // 20: search.Search.Stats:output_type -> search.StatsReply
+	14, // 21: search.Search.GetSynonyms:output_type -> search.SynonymsReply
+	15, // 22: search.Search.SetSynonyms:output_type -> google.protobuf.Empty
+	15, // [15:23] is the sub-list for method output_type
+	7,  // [7:15] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_proto_search_search_proto_init() }
@@ -233,7 +1246,7 @@ func file_proto_search_search_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_search_search_proto_rawDesc), len(file_proto_search_search_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,6 +23,11 @@ const (
 	Search_Ping_FullMethodName        = "/search.Search/Ping"
 	Search_Search_FullMethodName      = "/search.Search/Search"
 	Search_IndexSearch_FullMethodName = "/search.Search/IndexSearch"
+	Search_Suggest_FullMethodName     = "/search.Search/Suggest"
+	Search_Similar_FullMethodName     = "/search.Search/Similar"
+	Search_Stats_FullMethodName       = "/search.Search/Stats"
+	Search_GetSynonyms_FullMethodName = "/search.Search/GetSynonyms"
+	Search_SetSynonyms_FullMethodName = "/search.Search/SetSynonyms"
 )
 
 // SearchClient is the client API for Search service.
@@ -32,6 +37,14 @@ type SearchClient interface {
 	Ping(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
 	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchReply, error)
 	IndexSearch(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchReply, error)
+	Suggest(ctx context.Context, in *SuggestRequest, opts ...grpc.CallOption) (*SuggestReply, error)
+	// комиксы, похожие на заданный по словам; total - сколько их всего.
+	// Комикс другого шарда читается из базы, похожие ищутся среди своих.
+	Similar(ctx context.Context, in *SimilarRequest, opts ...grpc.CallOption) (*SearchReply, error)
+	Stats(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*StatsReply, error)
+	GetSynonyms(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*SynonymsReply, error)
+	// проверяет, сохраняет и сразу применяет словарь
+	SetSynonyms(ctx context.Context, in *SynonymsRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 }
 
 type searchClient struct {
@@ -72,6 +85,56 @@ func (c *searchClient) IndexSearch(ctx context.Context, in *SearchRequest, opts
 	return out, nil
 }
 
+func (c *searchClient) Suggest(ctx context.Context, in *SuggestRequest, opts ...grpc.CallOption) (*SuggestReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SuggestReply)
+	err := c.cc.Invoke(ctx, Search_Suggest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) Similar(ctx context.Context, in *SimilarRequest, opts ...grpc.CallOption) (*SearchReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchReply)
+	err := c.cc.Invoke(ctx, Search_Similar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) Stats(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*StatsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsReply)
+	err := c.cc.Invoke(ctx, Search_Stats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) GetSynonyms(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*SynonymsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SynonymsReply)
+	err := c.cc.Invoke(ctx, Search_GetSynonyms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) SetSynonyms(ctx context.Context, in *SynonymsRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, Search_SetSynonyms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SearchServer is the server API for Search service.
 // All implementations must embed UnimplementedSearchServer
 // for forward compatibility.
@@ -79,6 +142,14 @@ type SearchServer interface {
 	Ping(context.Context, *empty.Empty) (*empty.Empty, error)
 	Search(context.Context, *SearchRequest) (*SearchReply, error)
 	IndexSearch(context.Context, *SearchRequest) (*SearchReply, error)
+	Suggest(context.Context, *SuggestRequest) (*SuggestReply, error)
+	// комиксы, похожие на заданный по словам; total - сколько их всего.
+	// Комикс другого шарда читается из базы, похожие ищутся среди своих.
+	Similar(context.Context, *SimilarRequest) (*SearchReply, error)
+	Stats(context.Context, *empty.Empty) (*StatsReply, error)
+	GetSynonyms(context.Context, *empty.Empty) (*SynonymsReply, error)
+	// проверяет, сохраняет и сразу применяет словарь
+	SetSynonyms(context.Context, *SynonymsRequest) (*empty.Empty, error)
 	mustEmbedUnimplementedSearchServer()
 }
 
@@ -98,6 +169,21 @@ func (UnimplementedSearchServer) Search(context.Context, *SearchRequest) (*Searc
 func (UnimplementedSearchServer) IndexSearch(context.Context, *SearchRequest) (*SearchReply, error) {
 	return nil, status.Error(codes.Unimplemented, "method IndexSearch not implemented")
 }
+func (UnimplementedSearchServer) Suggest(context.Context, *SuggestRequest) (*SuggestReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method Suggest not implemented")
+}
+func (UnimplementedSearchServer) Similar(context.Context, *SimilarRequest) (*SearchReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method Similar not implemented")
+}
+func (UnimplementedSearchServer) Stats(context.Context, *empty.Empty) (*StatsReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedSearchServer) GetSynonyms(context.Context, *empty.Empty) (*SynonymsReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetSynonyms not implemented")
+}
+func (UnimplementedSearchServer) SetSynonyms(context.Context, *SynonymsRequest) (*empty.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetSynonyms not implemented")
+}
 func (UnimplementedSearchServer) mustEmbedUnimplementedSearchServer() {}
 func (UnimplementedSearchServer) testEmbeddedByValue()                {}
 
@@ -173,6 +259,96 @@ func _Search_IndexSearch_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Search_Suggest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuggestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Suggest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Search_Suggest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Suggest(ctx, req.(*SuggestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_Similar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimilarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Similar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Search_Similar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Similar(ctx, req.(*SimilarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Search_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Stats(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_GetSynonyms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).GetSynonyms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Search_GetSynonyms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).GetSynonyms(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_SetSynonyms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SynonymsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).SetSynonyms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Search_SetSynonyms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).SetSynonyms(ctx, req.(*SynonymsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Search_ServiceDesc is the grpc.ServiceDesc for Search service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -192,6 +368,26 @@ var Search_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "IndexSearch",
 			Handler:    _Search_IndexSearch_Handler,
 		},
+		{
+			MethodName: "Suggest",
+			Handler:    _Search_Suggest_Handler,
+		},
+		{
+			MethodName: "Similar",
+			Handler:    _Search_Similar_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _Search_Stats_Handler,
+		},
+		{
+			MethodName: "GetSynonyms",
+			Handler:    _Search_GetSynonyms_Handler,
+		},
+		{
+			MethodName: "SetSynonyms",
+			Handler:    _Search_SetSynonyms_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/search/search.proto",
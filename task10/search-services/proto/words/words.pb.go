@@ -23,8 +23,15 @@ const (
 )
 
 type WordsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Phrase        string                 `protobuf:"bytes,1,opt,name=phrase,proto3" json:"phrase,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Phrase string                 `protobuf:"bytes,1,opt,name=phrase,proto3" json:"phrase,omitempty"`
+	// вернуть также все слова фразы с позициями
+	Positions bool `protobuf:"varint,2,opt,name=positions,proto3" json:"positions,omitempty"`
+	// english, russian или auto: язык по алфавиту каждого слова.
+	// Пусто - auto, update и search так нормализуют и комиксы, и запросы.
+	Language string `protobuf:"bytes,3,opt,name=language,proto3" json:"language,omitempty"`
+	// анализатор из words/config.yaml, пусто - default
+	Analyzer      string `protobuf:"bytes,4,opt,name=analyzer,proto3" json:"analyzer,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -66,16 +73,101 @@ func (x *WordsRequest) GetPhrase() string {
 	return ""
 }
 
+func (x *WordsRequest) GetPositions() bool {
+	if x != nil {
+		return x.Positions
+	}
+	return false
+}
+
+func (x *WordsRequest) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *WordsRequest) GetAnalyzer() string {
+	if x != nil {
+		return x.Analyzer
+	}
+	return ""
+}
+
+type Token struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Word     string                 `protobuf:"bytes,1,opt,name=word,proto3" json:"word,omitempty"`
+	Position int32                  `protobuf:"varint,2,opt,name=position,proto3" json:"position,omitempty"`
+	// слово в том виде, в каком оно стояло во фразе
+	Original      string `protobuf:"bytes,3,opt,name=original,proto3" json:"original,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Token) Reset() {
+	*x = Token{}
+	mi := &file_proto_words_words_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Token) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Token) ProtoMessage() {}
+
+func (x *Token) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Token.ProtoReflect.Descriptor instead.
+func (*Token) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Token) GetWord() string {
+	if x != nil {
+		return x.Word
+	}
+	return ""
+}
+
+func (x *Token) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *Token) GetOriginal() string {
+	if x != nil {
+		return x.Original
+	}
+	return ""
+}
+
 type WordsReply struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Words         []string               `protobuf:"bytes,1,rep,name=words,proto3" json:"words,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Words  []string               `protobuf:"bytes,1,rep,name=words,proto3" json:"words,omitempty"`
+	Tokens []*Token               `protobuf:"bytes,2,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	// язык фразы: заданный в запросе или для auto - язык большинства слов
+	Language      string `protobuf:"bytes,3,opt,name=language,proto3" json:"language,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *WordsReply) Reset() {
 	*x = WordsReply{}
-	mi := &file_proto_words_words_proto_msgTypes[1]
+	mi := &file_proto_words_words_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -87,7 +179,7 @@ func (x *WordsReply) String() string {
 func (*WordsReply) ProtoMessage() {}
 
 func (x *WordsReply) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_words_words_proto_msgTypes[1]
+	mi := &file_proto_words_words_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -100,7 +192,7 @@ func (x *WordsReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WordsReply.ProtoReflect.Descriptor instead.
 func (*WordsReply) Descriptor() ([]byte, []int) {
-	return file_proto_words_words_proto_rawDescGZIP(), []int{1}
+	return file_proto_words_words_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *WordsReply) GetWords() []string {
@@ -110,19 +202,263 @@ func (x *WordsReply) GetWords() []string {
 	return nil
 }
 
+func (x *WordsReply) GetTokens() []*Token {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+func (x *WordsReply) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+// NormBatch нормализует фразы независимо: ответ на каждую - на том же месте
+type WordsBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*WordsRequest        `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WordsBatchRequest) Reset() {
+	*x = WordsBatchRequest{}
+	mi := &file_proto_words_words_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WordsBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordsBatchRequest) ProtoMessage() {}
+
+func (x *WordsBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordsBatchRequest.ProtoReflect.Descriptor instead.
+func (*WordsBatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WordsBatchRequest) GetItems() []*WordsRequest {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// WordsItem - ответ на одну фразу пачки или потока. code - код gRPC,
+// при ошибке (например, слишком длинной фразе) reply пустой, а в error описание.
+type WordsItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reply         *WordsReply            `protobuf:"bytes,1,opt,name=reply,proto3" json:"reply,omitempty"`
+	Code          int32                  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WordsItem) Reset() {
+	*x = WordsItem{}
+	mi := &file_proto_words_words_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WordsItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordsItem) ProtoMessage() {}
+
+func (x *WordsItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordsItem.ProtoReflect.Descriptor instead.
+func (*WordsItem) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WordsItem) GetReply() *WordsReply {
+	if x != nil {
+		return x.Reply
+	}
+	return nil
+}
+
+func (x *WordsItem) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *WordsItem) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type WordsBatchReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*WordsItem           `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WordsBatchReply) Reset() {
+	*x = WordsBatchReply{}
+	mi := &file_proto_words_words_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WordsBatchReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordsBatchReply) ProtoMessage() {}
+
+func (x *WordsBatchReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordsBatchReply.ProtoReflect.Descriptor instead.
+func (*WordsBatchReply) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WordsBatchReply) GetItems() []*WordsItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type InfoReply struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// меняется с каждой правкой словарей стоп-слов, защищённых слов и основ
+	// и с изменением настроек анализаторов:
+	// нормализованное при другой версии пора нормализовать заново
+	DictionaryVersion string   `protobuf:"bytes,1,opt,name=dictionary_version,json=dictionaryVersion,proto3" json:"dictionary_version,omitempty"`
+	Analyzers         []string `protobuf:"bytes,2,rep,name=analyzers,proto3" json:"analyzers,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *InfoReply) Reset() {
+	*x = InfoReply{}
+	mi := &file_proto_words_words_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoReply) ProtoMessage() {}
+
+func (x *InfoReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoReply.ProtoReflect.Descriptor instead.
+func (*InfoReply) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *InfoReply) GetDictionaryVersion() string {
+	if x != nil {
+		return x.DictionaryVersion
+	}
+	return ""
+}
+
+func (x *InfoReply) GetAnalyzers() []string {
+	if x != nil {
+		return x.Analyzers
+	}
+	return nil
+}
+
 var File_proto_words_words_proto protoreflect.FileDescriptor
 
 const file_proto_words_words_proto_rawDesc = "" +
 	"\n" +
-	"\x17proto/words/words.proto\x12\x05words\x1a\x1bgoogle/protobuf/empty.proto\"&\n" +
+	"\x17proto/words/words.proto\x12\x05words\x1a\x1bgoogle/protobuf/empty.proto\"|\n" +
 	"\fWordsRequest\x12\x16\n" +
-	"\x06phrase\x18\x01 \x01(\tR\x06phrase\"\"\n" +
+	"\x06phrase\x18\x01 \x01(\tR\x06phrase\x12\x1c\n" +
+	"\tpositions\x18\x02 \x01(\bR\tpositions\x12\x1a\n" +
+	"\blanguage\x18\x03 \x01(\tR\blanguage\x12\x1a\n" +
+	"\banalyzer\x18\x04 \x01(\tR\banalyzer\"S\n" +
+	"\x05Token\x12\x12\n" +
+	"\x04word\x18\x01 \x01(\tR\x04word\x12\x1a\n" +
+	"\bposition\x18\x02 \x01(\x05R\bposition\x12\x1a\n" +
+	"\boriginal\x18\x03 \x01(\tR\boriginal\"d\n" +
 	"\n" +
 	"WordsReply\x12\x14\n" +
-	"\x05words\x18\x01 \x03(\tR\x05words2s\n" +
+	"\x05words\x18\x01 \x03(\tR\x05words\x12$\n" +
+	"\x06tokens\x18\x02 \x03(\v2\f.words.TokenR\x06tokens\x12\x1a\n" +
+	"\blanguage\x18\x03 \x01(\tR\blanguage\">\n" +
+	"\x11WordsBatchRequest\x12)\n" +
+	"\x05items\x18\x01 \x03(\v2\x13.words.WordsRequestR\x05items\"^\n" +
+	"\tWordsItem\x12'\n" +
+	"\x05reply\x18\x01 \x01(\v2\x11.words.WordsReplyR\x05reply\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"9\n" +
+	"\x0fWordsBatchReply\x12&\n" +
+	"\x05items\x18\x01 \x03(\v2\x10.words.WordsItemR\x05items\"X\n" +
+	"\tInfoReply\x12-\n" +
+	"\x12dictionary_version\x18\x01 \x01(\tR\x11dictionaryVersion\x12\x1c\n" +
+	"\tanalyzers\x18\x02 \x03(\tR\tanalyzers2\xa3\x02\n" +
 	"\x05Words\x128\n" +
-	"\x04Ping\x12\x16.google.protobuf.Empty\x1a\x16.google.protobuf.Empty\"\x00\x120\n" +
-	"\x04Norm\x12\x13.words.WordsRequest\x1a\x11.words.WordsReply\"\x00B\x1eZ\x1cyadro.com/course/proto/wordsb\x06proto3"
+	"\x04Ping\x12\x16.google.protobuf.Empty\x1a\x16.google.protobuf.Empty\"\x00\x122\n" +
+	"\x04Info\x12\x16.google.protobuf.Empty\x1a\x10.words.InfoReply\"\x00\x120\n" +
+	"\x04Norm\x12\x13.words.WordsRequest\x1a\x11.words.WordsReply\"\x00\x12?\n" +
+	"\tNormBatch\x12\x18.words.WordsBatchRequest\x1a\x16.words.WordsBatchReply\"\x00\x129\n" +
+	"\n" +
+	"NormStream\x12\x13.words.WordsRequest\x1a\x10.words.WordsItem\"\x00(\x010\x01B\x1eZ\x1cyadro.com/course/proto/wordsb\x06proto3"
 
 var (
 	file_proto_words_words_proto_rawDescOnce sync.Once
@@ -136,22 +472,37 @@ func file_proto_words_words_proto_rawDescGZIP() []byte {
 	return file_proto_words_words_proto_rawDescData
 }
 
-var file_proto_words_words_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_words_words_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_proto_words_words_proto_goTypes = []any{
-	(*WordsRequest)(nil), // 0: words.WordsRequest
-	(*WordsReply)(nil),   // 1: words.WordsReply
-	(*empty.Empty)(nil),  // 2: google.protobuf.Empty
+	(*WordsRequest)(nil),      // 0: words.WordsRequest
+	(*Token)(nil),             // 1: words.Token
+	(*WordsReply)(nil),        // 2: words.WordsReply
+	(*WordsBatchRequest)(nil), // 3: words.WordsBatchRequest
+	(*WordsItem)(nil),         // 4: words.WordsItem
+	(*WordsBatchReply)(nil),   // 5: words.WordsBatchReply
+	(*InfoReply)(nil),         // 6: words.InfoReply
+	(*empty.Empty)(nil),       // 7: google.protobuf.Empty
 }
 var file_proto_words_words_proto_depIdxs = []int32{
-	2, // 0: words.Words.Ping:input_type -> google.protobuf.Empty
-	0, // 1: words.Words.Norm:input_type -> words.WordsRequest
-	2, // 2: words.Words.Ping:output_type -> google.protobuf.Empty
-	1, // 3: words.Words.Norm:output_type -> words.WordsReply
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: words.WordsReply.tokens:type_name -> words.Token
+	0, // 1: words.WordsBatchRequest.items:type_name -> words.WordsRequest
+	2, // 2: words.WordsItem.reply:type_name -> words.WordsReply
+	4, // 3: words.WordsBatchReply.items:type_name -> words.WordsItem
+	7, // 4: words.Words.Ping:input_type -> google.protobuf.Empty
+	7, // 5: words.Words.Info:input_type -> google.protobuf.Empty
+	0, // 6: words.Words.Norm:input_type -> words.WordsRequest
+	3, // 7: words.Words.NormBatch:input_type -> words.WordsBatchRequest
+	0, // 8: words.Words.NormStream:input_type -> words.WordsRequest
+	7, // 9: words.Words.Ping:output_type -> google.protobuf.Empty
+	6, // 10: words.Words.Info:output_type -> words.InfoReply
+	2, // 11: words.Words.Norm:output_type -> words.WordsReply
+	5, // 12: words.Words.NormBatch:output_type -> words.WordsBatchReply
+	4, // 13: words.Words.NormStream:output_type -> words.WordsItem
+	9, // [9:14] is the sub-list for method output_type
+	4, // [4:9] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_proto_words_words_proto_init() }
@@ -165,7 +516,7 @@ func file_proto_words_words_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_words_words_proto_rawDesc), len(file_proto_words_words_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,8 +20,11 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Words_Ping_FullMethodName = "/words.Words/Ping"
-	Words_Norm_FullMethodName = "/words.Words/Norm"
+	Words_Ping_FullMethodName       = "/words.Words/Ping"
+	Words_Info_FullMethodName       = "/words.Words/Info"
+	Words_Norm_FullMethodName       = "/words.Words/Norm"
+	Words_NormBatch_FullMethodName  = "/words.Words/NormBatch"
+	Words_NormStream_FullMethodName = "/words.Words/NormStream"
 )
 
 // WordsClient is the client API for Words service.
@@ -31,8 +34,13 @@ const (
 // Service
 type WordsClient interface {
 	Ping(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
+	Info(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*InfoReply, error)
 	// Send name, receive greeting
 	Norm(ctx context.Context, in *WordsRequest, opts ...grpc.CallOption) (*WordsReply, error)
+	// лимит длины фразы действует на каждую фразу отдельно
+	NormBatch(ctx context.Context, in *WordsBatchRequest, opts ...grpc.CallOption) (*WordsBatchReply, error)
+	// то же потоком: на каждый запрос по ответу в том же порядке
+	NormStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WordsRequest, WordsItem], error)
 }
 
 type wordsClient struct {
@@ -53,6 +61,16 @@ func (c *wordsClient) Ping(ctx context.Context, in *empty.Empty, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *wordsClient) Info(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*InfoReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InfoReply)
+	err := c.cc.Invoke(ctx, Words_Info_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *wordsClient) Norm(ctx context.Context, in *WordsRequest, opts ...grpc.CallOption) (*WordsReply, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(WordsReply)
@@ -63,6 +81,29 @@ func (c *wordsClient) Norm(ctx context.Context, in *WordsRequest, opts ...grpc.C
 	return out, nil
 }
 
+func (c *wordsClient) NormBatch(ctx context.Context, in *WordsBatchRequest, opts ...grpc.CallOption) (*WordsBatchReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WordsBatchReply)
+	err := c.cc.Invoke(ctx, Words_NormBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wordsClient) NormStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WordsRequest, WordsItem], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Words_ServiceDesc.Streams[0], Words_NormStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WordsRequest, WordsItem]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Words_NormStreamClient = grpc.BidiStreamingClient[WordsRequest, WordsItem]
+
 // WordsServer is the server API for Words service.
 // All implementations must embed UnimplementedWordsServer
 // for forward compatibility.
@@ -70,8 +111,13 @@ func (c *wordsClient) Norm(ctx context.Context, in *WordsRequest, opts ...grpc.C
 // Service
 type WordsServer interface {
 	Ping(context.Context, *empty.Empty) (*empty.Empty, error)
+	Info(context.Context, *empty.Empty) (*InfoReply, error)
 	// Send name, receive greeting
 	Norm(context.Context, *WordsRequest) (*WordsReply, error)
+	// лимит длины фразы действует на каждую фразу отдельно
+	NormBatch(context.Context, *WordsBatchRequest) (*WordsBatchReply, error)
+	// то же потоком: на каждый запрос по ответу в том же порядке
+	NormStream(grpc.BidiStreamingServer[WordsRequest, WordsItem]) error
 	mustEmbedUnimplementedWordsServer()
 }
 
@@ -85,9 +131,18 @@ type UnimplementedWordsServer struct{}
 func (UnimplementedWordsServer) Ping(context.Context, *empty.Empty) (*empty.Empty, error) {
 	return nil, status.Error(codes.Unimplemented, "method Ping not implemented")
 }
+func (UnimplementedWordsServer) Info(context.Context, *empty.Empty) (*InfoReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method Info not implemented")
+}
 func (UnimplementedWordsServer) Norm(context.Context, *WordsRequest) (*WordsReply, error) {
 	return nil, status.Error(codes.Unimplemented, "method Norm not implemented")
 }
+func (UnimplementedWordsServer) NormBatch(context.Context, *WordsBatchRequest) (*WordsBatchReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method NormBatch not implemented")
+}
+func (UnimplementedWordsServer) NormStream(grpc.BidiStreamingServer[WordsRequest, WordsItem]) error {
+	return status.Error(codes.Unimplemented, "method NormStream not implemented")
+}
 func (UnimplementedWordsServer) mustEmbedUnimplementedWordsServer() {}
 func (UnimplementedWordsServer) testEmbeddedByValue()               {}
 
@@ -127,6 +182,24 @@ func _Words_Ping_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Words_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WordsServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Words_Info_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WordsServer).Info(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Words_Norm_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(WordsRequest)
 	if err := dec(in); err != nil {
@@ -145,6 +218,31 @@ func _Words_Norm_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Words_NormBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WordsBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WordsServer).NormBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Words_NormBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WordsServer).NormBatch(ctx, req.(*WordsBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Words_NormStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WordsServer).NormStream(&grpc.GenericServerStream[WordsRequest, WordsItem]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Words_NormStreamServer = grpc.BidiStreamingServer[WordsRequest, WordsItem]
+
 // Words_ServiceDesc is the grpc.ServiceDesc for Words service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -156,11 +254,26 @@ var Words_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _Words_Ping_Handler,
 		},
+		{
+			MethodName: "Info",
+			Handler:    _Words_Info_Handler,
+		},
 		{
 			MethodName: "Norm",
 			Handler:    _Words_Norm_Handler,
 		},
+		{
+			MethodName: "NormBatch",
+			Handler:    _Words_NormBatch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "NormStream",
+			Handler:       _Words_NormStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/words/words.proto",
 }
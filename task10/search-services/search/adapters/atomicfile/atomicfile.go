@@ -0,0 +1,36 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Write пишет data во временный файл рядом с path, сбрасывает его на диск
+// и переименовывает в path. Читатель видит либо прежний файл, либо новый
+// целиком, а падение посреди записи не оставляет обрезанного файла.
+func Write(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,26 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir", "file.txt")
+
+	require.NoError(t, Write(path, []byte("first")))
+	require.NoError(t, Write(path, []byte("second")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), data)
+
+	// временные файлы не остаются
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
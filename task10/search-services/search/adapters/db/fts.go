@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"yadro.com/course/search/core"
+)
+
+// Полнотекстовый поиск по колонке search_vector, её заполняет триггер
+// из миграций update service. Слова в ней уже нормализованы words
+// сервисом, поэтому используется конфигурация simple без своей морфологии.
+
+const searchText = `SELECT ` + comicColumns + `, ts_rank(search_vector, query) AS rank, count(*) OVER () AS total
+	FROM comics, to_tsquery('simple', $1) AS query
+	WHERE search_vector @@ query
+	ORDER BY rank DESC, id
+	LIMIT $2 OFFSET $3`
+
+const countText = `SELECT count(*) FROM comics WHERE search_vector @@ to_tsquery('simple', $1)`
+
+const suggestText = `SELECT word, count(*) AS count
+	FROM comics, unnest(words) AS word
+	WHERE word LIKE $1
+	GROUP BY word
+	ORDER BY count DESC, word
+	LIMIT $2`
+
+const textStats = `SELECT count(*) AS comics,
+	coalesce(sum(cardinality(words)), 0) AS postings,
+	(SELECT count(DISTINCT word) FROM comics, unnest(words) AS word) AS words
+	FROM comics`
+
+type rankedRow struct {
+	comicRow
+	Rank  float64 `db:"rank"`
+	Total int     `db:"total"`
+}
+
+func (db *DB) SearchText(ctx context.Context, query string, limit, offset int) (core.SearchResult, error) {
+	var rows []rankedRow
+	if err := db.conn.SelectContext(ctx, &rows, searchText, query, limit, offset); err != nil {
+		return core.SearchResult{}, err
+	}
+
+	if len(rows) == 0 {
+		if offset == 0 {
+			return core.SearchResult{}, nil
+		}
+		// страница за концом выдачи: всего найденных окном не посчитать
+		var res core.SearchResult
+		err := db.conn.GetContext(ctx, &res.Total, countText, query)
+		return res, err
+	}
+
+	comicRows := make([]comicRow, 0, len(rows))
+	for _, r := range rows {
+		comicRows = append(comicRows, r.comicRow)
+	}
+	comics, err := toComics(comicRows)
+	if err != nil {
+		return core.SearchResult{}, err
+	}
+	for i := range comics {
+		comics[i].Score = rows[i].Rank
+	}
+	return core.SearchResult{Comics: comics, Total: rows[0].Total}, nil
+}
+
+func (db *DB) SuggestText(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error) {
+	var rows []struct {
+		Word  string `db:"word"`
+		Count int    `db:"count"`
+	}
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+	if err := db.conn.SelectContext(ctx, &rows, suggestText, pattern, limit); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	res := make([]core.Suggestion, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, core.Suggestion{Word: r.Word, Count: r.Count})
+	}
+	return res, nil
+}
+
+func (db *DB) TextStats(ctx context.Context) (core.IndexStats, error) {
+	var row struct {
+		Comics   int `db:"comics"`
+		Words    int `db:"words"`
+		Postings int `db:"postings"`
+	}
+	if err := db.conn.GetContext(ctx, &row, textStats); err != nil {
+		return core.IndexStats{}, err
+	}
+	return core.IndexStats{Comics: row.Comics, Words: row.Words, Postings: row.Postings}, nil
+}
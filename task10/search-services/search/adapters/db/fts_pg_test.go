@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	updatedb "yadro.com/course/update/adapters/db"
+	updatecore "yadro.com/course/update/core"
+)
+
+// TEST_DB_ADDRESS - пустая база для проверки запросов на живом postgres:
+// схему создают миграции update, таблица comics очищается
+func newPostgresDB(t *testing.T) *DB {
+	t.Helper()
+	address := os.Getenv("TEST_DB_ADDRESS")
+	if address == "" {
+		t.Skip("TEST_DB_ADDRESS is not set")
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	writer, err := updatedb.New(log, address)
+	require.NoError(t, err)
+	require.NoError(t, writer.Migrate())
+	require.NoError(t, writer.Drop(context.Background()))
+	t.Cleanup(func() { _ = writer.Drop(context.Background()) })
+
+	// слова с позициями, как их сохраняет update
+	for _, c := range []updatecore.Comics{
+		{ID: 1, URL: "u1", Words: []string{"bobbi", "tabl", "drop"},
+			Positions: map[string][]int{"bobbi": {0}, "tabl": {1}, "drop": {2}}},
+		// те же слова не рядом, хотя по алфавиту соседи
+		{ID: 2, URL: "u2", Words: []string{"tabl", "drop", "bobbi"},
+			Positions: map[string][]int{"tabl": {0}, "drop": {1}, "bobbi": {5}}},
+	} {
+		require.NoError(t, writer.Add(context.Background(), c))
+	}
+
+	storage, err := New(log, address)
+	require.NoError(t, err)
+	return storage
+}
+
+func TestPostgresSearchText_Phrase(t *testing.T) {
+	storage := newPostgresDB(t)
+
+	testCases := []struct {
+		query string
+		ids   []int
+	}{
+		{"('bobbi' <1> 'tabl')", []int{1}},
+		{"('tabl' <1> 'drop')", []int{1, 2}},
+		{"('bobbi' <2> 'drop')", []int{1}},
+		{"('drop' <1> 'bobbi')", nil},
+		{"('bobbi' & 'drop')", []int{1, 2}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			res, err := storage.SearchText(context.Background(), tc.query, 10, 0)
+			require.NoError(t, err)
+			var ids []int
+			for _, c := range res.Comics {
+				ids = append(ids, c.ID)
+			}
+			assert.ElementsMatch(t, tc.ids, ids)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+	"yadro.com/course/search/core"
+)
+
+func TestDBSearchText(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	rows := sqlmock.NewRows([]string{"id", "url", "words", "rank", "total"}).
+		AddRow(3, "u3", "{linux,cpu}", 0.6, 12).
+		AddRow(1, "u1", "{linux}", 0.3, 12)
+	mock.ExpectQuery(`SELECT id, url, words, .* ts_rank\(search_vector, query\) .* FROM comics, to_tsquery\('simple', \$1\)`).
+		WithArgs("('linux')", 2, 4).
+		WillReturnRows(rows)
+
+	res, err := storage.SearchText(context.Background(), "('linux')", 2, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 12, res.Total)
+	require.Len(t, res.Comics, 2)
+	assert.Equal(t, core.Comic{ID: 3, URL: "u3", Words: []string{"linux", "cpu"}, Score: 0.6}, res.Comics[0])
+	assert.Equal(t, 1, res.Comics[1].ID)
+	assert.Equal(t, 0.3, res.Comics[1].Score)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBSearchText_PageAfterEnd(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	mock.ExpectQuery(`SELECT id, url, words, .* FROM comics, to_tsquery`).
+		WithArgs("('linux')", 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "words", "rank", "total"}))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM comics WHERE search_vector @@`).
+		WithArgs("('linux')").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	res, err := storage.SearchText(context.Background(), "('linux')", 10, 20)
+	require.NoError(t, err)
+	assert.Empty(t, res.Comics)
+	assert.Equal(t, 7, res.Total)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBSuggestText(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	// символы LIKE в префиксе экранируются
+	mock.ExpectQuery(`SELECT word, count\(\*\) AS count FROM comics, unnest\(words\)`).
+		WithArgs(`50\%\_%`, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"word", "count"}).AddRow("50%_off", 2))
+
+	res, err := storage.SuggestText(context.Background(), "50%_", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []core.Suggestion{{Word: "50%_off", Count: 2}}, res)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBTextStats(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	mock.ExpectQuery(`SELECT count\(\*\) AS comics, coalesce\(sum\(cardinality\(words\)\), 0\) AS postings`).
+		WillReturnRows(sqlmock.NewRows([]string{"comics", "postings", "words"}).AddRow(2, 5, 4))
+
+	st, err := storage.TextStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, core.IndexStats{Comics: 2, Words: 4, Postings: 5}, st)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
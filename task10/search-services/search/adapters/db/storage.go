@@ -2,6 +2,9 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -27,29 +30,125 @@ func New(log *slog.Logger, address string) (*DB, error) {
 	}, nil
 }
 
-func (db *DB) Search(ctx context.Context) ([]core.Comic, error) {
-	type row struct {
-		ID    int            `db:"id"`
-		URL   string         `db:"url"`
-		Words pq.StringArray `db:"words"`
+const (
+	comicColumns = `id, url, words, positions, surfaces,
+	safe_title, title, alt, transcript, published, link, news,
+	title_words, alt_words, transcript_words`
+	selectComics = `SELECT ` + comicColumns + ` FROM comics`
+)
+
+type comicRow struct {
+	ID         int            `db:"id"`
+	URL        string         `db:"url"`
+	Words      pq.StringArray `db:"words"`
+	Positions  []byte         `db:"positions"`
+	Surfaces   []byte         `db:"surfaces"`
+	SafeTitle  string         `db:"safe_title"`
+	Title      string         `db:"title"`
+	Alt        string         `db:"alt"`
+	Transcript string         `db:"transcript"`
+	Published  sql.NullTime   `db:"published"`
+	Link       string         `db:"link"`
+	News       string         `db:"news"`
+	// слова по полям, у старых записей NULL
+	TitleWords      pq.StringArray `db:"title_words"`
+	AltWords        pq.StringArray `db:"alt_words"`
+	TranscriptWords pq.StringArray `db:"transcript_words"`
+}
+
+// scanBatch - сколько строк курсора читается за раз, больше в памяти не держим
+const scanBatch = 500
+
+// Scan читает комиксы через серверный курсор пачками по scanBatch,
+// поэтому память не растёт вместе с базой
+func (db *DB) Scan(ctx context.Context, fn func(core.Comic) error) error {
+	tx, err := db.conn.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	// курсор только читает, откатывать нечего
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "DECLARE comics_scan NO SCROLL CURSOR FOR "+selectComics+" ORDER BY id"); err != nil {
+		return err
 	}
+	fetch := fmt.Sprintf("FETCH %d FROM comics_scan", scanBatch)
+	rows := make([]comicRow, 0, scanBatch)
+	for {
+		rows = rows[:0]
+		if err := tx.SelectContext(ctx, &rows, fetch); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			c, err := toComic(r)
+			if err != nil {
+				return err
+			}
+			if err := fn(c); err != nil {
+				return err
+			}
+		}
+		if len(rows) < scanBatch {
+			return nil
+		}
+	}
+}
 
-	var rows []row
-	if err := db.conn.SelectContext(ctx, &rows, "SELECT id, url, words FROM comics"); err != nil {
+// Get - комиксы с указанными id, отсутствующие в базе пропускаются
+func (db *DB) Get(ctx context.Context, ids []int) ([]core.Comic, error) {
+	var rows []comicRow
+	if err := db.conn.SelectContext(ctx, &rows, selectComics+" WHERE id = ANY($1)", pq.Array(ids)); err != nil {
 		return nil, err
 	}
+	return toComics(rows)
+}
 
+func toComics(rows []comicRow) ([]core.Comic, error) {
 	res := make([]core.Comic, 0, len(rows))
 	for _, r := range rows {
-		res = append(res, core.Comic{
-			ID:    r.ID,
-			URL:   r.URL,
-			Words: r.Words,
-		})
+		c, err := toComic(r)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
 	}
 	return res, nil
 }
 
+func toComic(r comicRow) (core.Comic, error) {
+	c := core.Comic{
+		ID:    r.ID,
+		URL:   r.URL,
+		Words: r.Words,
+		Meta: core.ComicMeta{
+			SafeTitle:  r.SafeTitle,
+			Title:      r.Title,
+			Alt:        r.Alt,
+			Transcript: r.Transcript,
+			Published:  r.Published.Time,
+			Link:       r.Link,
+			News:       r.News,
+		},
+		Fields: core.FieldWords{
+			Title:      r.TitleWords,
+			Alt:        r.AltWords,
+			Transcript: r.TranscriptWords,
+		},
+	}
+	// у записей до появления позиций колонка пустая
+	if len(r.Positions) > 0 {
+		if err := json.Unmarshal(r.Positions, &c.Positions); err != nil {
+			return core.Comic{}, fmt.Errorf("bad positions of comic %d: %w", r.ID, err)
+		}
+	}
+	if len(r.Surfaces) > 0 {
+		if err := json.Unmarshal(r.Surfaces, &c.Surfaces); err != nil {
+			return core.Comic{}, fmt.Errorf("bad surfaces of comic %d: %w", r.ID, err)
+		}
+	}
+	return c, nil
+}
+
 func (db *DB) Close() error {
 	return db.conn.Close()
 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,20 +29,38 @@ func newMockDB(t *testing.T) (*DB, sqlmock.Sqlmock) {
 	}, mock
 }
 
-func TestDBSearch_Success(t *testing.T) {
+// scanAll собирает всё, что отдал Scan
+func scanAll(storage *DB) ([]core.Comic, error) {
+	var res []core.Comic
+	err := storage.Scan(context.Background(), func(c core.Comic) error {
+		res = append(res, c)
+		return nil
+	})
+	return res, err
+}
+
+// expectCursor - курсор в транзакции только на чтение
+func expectCursor(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`DECLARE comics_scan NO SCROLL CURSOR FOR SELECT id, url, words, positions, .* FROM comics ORDER BY id`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestDBScan_Success(t *testing.T) {
 	storage, mock := newMockDB(t)
-	ctx := context.Background()
 
-	// Настраиваем ожидаемый SELECT и возвращаем несколько строк
-	rows := sqlmock.NewRows([]string{"id", "url", "words"}).
-		AddRow(1, "u1", "{foo,bar}").
-		AddRow(2, "u2", "{baz}")
+	// Настраиваем курсор и возвращаем несколько строк,
+	// у второй строки позиций нет, как у старых записей
+	rows := sqlmock.NewRows([]string{"id", "url", "words", "positions"}).
+		AddRow(1, "u1", "{foo,bar}", []byte(`{"foo":[0,2],"bar":[1]}`)).
+		AddRow(2, "u2", "{baz}", nil)
 
-	mock.ExpectQuery(`SELECT id, url, words FROM comics`).
-		WithArgs(). // аргументов нет
+	expectCursor(mock)
+	mock.ExpectQuery(`FETCH 500 FROM comics_scan`).
 		WillReturnRows(rows)
+	mock.ExpectRollback()
 
-	result, err := storage.Search(ctx)
+	result, err := scanAll(storage)
 	require.NoError(t, err)
 	require.Len(t, result, 2)
 
@@ -50,6 +69,10 @@ func TestDBSearch_Success(t *testing.T) {
 		ID:    1,
 		URL:   "u1",
 		Words: []string{"foo", "bar"},
+		Positions: map[string][]int{
+			"foo": {0, 2},
+			"bar": {1},
+		},
 	}, result[0])
 
 	// вторую тоже как бы не забываем
@@ -59,17 +82,86 @@ func TestDBSearch_Success(t *testing.T) {
 		Words: []string{"baz"},
 	}, result[1])
 
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBScan_Batches(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	// полная пачка - значит, читаем дальше, пока не придёт неполная
+	full := sqlmock.NewRows([]string{"id", "url", "words"})
+	for id := 1; id <= scanBatch; id++ {
+		full.AddRow(id, "u", "{foo}")
+	}
+	expectCursor(mock)
+	mock.ExpectQuery(`FETCH 500 FROM comics_scan`).WillReturnRows(full)
+	mock.ExpectQuery(`FETCH 500 FROM comics_scan`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "words"}).AddRow(scanBatch+1, "u", "{bar}"))
+	mock.ExpectRollback()
+
+	result, err := scanAll(storage)
+	require.NoError(t, err)
+	require.Len(t, result, scanBatch+1)
+	assert.Equal(t, scanBatch+1, result[scanBatch].ID)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBScan_Meta(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	published := time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "url", "words", "positions", "surfaces",
+		"safe_title", "title", "alt", "transcript", "published", "link", "news"}).
+		AddRow(1, "u1", "{foo}", nil, []byte(`{"foo":["foos"]}`), "Safe", "Title", "alt text", "[[...]]", published, "http://link", "").
+		AddRow(2, "u2", "{bar}", nil, nil, "", "", "", "", nil, "", "") // дата неизвестна
+	expectCursor(mock)
+	mock.ExpectQuery(`FETCH 500 FROM comics_scan`).
+		WillReturnRows(rows)
+
+	result, err := scanAll(storage)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	assert.Equal(t, core.ComicMeta{
+		SafeTitle:  "Safe",
+		Title:      "Title",
+		Alt:        "alt text",
+		Transcript: "[[...]]",
+		Published:  published,
+		Link:       "http://link",
+	}, result[0].Meta)
+	assert.Equal(t, map[string][]string{"foo": {"foos"}}, result[0].Surfaces)
+	assert.True(t, result[1].Meta.Published.IsZero())
+}
+
+func TestDBScan_FieldWords(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	rows := sqlmock.NewRows([]string{"id", "url", "words", "title_words", "alt_words", "transcript_words"}).
+		AddRow(1, "u1", "{bobby,tables}", "{bobby,tables}", "{bobby}", nil)
+	expectCursor(mock)
+	mock.ExpectQuery(`FETCH 500 FROM comics_scan`).
+		WillReturnRows(rows)
+
+	result, err := scanAll(storage)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, core.FieldWords{
+		Title: []string{"bobby", "tables"},
+		Alt:   []string{"bobby"},
+	}, result[0].Fields)
 }
 
-func TestDBSearch_QueryError(t *testing.T) {
+func TestDBScan_QueryError(t *testing.T) {
 	storage, mock := newMockDB(t)
-	ctx := context.Background()
 
-	mock.ExpectQuery(`SELECT id, url, words FROM comics`).
-		WithArgs().
+	expectCursor(mock)
+	mock.ExpectQuery(`FETCH 500 FROM comics_scan`).
 		WillReturnError(assert.AnError)
+	mock.ExpectRollback()
 
-	result, err := storage.Search(ctx)
+	result, err := scanAll(storage)
 	require.Error(t, err)
 	assert.ErrorIs(t, err, assert.AnError)
 	assert.Nil(t, result)
@@ -77,6 +169,42 @@ func TestDBSearch_QueryError(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestDBScan_CallbackError(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	rows := sqlmock.NewRows([]string{"id", "url", "words"}).
+		AddRow(1, "u1", "{foo}").
+		AddRow(2, "u2", "{bar}")
+	expectCursor(mock)
+	mock.ExpectQuery(`FETCH 500 FROM comics_scan`).WillReturnRows(rows)
+	mock.ExpectRollback()
+
+	// ошибка fn останавливает обход
+	calls := 0
+	err := storage.Scan(context.Background(), func(c core.Comic) error {
+		calls++
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 1, calls)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBScan_BadPositions(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	rows := sqlmock.NewRows([]string{"id", "url", "words", "positions"}).
+		AddRow(1, "u1", "{foo}", []byte(`not json`))
+	expectCursor(mock)
+	mock.ExpectQuery(`FETCH 500 FROM comics_scan`).
+		WillReturnRows(rows)
+
+	result, err := scanAll(storage)
+	require.Error(t, err)
+	assert.Nil(t, result)
+}
+
 func TestDBClose(t *testing.T) {
 	storage, mock := newMockDB(t)
 
@@ -89,3 +217,19 @@ func TestDBClose(t *testing.T) {
 	// Проверяем, что все ожидания (включая Close) выполнены
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestDBGet_Success(t *testing.T) {
+	storage, mock := newMockDB(t)
+
+	rows := sqlmock.NewRows([]string{"id", "url", "words", "positions"}).
+		AddRow(5, "u5", "{foo}", nil)
+	mock.ExpectQuery(`SELECT id, url, words, positions, .* FROM comics WHERE id = ANY\(\$1\)`).
+		WithArgs("{5,7}").
+		WillReturnRows(rows)
+
+	result, err := storage.Get(context.Background(), []int{5, 7})
+	require.NoError(t, err)
+	assert.Equal(t, []core.Comic{{ID: 5, URL: "u5", Words: []string{"foo"}}}, result)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,15 +2,26 @@ package events
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 
 	"github.com/nats-io/nats.go"
+	"yadro.com/course/search/core"
 )
 
 const subjectDBUpdated = "xkcd.db.updated" // топик, в который публикует update service
 
+// dbUpdatedEvent - тело сообщения от update service
+type dbUpdatedEvent struct {
+	Added   []int `json:"added"`
+	Changed []int `json:"changed"`
+	Removed []int `json:"removed"`
+	Dropped bool  `json:"dropped"`
+}
+
 type IndexerSearch interface {
 	RebuildIndex(ctx context.Context) error
+	UpdateIndex(ctx context.Context, changes core.IndexChanges) error
 }
 type NatsSubscriber struct {
 	log *slog.Logger
@@ -35,9 +46,25 @@ func NewNatsSubscriber(address string, log *slog.Logger, indexerSearch IndexerSe
 	subscribe, err := nc.Subscribe(subjectDBUpdated, func(msg *nats.Msg) {
 
 		log.Info("received db update event", "subject", msg.Subject)
-		// Перестраиваем индекс
-		if err := indexerSearch.RebuildIndex(context.Background()); err != nil {
-			log.Error("failed to rebuild index on event", "error", err)
+
+		var event dbUpdatedEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			// старый формат сообщения без списка изменений - перестраиваем индекс
+			log.Warn("unknown db update event format, rebuilding index", "error", err)
+			if err := indexerSearch.RebuildIndex(context.Background()); err != nil {
+				log.Error("failed to rebuild index on event", "error", err)
+			}
+			return
+		}
+
+		err := indexerSearch.UpdateIndex(context.Background(), core.IndexChanges{
+			Added:   event.Added,
+			Changed: event.Changed,
+			Removed: event.Removed,
+			Dropped: event.Dropped,
+		})
+		if err != nil {
+			log.Error("failed to update index on event", "error", err)
 			return
 		}
 	})
@@ -3,6 +3,7 @@ package grpc
 import (
 	"context"
 	"errors"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -24,9 +25,77 @@ func (s *Server) Ping(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, erro
 	return &emptypb.Empty{}, nil
 }
 
+func searchQuery(req *searchpb.SearchRequest) core.SearchQuery {
+	return core.SearchQuery{
+		Phrase:    req.GetPhrase(),
+		Limit:     int(req.GetLimit()),
+		Offset:    int(req.GetOffset()),
+		Fuzziness: int(req.GetFuzziness()),
+		Explain:   req.GetExplain(),
+	}
+}
+
+func comicReply(c core.Comic) *searchpb.Comic {
+	reply := &searchpb.Comic{
+		Id:         int64(c.ID),
+		Url:        c.URL,
+		Score:      c.Score,
+		SafeTitle:  c.Meta.SafeTitle,
+		Title:      c.Meta.Title,
+		Alt:        c.Meta.Alt,
+		Transcript: c.Meta.Transcript,
+		Link:       c.Meta.Link,
+		News:       c.Meta.News,
+	}
+	if !c.Meta.Published.IsZero() {
+		reply.Published = c.Meta.Published.Format(time.DateOnly)
+	}
+	if c.Snippet != nil {
+		reply.Snippet = &searchpb.Snippet{
+			Field:      c.Snippet.Field,
+			Text:       c.Snippet.Text,
+			Highlights: make([]*searchpb.Highlight, 0, len(c.Snippet.Highlights)),
+		}
+		for _, h := range c.Snippet.Highlights {
+			reply.Snippet.Highlights = append(reply.Snippet.Highlights, &searchpb.Highlight{
+				Start: int32(h.Start),
+				End:   int32(h.End),
+			})
+		}
+	}
+	if c.Explain != nil {
+		reply.Explanation = explanationReply(c.Explain)
+	}
+	return reply
+}
+
+func explanationReply(e *core.Explanation) *searchpb.Explanation {
+	reply := &searchpb.Explanation{
+		Terms:     e.Terms,
+		Matched:   make([]*searchpb.TermScore, 0, len(e.Matched)),
+		Proximity: e.Proximity,
+		Matches:   int64(e.Matches),
+		Ratio:     e.Ratio,
+		Score:     e.Score,
+		TieBreak:  string(e.TieBreak),
+	}
+	for _, t := range e.Matched {
+		reply.Matched = append(reply.Matched, &searchpb.TermScore{
+			Term:   t.Term,
+			Field:  t.Field,
+			Dist:   int32(t.Dist),
+			Weight: t.Weight,
+			Tf:     int64(t.TF),
+			Idf:    t.IDF,
+			Score:  t.Score,
+		})
+	}
+	return reply
+}
+
 func (s *Server) Search(ctx context.Context, req *searchpb.SearchRequest) (*searchpb.SearchReply, error) {
 
-	comics, err := s.service.Search(ctx, req.GetPhrase(), int(req.GetLimit()))
+	res, err := s.service.Search(ctx, searchQuery(req))
 
 	if err != nil {
 		if errors.Is(err, core.ErrBadArguments) {
@@ -36,13 +105,12 @@ func (s *Server) Search(ctx context.Context, req *searchpb.SearchRequest) (*sear
 	}
 
 	resp := &searchpb.SearchReply{
-		Comics: make([]*searchpb.Comic, 0, len(comics)),
+		Comics:     make([]*searchpb.Comic, 0, len(res.Comics)),
+		Total:      int64(res.Total),
+		Generation: res.Generation,
 	}
-	for _, c := range comics {
-		resp.Comics = append(resp.Comics, &searchpb.Comic{
-			Id:  int64(c.ID),
-			Url: c.URL,
-		})
+	for _, c := range res.Comics {
+		resp.Comics = append(resp.Comics, comicReply(c))
 	}
 	return resp, nil
 
@@ -50,7 +118,7 @@ func (s *Server) Search(ctx context.Context, req *searchpb.SearchRequest) (*sear
 
 func (s *Server) IndexSearch(ctx context.Context, req *searchpb.SearchRequest) (*searchpb.SearchReply, error) {
 
-	comics, err := s.service.IndexSearch(ctx, req.GetPhrase(), int(req.GetLimit()))
+	res, err := s.service.IndexSearch(ctx, searchQuery(req))
 
 	if err != nil {
 		if errors.Is(err, core.ErrBadArguments) {
@@ -60,14 +128,133 @@ func (s *Server) IndexSearch(ctx context.Context, req *searchpb.SearchRequest) (
 	}
 
 	resp := &searchpb.SearchReply{
-		Comics: make([]*searchpb.Comic, 0, len(comics)),
+		Comics:     make([]*searchpb.Comic, 0, len(res.Comics)),
+		Total:      int64(res.Total),
+		Generation: res.Generation,
 	}
 
+	for _, c := range res.Comics {
+		resp.Comics = append(resp.Comics, comicReply(c))
+	}
+	return resp, nil
+}
+
+func (s *Server) Similar(ctx context.Context, req *searchpb.SimilarRequest) (*searchpb.SearchReply, error) {
+
+	comics, err := s.service.Similar(ctx, int(req.GetId()), int(req.GetLimit()))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, core.ErrBadArguments):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, core.ErrNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &searchpb.SearchReply{
+		Comics: make([]*searchpb.Comic, 0, len(comics)),
+		Total:  int64(len(comics)),
+	}
 	for _, c := range comics {
-		resp.Comics = append(resp.Comics, &searchpb.Comic{
-			Id:  int64(c.ID),
-			Url: c.URL,
+		resp.Comics = append(resp.Comics, comicReply(c))
+	}
+	return resp, nil
+}
+
+func (s *Server) Suggest(ctx context.Context, req *searchpb.SuggestRequest) (*searchpb.SuggestReply, error) {
+
+	suggestions, err := s.service.Suggest(ctx, req.GetPrefix(), int(req.GetLimit()))
+
+	if err != nil {
+		if errors.Is(err, core.ErrBadArguments) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &searchpb.SuggestReply{
+		Suggestions: make([]*searchpb.Suggestion, 0, len(suggestions)),
+	}
+	for _, sg := range suggestions {
+		resp.Suggestions = append(resp.Suggestions, &searchpb.Suggestion{
+			Word:  sg.Word,
+			Count: int64(sg.Count),
 		})
 	}
 	return resp, nil
 }
+
+func (s *Server) Stats(ctx context.Context, _ *emptypb.Empty) (*searchpb.StatsReply, error) {
+
+	st, err := s.service.Stats(ctx)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &searchpb.StatsReply{
+		Comics:            int64(st.Comics),
+		Words:             int64(st.Words),
+		Postings:          int64(st.Postings),
+		Generation:        st.Generation,
+		LastRebuild:       timeReply(st.LastRebuild),
+		RebuildDurationMs: st.RebuildDuration.Milliseconds(),
+		LastError:         st.LastError,
+		LastErrorAt:       timeReply(st.LastErrorAt),
+		Cache: &searchpb.CacheStats{
+			Hits:    st.Cache.Hits,
+			Misses:  st.Cache.Misses,
+			Entries: int64(st.Cache.Entries),
+		},
+	}, nil
+}
+
+// synonyms - словарь есть только у индекса в памяти
+func (s *Server) synonyms() (core.SynonymEditor, error) {
+	editor, ok := s.service.(core.SynonymEditor)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "synonyms are not supported by this search backend")
+	}
+	return editor, nil
+}
+
+func (s *Server) GetSynonyms(ctx context.Context, _ *emptypb.Empty) (*searchpb.SynonymsReply, error) {
+	editor, err := s.synonyms()
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := editor.Synonyms(ctx)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &searchpb.SynonymsReply{Text: text}, nil
+}
+
+func (s *Server) SetSynonyms(ctx context.Context, req *searchpb.SynonymsRequest) (*emptypb.Empty, error) {
+	editor, err := s.synonyms()
+	if err != nil {
+		return nil, err
+	}
+
+	err = editor.ReplaceSynonyms(ctx, req.GetText())
+
+	if err != nil {
+		if errors.Is(err, core.ErrBadArguments) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// timeReply - время в RFC 3339, нулевое время - пустая строка
+func timeReply(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
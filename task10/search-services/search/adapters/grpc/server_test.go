@@ -3,6 +3,7 @@ package grpc
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,28 +14,52 @@ import (
 )
 
 type mockSearcher struct {
-	searchFn      func(ctx context.Context, phrase string, limit int) ([]core.Comic, error)
-	indexSearchFn func(ctx context.Context, phrase string, limit int) ([]core.Comic, error)
+	searchFn      func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error)
+	indexSearchFn func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error)
+	suggestFn     func(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error)
+	similarFn     func(ctx context.Context, id, limit int) ([]core.Comic, error)
+	statsFn       func(ctx context.Context) (core.IndexStats, error)
 }
 
-func (m *mockSearcher) Search(ctx context.Context, phrase string, limit int) ([]core.Comic, error) {
-	if m.searchFn == nil {
+func (m *mockSearcher) Stats(ctx context.Context) (core.IndexStats, error) {
+	if m.statsFn == nil {
+		return core.IndexStats{}, nil
+	}
+	return m.statsFn(ctx)
+}
+
+func (m *mockSearcher) Similar(ctx context.Context, id, limit int) ([]core.Comic, error) {
+	if m.similarFn == nil {
 		return nil, nil
 	}
-	return m.searchFn(ctx, phrase, limit)
+	return m.similarFn(ctx, id, limit)
 }
 
-func (m *mockSearcher) IndexSearch(ctx context.Context, phrase string, limit int) ([]core.Comic, error) {
-	if m.indexSearchFn == nil {
+func (m *mockSearcher) Suggest(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error) {
+	if m.suggestFn == nil {
 		return nil, nil
 	}
-	return m.indexSearchFn(ctx, phrase, limit)
+	return m.suggestFn(ctx, prefix, limit)
+}
+
+func (m *mockSearcher) Search(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+	if m.searchFn == nil {
+		return core.SearchResult{}, nil
+	}
+	return m.searchFn(ctx, query)
+}
+
+func (m *mockSearcher) IndexSearch(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+	if m.indexSearchFn == nil {
+		return core.SearchResult{}, nil
+	}
+	return m.indexSearchFn(ctx, query)
 }
 
 func TestServer_Search_ErrBadArguments(t *testing.T) {
 	ms := &mockSearcher{
-		searchFn: func(ctx context.Context, phrase string, limit int) ([]core.Comic, error) {
-			return nil, core.ErrBadArguments
+		searchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{}, core.ErrBadArguments
 		},
 	}
 	s := NewServer(ms)
@@ -56,8 +81,8 @@ func TestServer_Search_InternalError(t *testing.T) {
 	expErr := assert.AnError
 
 	ms := &mockSearcher{
-		searchFn: func(ctx context.Context, phrase string, limit int) ([]core.Comic, error) {
-			return nil, expErr
+		searchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{}, expErr
 		},
 	}
 	s := NewServer(ms)
@@ -77,8 +102,8 @@ func TestServer_Search_InternalError(t *testing.T) {
 
 func TestServer_IndexSearch_ErrBadArguments(t *testing.T) {
 	ms := &mockSearcher{
-		indexSearchFn: func(ctx context.Context, phrase string, limit int) ([]core.Comic, error) {
-			return nil, core.ErrBadArguments
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{}, core.ErrBadArguments
 		},
 	}
 	s := NewServer(ms)
@@ -100,8 +125,8 @@ func TestServer_IndexSearch_InternalError(t *testing.T) {
 	expErr := assert.AnError
 
 	ms := &mockSearcher{
-		indexSearchFn: func(ctx context.Context, phrase string, limit int) ([]core.Comic, error) {
-			return nil, expErr
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{}, expErr
 		},
 	}
 	s := NewServer(ms)
@@ -118,3 +143,220 @@ func TestServer_IndexSearch_InternalError(t *testing.T) {
 	assert.Equal(t, codes.Internal, st.Code())
 	assert.Equal(t, expErr.Error(), st.Message())
 }
+
+func TestServer_IndexSearch_QueryError(t *testing.T) {
+	ms := &mockSearcher{
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			return core.SearchResult{}, &core.QueryError{Pos: 5, Msg: "unexpected ')'"}
+		},
+	}
+	s := NewServer(ms)
+
+	resp, err := s.IndexSearch(context.Background(), &searchpb.SearchRequest{
+		Phrase: "foo )",
+		Limit:  5,
+	})
+	assert.Nil(t, resp)
+	require.Error(t, err)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Contains(t, st.Message(), "position 5")
+}
+
+func TestServer_IndexSearch_PassesQuery(t *testing.T) {
+	var got core.SearchQuery
+	ms := &mockSearcher{
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			got = query
+			return core.SearchResult{Comics: []core.Comic{{ID: 1, URL: "u1"}}, Total: 12}, nil
+		},
+	}
+	s := NewServer(ms)
+
+	resp, err := s.IndexSearch(context.Background(), &searchpb.SearchRequest{
+		Phrase:    "raptr",
+		Limit:     5,
+		Offset:    10,
+		Fuzziness: core.FuzzinessAuto,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Comics, 1)
+	assert.Equal(t, int64(12), resp.Total)
+	assert.Equal(t, core.SearchQuery{Phrase: "raptr", Limit: 5, Offset: 10, Fuzziness: core.FuzzinessAuto}, got)
+}
+
+func TestServer_Suggest(t *testing.T) {
+	ms := &mockSearcher{
+		suggestFn: func(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error) {
+			assert.Equal(t, "rap", prefix)
+			assert.Equal(t, 3, limit)
+			return []core.Suggestion{{Word: "raptor", Count: 7}, {Word: "rap", Count: 2}}, nil
+		},
+	}
+	s := NewServer(ms)
+
+	resp, err := s.Suggest(context.Background(), &searchpb.SuggestRequest{Prefix: "rap", Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, resp.Suggestions, 2)
+	assert.Equal(t, "raptor", resp.Suggestions[0].Word)
+	assert.Equal(t, int64(7), resp.Suggestions[0].Count)
+}
+
+func TestServer_Suggest_ErrBadArguments(t *testing.T) {
+	ms := &mockSearcher{
+		suggestFn: func(ctx context.Context, prefix string, limit int) ([]core.Suggestion, error) {
+			return nil, core.ErrBadArguments
+		},
+	}
+	s := NewServer(ms)
+
+	resp, err := s.Suggest(context.Background(), &searchpb.SuggestRequest{})
+	assert.Nil(t, resp)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
+func TestServer_Similar(t *testing.T) {
+	ms := &mockSearcher{
+		similarFn: func(ctx context.Context, id, limit int) ([]core.Comic, error) {
+			assert.Equal(t, 42, id)
+			assert.Equal(t, 5, limit)
+			return []core.Comic{{ID: 7, URL: "u7", Score: 0.5}}, nil
+		},
+	}
+	s := NewServer(ms)
+
+	resp, err := s.Similar(context.Background(), &searchpb.SimilarRequest{Id: 42, Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, resp.Comics, 1)
+	assert.Equal(t, int64(7), resp.Comics[0].Id)
+	assert.Equal(t, 0.5, resp.Comics[0].Score)
+	assert.Equal(t, int64(1), resp.Total)
+}
+
+func TestServer_Similar_NotFound(t *testing.T) {
+	ms := &mockSearcher{
+		similarFn: func(ctx context.Context, id, limit int) ([]core.Comic, error) {
+			return nil, core.ErrNotFound
+		},
+	}
+	s := NewServer(ms)
+
+	_, err := s.Similar(context.Background(), &searchpb.SimilarRequest{Id: 1, Limit: 5})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_IndexSearch_Explain(t *testing.T) {
+	var got core.SearchQuery
+	ms := &mockSearcher{
+		indexSearchFn: func(ctx context.Context, query core.SearchQuery) (core.SearchResult, error) {
+			got = query
+			return core.SearchResult{Comics: []core.Comic{{ID: 1, Score: 2.5, Explain: &core.Explanation{
+				Terms:     []string{"foo", "title:bar"},
+				Matched:   []core.TermScore{{Term: "foo", Dist: 1, Weight: 0.5, TF: 2, IDF: 1.5, Score: 2.5}},
+				Proximity: 1,
+				Score:     2.5,
+				TieBreak:  core.TieBreakScore,
+			}}}, Total: 1}, nil
+		},
+	}
+	s := NewServer(ms)
+
+	resp, err := s.IndexSearch(context.Background(), &searchpb.SearchRequest{Phrase: "foo", Limit: 5, Explain: true})
+	require.NoError(t, err)
+	assert.True(t, got.Explain)
+	require.Len(t, resp.Comics, 1)
+
+	e := resp.Comics[0].Explanation
+	require.NotNil(t, e)
+	assert.Equal(t, []string{"foo", "title:bar"}, e.Terms)
+	require.Len(t, e.Matched, 1)
+	assert.Equal(t, "foo", e.Matched[0].Term)
+	assert.Equal(t, int32(1), e.Matched[0].Dist)
+	assert.Equal(t, int64(2), e.Matched[0].Tf)
+	assert.Equal(t, 2.5, e.Matched[0].Score)
+	assert.Equal(t, "score", e.TieBreak)
+}
+
+func TestServer_Stats(t *testing.T) {
+	rebuilt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ms := &mockSearcher{
+		statsFn: func(ctx context.Context) (core.IndexStats, error) {
+			return core.IndexStats{
+				Comics:          3,
+				Words:           10,
+				Postings:        15,
+				Generation:      4,
+				LastRebuild:     rebuilt,
+				RebuildDuration: 1500 * time.Millisecond,
+				Cache:           core.CacheStats{Hits: 2, Misses: 1, Entries: 1},
+			}, nil
+		},
+	}
+	s := NewServer(ms)
+
+	resp, err := s.Stats(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), resp.Comics)
+	assert.Equal(t, int64(10), resp.Words)
+	assert.Equal(t, int64(15), resp.Postings)
+	assert.Equal(t, uint64(4), resp.Generation)
+	assert.Equal(t, "2024-01-02T03:04:05Z", resp.LastRebuild)
+	assert.Equal(t, int64(1500), resp.RebuildDurationMs)
+	// ошибок не было - время пустое
+	assert.Empty(t, resp.LastErrorAt)
+	assert.Equal(t, uint64(2), resp.Cache.Hits)
+
+	ms.statsFn = func(ctx context.Context) (core.IndexStats, error) {
+		return core.IndexStats{}, assert.AnError
+	}
+	_, err = s.Stats(context.Background(), nil)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+type mockSynonymSearcher struct {
+	mockSearcher
+	text       string
+	replaceErr error
+}
+
+func (m *mockSynonymSearcher) Synonyms(ctx context.Context) (string, error) {
+	return m.text, nil
+}
+
+func (m *mockSynonymSearcher) ReplaceSynonyms(ctx context.Context, text string) error {
+	if m.replaceErr != nil {
+		return m.replaceErr
+	}
+	m.text = text
+	return nil
+}
+
+func TestServer_Synonyms(t *testing.T) {
+	ms := &mockSynonymSearcher{}
+	s := NewServer(ms)
+
+	_, err := s.SetSynonyms(context.Background(), &searchpb.SynonymsRequest{Text: "pc, computer"})
+	require.NoError(t, err)
+
+	resp, err := s.GetSynonyms(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "pc, computer", resp.Text)
+
+	ms.replaceErr = core.ErrBadArguments
+	_, err = s.SetSynonyms(context.Background(), &searchpb.SynonymsRequest{Text: "pc"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Synonyms_Unsupported(t *testing.T) {
+	s := NewServer(&mockSearcher{})
+
+	_, err := s.GetSynonyms(context.Background(), nil)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = s.SetSynonyms(context.Background(), &searchpb.SynonymsRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,34 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+
+	"yadro.com/course/search/adapters/atomicfile"
+	"yadro.com/course/search/core"
+)
+
+// File хранит снимок индекса в одном файле
+type File struct {
+	path string
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Save пишет снимок во временный файл рядом и переименовывает его,
+// чтобы при падении посреди записи не остался обрезанный снимок
+func (f *File) Save(_ context.Context, data []byte) error {
+	return atomicfile.Write(f.path, data)
+}
+
+func (f *File) Load(_ context.Context) ([]byte, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, core.ErrSnapshotNotFound
+	}
+	return data, err
+}
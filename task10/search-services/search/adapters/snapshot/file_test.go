@@ -0,0 +1,34 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yadro.com/course/search/core"
+)
+
+func TestFile_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index", "search.snap")
+	f := NewFile(path)
+	ctx := context.Background()
+
+	// файла ещё нет
+	_, err := f.Load(ctx)
+	require.ErrorIs(t, err, core.ErrSnapshotNotFound)
+
+	require.NoError(t, f.Save(ctx, []byte("first")))
+	require.NoError(t, f.Save(ctx, []byte("second")))
+
+	data, err := f.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), data)
+
+	// временные файлы не остаются
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,62 @@
+package synonyms
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"yadro.com/course/search/adapters/atomicfile"
+)
+
+// File хранит словарь синонимов в текстовом файле, который можно править и руками
+type File struct {
+	path string
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Load читает словарь, файла ещё нет - словарь пустой
+func (f *File) Load(_ context.Context) ([]byte, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save заменяет файл целиком через временный, чтобы Watch
+// не прочитал наполовину записанный словарь
+func (f *File) Save(_ context.Context, data []byte) error {
+	return atomicfile.Write(f.path, data)
+}
+
+type Reloader interface {
+	ReloadSynonyms(ctx context.Context) error
+}
+
+// Watch раз в interval перечитывает словарь, подхватывая правки файла без перезапуска.
+// Нулевой interval отключает перечитывание: правки через API применяются и так.
+func Watch(ctx context.Context, log *slog.Logger, interval time.Duration, r Reloader) {
+	if interval <= 0 {
+		log.Info("synonyms reload disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.ReloadSynonyms(ctx); err != nil {
+				log.Error("failed to reload synonyms", "error", err)
+			}
+		}
+	}
+}
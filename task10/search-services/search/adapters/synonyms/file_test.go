@@ -0,0 +1,65 @@
+package synonyms
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dict", "synonyms.txt")
+	f := NewFile(path)
+	ctx := context.Background()
+
+	// файла ещё нет - словарь пустой
+	data, err := f.Load(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	require.NoError(t, f.Save(ctx, []byte("pc, computer\n")))
+
+	data, err = f.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "pc, computer\n", string(data))
+
+	// правка файла руками видна сразу
+	require.NoError(t, os.WriteFile(path, []byte("car, auto\n"), 0o644))
+	data, err = f.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "car, auto\n", string(data))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+type reloaderFunc func(ctx context.Context) error
+
+func (f reloaderFunc) ReloadSynonyms(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestWatch_Disabled(t *testing.T) {
+	// нулевой интервал - файл не перечитывается, а не паника тикера
+	done := make(chan struct{})
+	go func() {
+		Watch(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), 0, reloaderFunc(func(context.Context) error {
+			t.Error("synonyms should not be reloaded")
+			return nil
+		}))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not return")
+	}
+}
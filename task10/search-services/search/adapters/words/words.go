@@ -16,12 +16,18 @@ import (
 )
 
 type Client struct {
-	log    *slog.Logger
-	client wordspb.WordsClient
-	conn   *grpc.ClientConn
+	log      *slog.Logger
+	client   wordspb.WordsClient
+	conn     *grpc.ClientConn
+	analyzer string
 }
 
-func NewClient(address string, log *slog.Logger) (*Client, error) {
+// language - комиксы и запросы нормализуются одинаково:
+// язык выбирается по алфавиту каждого слова.
+// Анализатор тоже должен совпадать у update и search, пустой - default.
+const language = "auto"
+
+func NewClient(address, analyzer string, log *slog.Logger) (*Client, error) {
 	conn, err := grpc.NewClient(
 		address,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -40,14 +46,15 @@ func NewClient(address string, log *slog.Logger) (*Client, error) {
 	conn.Connect()
 
 	return &Client{
-		log:    log,
-		client: wordspb.NewWordsClient(conn),
-		conn:   conn,
+		log:      log,
+		client:   wordspb.NewWordsClient(conn),
+		conn:     conn,
+		analyzer: analyzer,
 	}, nil
 }
 
 func (c *Client) Norm(ctx context.Context, phrase string) ([]string, error) {
-	resp, err := c.client.Norm(ctx, &wordspb.WordsRequest{Phrase: phrase})
+	resp, err := c.client.Norm(ctx, &wordspb.WordsRequest{Phrase: phrase, Language: language, Analyzer: c.analyzer})
 	if err != nil {
 		if status.Code(err) == codes.ResourceExhausted {
 			return nil, core.ErrBadArguments
@@ -57,6 +64,22 @@ func (c *Client) Norm(ctx context.Context, phrase string) ([]string, error) {
 	return resp.GetWords(), nil
 }
 
+func (c *Client) Tokens(ctx context.Context, phrase string) ([]core.Token, error) {
+	resp, err := c.client.Norm(ctx, &wordspb.WordsRequest{Phrase: phrase, Language: language, Analyzer: c.analyzer, Positions: true})
+	if err != nil {
+		if status.Code(err) == codes.ResourceExhausted {
+			return nil, core.ErrBadArguments
+		}
+		return nil, err
+	}
+
+	tokens := make([]core.Token, 0, len(resp.GetTokens()))
+	for _, t := range resp.GetTokens() {
+		tokens = append(tokens, core.Token{Word: t.GetWord(), Pos: int(t.GetPosition())})
+	}
+	return tokens, nil
+}
+
 func (c *Client) Ping(ctx context.Context) error {
 	_, err := c.client.Ping(ctx, &emptypb.Empty{})
 	return err
@@ -7,13 +7,64 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+type Ranking struct {
+	Mode   string      `yaml:"mode" env:"RANKING_MODE" env-default:"bm25"`
+	K1     float64     `yaml:"k1" env:"RANKING_K1" env-default:"1.2"`
+	B      float64     `yaml:"b" env:"RANKING_B" env-default:"0.75"`
+	Fields FieldBoosts `yaml:"fields"`
+}
+
+// FieldBoosts - веса совпадений в полях комикса, все нули - поля не различаются
+type FieldBoosts struct {
+	Title      float64 `yaml:"title" env:"RANKING_TITLE_BOOST" env-default:"3"`
+	Alt        float64 `yaml:"alt" env:"RANKING_ALT_BOOST" env-default:"1.5"`
+	Transcript float64 `yaml:"transcript" env:"RANKING_TRANSCRIPT_BOOST" env-default:"1"`
+}
+
+// Shard - часть комиксов по id, которую индексирует этот экземпляр.
+// hash: шард index из count, range: id из [from, to), to = 0 - без границы.
+type Shard struct {
+	Mode  string `yaml:"mode" env:"SHARD_MODE" env-default:"none"`
+	Index int    `yaml:"index" env:"SHARD_INDEX" env-default:"0"`
+	Count int    `yaml:"count" env:"SHARD_COUNT" env-default:"1"`
+	From  int    `yaml:"from" env:"SHARD_FROM" env-default:"0"`
+	To    int    `yaml:"to" env:"SHARD_TO" env-default:"0"`
+}
+
+// Cache - кэш выдачи, size = 0 выключает его
+type Cache struct {
+	Size int           `yaml:"size" env:"CACHE_SIZE" env-default:"1000"`
+	TTL  time.Duration `yaml:"ttl" env:"CACHE_TTL" env-default:"1m"`
+}
+
+// Synonyms - файл словаря синонимов, пустой path - синонимы отключены,
+// ReloadInterval 0 - правки файла руками подхватываются только при запуске.
+// Правки администратора пишутся в этот же файл, в контейнере он должен лежать на томе.
+type Synonyms struct {
+	Path           string        `yaml:"path" env:"SYNONYMS_PATH"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"SYNONYMS_RELOAD_INTERVAL" env-default:"30s"`
+}
+
+// движки поиска: индекс в памяти или полнотекстовый поиск postgres
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
 type Config struct {
 	LogLevel      string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"DEBUG"`
+	Backend       string        `yaml:"backend" env:"SEARCH_BACKEND" env-default:"memory"`
 	Address       string        `yaml:"search_address" env:"SEARCH_ADDRESS" env-default:"localhost:83"`
 	DBAddress     string        `yaml:"db_address" env:"DB_ADDRESS" env-default:"localhost:82"`
 	WordsAddress  string        `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"localhost:81"`
+	WordsAnalyzer string        `yaml:"words_analyzer" env:"WORDS_ANALYZER"` // пусто - default, как у update
 	IndexTTL      time.Duration `yaml:"index_ttl" env:"INDEX_TTL" env-default:"20s"`
 	BrokerAddress string        `yaml:"broker_address" env:"BROKER_ADDRESS" env-default:"nats://localhost:4222"`
+	SnapshotPath  string        `yaml:"snapshot_path" env:"SNAPSHOT_PATH"` // пусто - снимки индекса отключены
+	Ranking       Ranking       `yaml:"ranking"`
+	Shard         Shard         `yaml:"shard"`
+	Cache         Cache         `yaml:"cache"`
+	Synonyms      Synonyms      `yaml:"synonyms"`
 }
 
 func MustLoad(configPath string) Config {
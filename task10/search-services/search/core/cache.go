@@ -0,0 +1,219 @@
+package core
+
+import (
+	"container/list"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueryCache - настройки кэша выдачи: не больше Size запросов, каждый живёт TTL.
+// Size = 0 - кэш выключен, TTL = 0 - записи вытесняются только по размеру.
+type QueryCache struct {
+	Size int
+	TTL  time.Duration
+}
+
+func (c QueryCache) validate() error {
+	if c.Size < 0 || c.TTL < 0 {
+		return fmt.Errorf("wrong cache parameters: size=%d ttl=%v", c.Size, c.TTL)
+	}
+	return nil
+}
+
+// CacheStats - попадания и промахи кэша выдачи с запуска сервиса
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+type searchMode string
+
+const (
+	modeSearch      searchMode = "search"
+	modeIndexSearch searchMode = "index"
+)
+
+type cacheKey struct {
+	mode      searchMode
+	query     string
+	limit     int
+	offset    int
+	fuzziness int
+	explain   bool
+}
+
+// newCacheKey - ключ по разобранному и нормализованному запросу: одна запись
+// на запросы, которые отличаются регистром, формами слов или их порядком
+func newCacheKey(mode searchMode, q SearchQuery, query queryNode) cacheKey {
+	var b strings.Builder
+	writeQueryKey(&b, query)
+	return cacheKey{
+		mode:      mode,
+		query:     b.String(),
+		limit:     q.Limit,
+		offset:    q.Offset,
+		fuzziness: q.Fuzziness,
+		explain:   q.Explain,
+	}
+}
+
+// writeQueryKey пишет запрос в каноническом виде. Порядок слов и условий
+// в AND и OR на выдачу не влияет и сортируется, порядок слов фразы - влияет.
+func writeQueryKey(b *strings.Builder, node queryNode) {
+	switch n := node.(type) {
+	case *termNode:
+		b.WriteString(n.field + ":")
+		if n.phrase {
+			b.WriteString(`"`)
+			for i, t := range n.tokens {
+				if i > 0 {
+					b.WriteString(" ")
+				}
+				fmt.Fprintf(b, "%s@%d", t.Word, t.Pos)
+			}
+			fmt.Fprintf(b, `"~%d`, n.slop)
+			return
+		}
+		b.WriteString("{" + strings.Join(slices.Sorted(slices.Values(n.stems)), " ") + "}")
+	case *rangeNode:
+		fmt.Fprintf(b, "id:%d..%d", n.from, n.to)
+	case *notNode:
+		b.WriteString("NOT ")
+		writeQueryKey(b, n.child)
+	case *andNode:
+		writeChildrenKey(b, "AND", n.children)
+	case *orNode:
+		writeChildrenKey(b, "OR", n.children)
+	}
+}
+
+func writeChildrenKey(b *strings.Builder, op string, children []queryNode) {
+	keys := make([]string, 0, len(children))
+	for _, child := range children {
+		var cb strings.Builder
+		writeQueryKey(&cb, child)
+		keys = append(keys, cb.String())
+	}
+	slices.Sort(keys)
+	b.WriteString(op + "(" + strings.Join(keys, ", ") + ")")
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	res     SearchResult
+	expires time.Time
+}
+
+// resultCache - LRU выдачи с поколениями. invalidate начинает новое поколение,
+// и результаты, посчитанные по старому индексу, в кэш уже не попадут.
+type resultCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	gen     uint64
+	entries map[cacheKey]*list.Element
+	lru     *list.List // в начале - недавно использованные
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// newResultCache возвращает nil, если кэш выключен: методы nil-кэша ничего не хранят
+func newResultCache(cfg QueryCache) *resultCache {
+	if cfg.Size == 0 {
+		return nil
+	}
+	return &resultCache{
+		size:    cfg.Size,
+		ttl:     cfg.TTL,
+		now:     time.Now,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get возвращает сохранённую выдачу или текущее поколение, с которым
+// посчитанный результат потом передаётся в put
+func (c *resultCache) get(key cacheKey) (SearchResult, uint64, bool) {
+	if c == nil {
+		return SearchResult{}, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if c.ttl == 0 || c.now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.hits.Add(1)
+			return e.res, c.gen, true
+		}
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+	c.misses.Add(1)
+	return SearchResult{}, c.gen, false
+}
+
+func (c *resultCache) put(key cacheKey, gen uint64, res SearchResult) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	e := &cacheEntry{key: key, res: res, expires: c.now().Add(c.ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *resultCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	clear(c.entries)
+	c.lru.Init()
+}
+
+func (c *resultCache) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: entries,
+	}
+}
+
+// CacheStats - счётчики кэша выдачи
+func (s *Service) CacheStats() CacheStats {
+	return s.cache.stats()
+}
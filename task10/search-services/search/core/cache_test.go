@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultCache_LRU(t *testing.T) {
+	c := newResultCache(QueryCache{Size: 2})
+	key := func(word string) cacheKey {
+		return testCacheKey(modeIndexSearch, word)
+	}
+
+	_, gen, ok := c.get(key("a"))
+	require.False(t, ok)
+	c.put(key("a"), gen, SearchResult{Total: 1})
+	c.put(key("b"), gen, SearchResult{Total: 2})
+
+	// a использован последним, поэтому вытесняется b
+	res, _, ok := c.get(key("a"))
+	require.True(t, ok)
+	assert.Equal(t, 1, res.Total)
+	c.put(key("c"), gen, SearchResult{Total: 3})
+
+	_, _, ok = c.get(key("b"))
+	assert.False(t, ok)
+	_, _, ok = c.get(key("c"))
+	assert.True(t, ok)
+
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 2, Entries: 2}, c.stats())
+}
+
+func TestResultCache_TTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newResultCache(QueryCache{Size: 10, TTL: time.Minute})
+	c.now = func() time.Time { return now }
+	key := testCacheKey(modeSearch, "foo")
+
+	_, gen, _ := c.get(key)
+	c.put(key, gen, SearchResult{Total: 1})
+
+	now = now.Add(59 * time.Second)
+	_, _, ok := c.get(key)
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, _, ok = c.get(key)
+	assert.False(t, ok)
+	assert.Zero(t, c.stats().Entries)
+}
+
+func TestResultCache_Invalidate(t *testing.T) {
+	c := newResultCache(QueryCache{Size: 10})
+	key := testCacheKey(modeSearch, "foo")
+
+	_, gen, _ := c.get(key)
+	c.put(key, gen, SearchResult{Total: 1})
+	c.invalidate()
+	_, _, ok := c.get(key)
+	assert.False(t, ok)
+
+	// результат, посчитанный до сброса, не сохраняется
+	_, gen, _ = c.get(key)
+	c.invalidate()
+	c.put(key, gen, SearchResult{Total: 1})
+	_, _, ok = c.get(key)
+	assert.False(t, ok)
+}
+
+func TestResultCache_Disabled(t *testing.T) {
+	c := newResultCache(QueryCache{})
+	key := testCacheKey(modeSearch, "foo")
+
+	_, gen, _ := c.get(key)
+	c.put(key, gen, SearchResult{Total: 1})
+	_, _, ok := c.get(key)
+	assert.False(t, ok)
+	c.invalidate()
+	assert.Equal(t, CacheStats{}, c.stats())
+}
+
+// testCacheKey - ключ запроса из одного уже нормализованного слова
+func testCacheKey(mode searchMode, word string) cacheKey {
+	return newCacheKey(mode, SearchQuery{Phrase: word, Limit: 10}, &termNode{pos: 1, text: word, stems: []string{word}})
+}
+
+func TestNewCacheKey(t *testing.T) {
+	// нормализатор: нижний регистр и окончание -s
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		var res []string
+		for _, w := range strings.Fields(strings.ToLower(phrase)) {
+			res = append(res, strings.TrimSuffix(w, "s"))
+		}
+		return res, nil
+	}}
+	key := func(mode searchMode, q SearchQuery) cacheKey {
+		query, err := prepareQuery(context.Background(), words, q.Phrase)
+		require.NoError(t, err)
+		return newCacheKey(mode, q, query)
+	}
+	same := func(a, b string) {
+		t.Helper()
+		assert.Equal(t, key(modeSearch, SearchQuery{Phrase: a, Limit: 10}), key(modeSearch, SearchQuery{Phrase: b, Limit: 10}), "%q vs %q", a, b)
+	}
+	differ := func(a, b string) {
+		t.Helper()
+		assert.NotEqual(t, key(modeSearch, SearchQuery{Phrase: a, Limit: 10}), key(modeSearch, SearchQuery{Phrase: b, Limit: 10}), "%q vs %q", a, b)
+	}
+
+	same("Bobby Tables", "bobby tables")
+	same("bobby tables", "tables bobby")
+	same("bobby table", "Bobby Tables")
+	same("  linux   AND\tcpu ", "CPU AND linux")
+	same(`title:linux -windows`, `-Windows title:Linux`)
+	same(`"bobby tables"~1`, `"Bobby Table"~1`)
+
+	differ("linux AND cpu", "linux cpu")
+	differ("linux AND cpu", "linux and cpu")
+	differ(`"bobby tables"`, `"tables bobby"`)
+	differ(`"bobby tables"`, `"bobby tables"~1`)
+	differ(`title:linux`, `alt:linux`)
+	differ(`id:1..10`, `id:1..11`)
+
+	q := SearchQuery{Phrase: "linux", Limit: 10}
+	assert.NotEqual(t, key(modeSearch, q), key(modeIndexSearch, q))
+	assert.NotEqual(t, key(modeSearch, q), key(modeSearch, SearchQuery{Phrase: q.Phrase, Limit: 20}))
+	assert.NotEqual(t, key(modeSearch, q), key(modeSearch, SearchQuery{Phrase: q.Phrase, Limit: 10, Fuzziness: 1}))
+}
+
+func TestServiceIndexSearch_Cached(t *testing.T) {
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return strings.Fields(strings.ToLower(phrase)), nil
+	}}
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{{ID: 1, URL: "u1", Words: []string{"foo"}}}, nil
+		},
+		getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+			return []Comic{{ID: 2, URL: "u2", Words: []string{"foo"}}}, nil
+		},
+	}
+	svc, err := NewService(newTestLogger(), db, words, testRanking, Shard{}, QueryCache{Size: 10}, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	search := func(phrase string) int {
+		res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: phrase, Limit: 10})
+		require.NoError(t, err)
+		return res.Total
+	}
+
+	assert.Equal(t, 1, search("foo"))
+	assert.Equal(t, 1, search("Foo")) // тот же запрос после нормализации - из кэша
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Entries: 1}, svc.CacheStats())
+
+	// событие из базы сбрасывает кэш
+	require.NoError(t, svc.UpdateIndex(context.Background(), IndexChanges{Added: []int{2}}))
+	assert.Equal(t, 2, search("foo"))
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2, Entries: 1}, svc.CacheStats())
+
+	// и перестройка тоже
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	assert.Equal(t, 1, search("foo"))
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 3, Entries: 1}, svc.CacheStats())
+}
@@ -2,4 +2,9 @@ package core
 
 import "errors"
 
-var ErrBadArguments = errors.New("arguments are not acceptable")
+var (
+	ErrBadArguments     = errors.New("arguments are not acceptable")
+	ErrNotFound         = errors.New("comic is not found")
+	ErrBadSnapshot      = errors.New("bad index snapshot")
+	ErrSnapshotNotFound = errors.New("index snapshot not found")
+)
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldsFixture() []Comic {
+	return []Comic{
+		// слово в названии
+		{ID: 1, URL: "u1", Words: []string{"linux", "kernel"},
+			Fields: FieldWords{Title: []string{"linux"}, Transcript: []string{"kernel"}}},
+		// то же слово дважды, но в расшифровке
+		{ID: 2, URL: "u2", Words: []string{"linux", "kernel"},
+			Positions: map[string][]int{"linux": {0, 2}, "kernel": {1}},
+			Fields:    FieldWords{Transcript: []string{"linux", "kernel", "linux"}}},
+		// старая запись без полей
+		{ID: 3, URL: "u3", Words: []string{"linux", "kernel"}},
+	}
+}
+
+func lookupIDs(ix *invertedIndex, field, term string) []int {
+	var ids []int
+	for p := range ix.lookup(field, term) {
+		ids = append(ids, p.id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestInvertedIndex_FieldLookup(t *testing.T) {
+	ix := newInvertedIndex()
+	for _, c := range fieldsFixture() {
+		ix.add(c)
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, lookupIDs(ix, "", "linux"))
+	// в комиксе без полей поле ищется по всему тексту
+	assert.Equal(t, []int{1, 3}, lookupIDs(ix, FieldTitle, "linux"))
+	assert.Equal(t, []int{2, 3}, lookupIDs(ix, FieldTranscript, "linux"))
+	assert.Equal(t, []int{3}, lookupIDs(ix, FieldAlt, "linux"))
+
+	// позиции в поле считаются от начала поля
+	p, ok := ix.fields[fieldTranscript][ix.dict.ids["linux"]].get(2)
+	require.True(t, ok)
+	assert.Equal(t, []int{0, 2}, p.positions())
+
+	for _, c := range fieldsFixture()[:2] {
+		ix.remove(c)
+	}
+	assert.Equal(t, []int{3}, lookupIDs(ix, FieldTranscript, "kernel"))
+	assert.Empty(t, ix.fields[fieldTranscript][ix.dict.ids["kernel"]].df)
+}
+
+func TestServiceIndexSearch_FieldBoosts(t *testing.T) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		return fieldsFixture()[:2], nil
+	}}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{phrase}, nil
+	}}
+	search := func(ranking Ranking, phrase string) []int {
+		svc, err := NewService(newTestLogger(), db, words, ranking, Shard{}, QueryCache{}, nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, svc.RebuildIndex(context.Background()))
+		res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: phrase, Limit: 10})
+		require.NoError(t, err)
+		var ids []int
+		for _, c := range res.Comics {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+
+	// без весов выше комикс, где слово встречается чаще
+	assert.Equal(t, []int{2, 1}, search(testRanking, "linux"))
+
+	// с весами совпадение в названии перевешивает
+	boosted := testRanking
+	boosted.Fields = FieldBoosts{Title: 3, Alt: 1.5, Transcript: 1}
+	assert.Equal(t, []int{1, 2}, search(boosted, "linux"))
+
+	assert.Equal(t, []int{2}, search(boosted, "transcript:linux"))
+	assert.Equal(t, []int{1}, search(boosted, "title:linux"))
+
+	// расшифровка с нулевым весом ничего не добавляет к оценке
+	noTranscript := testRanking
+	noTranscript.Fields = FieldBoosts{Title: 1}
+	svc, err := NewService(newTestLogger(), db, words, noTranscript, Shard{}, QueryCache{}, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "linux", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, res.Comics, 2)
+	assert.Equal(t, 1, res.Comics[0].ID)
+	assert.Positive(t, res.Comics[0].Score)
+	assert.Equal(t, 2, res.Comics[1].ID)
+	assert.Zero(t, res.Comics[1].Score)
+
+	bad := testRanking
+	bad.Fields.Alt = -1
+	_, err = NewService(newTestLogger(), db, words, bad, Shard{}, QueryCache{}, nil, nil)
+	assert.Error(t, err)
+}
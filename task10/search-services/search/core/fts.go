@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// FTSService ищет через полнотекстовый индекс базы. Своего индекса у него нет,
+// поэтому экземпляр не прогревается при старте и их можно запускать сколько угодно.
+type FTSService struct {
+	log   *slog.Logger
+	db    DB
+	text  TextIndex
+	words Words
+}
+
+func NewFTSService(log *slog.Logger, db DB, text TextIndex, words Words) *FTSService {
+	return &FTSService{
+		log:   log,
+		db:    db,
+		text:  text,
+		words: words,
+	}
+}
+
+// Search и IndexSearch здесь одинаковые: оба запроса ранжирует база
+func (s *FTSService) Search(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	return s.IndexSearch(ctx, q)
+}
+
+// IndexSearch ищет по языку запросов, кроме полей, диапазонов id и опечаток:
+// в tsquery их не выразить, поэтому такие запросы отклоняются, а не ищутся
+// по всему комиксу. Разбор оценки (Explain) не заполняется.
+func (s *FTSService) IndexSearch(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	if err := q.validate(); err != nil {
+		return SearchResult{}, err
+	}
+	if q.Fuzziness != 0 {
+		return SearchResult{}, fmt.Errorf("%w: fuzziness is not supported by full-text search backend", ErrBadArguments)
+	}
+
+	query, err := prepareQuery(ctx, s.words, q.Phrase)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	if query == nil {
+		return SearchResult{}, nil
+	}
+	tsq, err := tsQuery(query)
+	if err != nil || tsq == "" {
+		return SearchResult{}, err
+	}
+
+	res, err := s.text.SearchText(ctx, tsq, q.Limit, q.Offset)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	return withSnippets(res, positiveTerms(query)), nil
+}
+
+func (s *FTSService) Suggest(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" || limit <= 0 {
+		return nil, ErrBadArguments
+	}
+	return s.text.SuggestText(ctx, prefix, limit)
+}
+
+// Similar ищет комиксы с любым из слов исходного, оценка - ts_rank, а не косинус
+func (s *FTSService) Similar(ctx context.Context, id, limit int) ([]Comic, error) {
+	if limit <= 0 {
+		return nil, ErrBadArguments
+	}
+	comics, err := s.db.Get(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(comics) == 0 {
+		return nil, ErrNotFound
+	}
+	source := comics[0]
+	if len(source.Words) == 0 {
+		return nil, nil
+	}
+
+	lexemes := make([]string, 0, len(source.Words))
+	for _, w := range source.Words {
+		lexemes = append(lexemes, tsLexeme(w))
+	}
+	// исходный комикс найдётся сам, поэтому просим на один больше
+	res, err := s.text.SearchText(ctx, strings.Join(lexemes, " | "), limit+1, 0)
+	if err != nil {
+		return nil, err
+	}
+	similar := make([]Comic, 0, len(res.Comics))
+	for _, c := range res.Comics {
+		if c.ID != id && len(similar) < limit {
+			similar = append(similar, c)
+		}
+	}
+	return similar, nil
+}
+
+// Stats считает база, индекс у неё обновляется вместе с комиксами,
+// поэтому перестроек, поколений и кэша у этого бэкенда нет
+func (s *FTSService) Stats(ctx context.Context) (IndexStats, error) {
+	return s.text.TextStats(ctx)
+}
+
+// tsQuery переводит нормализованный запрос в синтаксис to_tsquery.
+// Фраза с допуском (~N) ищется как все её слова в любом порядке.
+func tsQuery(node queryNode) (string, error) {
+	switch n := node.(type) {
+	case *termNode:
+		if n.field != "" {
+			return "", fmt.Errorf("%w: field search is not supported by full-text search backend", ErrBadArguments)
+		}
+		if n.phrase && n.slop == 0 && len(n.tokens) > 1 {
+			var b strings.Builder
+			b.WriteString("(" + tsLexeme(n.tokens[0].Word))
+			for i, t := range n.tokens[1:] {
+				dist := t.Pos - n.tokens[i].Pos
+				b.WriteString(" <" + strconv.Itoa(dist) + "> " + tsLexeme(t.Word))
+			}
+			b.WriteString(")")
+			return b.String(), nil
+		}
+		op := " | "
+		if n.phrase {
+			op = " & "
+		}
+		lexemes := make([]string, 0, len(n.stems))
+		for _, stem := range n.stems {
+			lexemes = append(lexemes, tsLexeme(stem))
+		}
+		return "(" + strings.Join(lexemes, op) + ")", nil
+	case *rangeNode:
+		return "", fmt.Errorf("%w: id ranges are not supported by full-text search backend", ErrBadArguments)
+	case *notNode:
+		child, err := tsQuery(n.child)
+		if err != nil || child == "" {
+			return "", err
+		}
+		return "!" + child, nil
+	case *andNode:
+		return tsGroup(n.children, " & ")
+	case *orNode:
+		return tsGroup(n.children, " | ")
+	}
+	return "", fmt.Errorf("unexpected query node %T", node)
+}
+
+// tsGroup - как evalGroup: исключения вычитаются из всей группы,
+// группа без положительных условий ничего не находит и даёт пустой запрос
+func tsGroup(children []queryNode, op string) (string, error) {
+	var parts, excluded []string
+	for _, child := range children {
+		part, err := tsQuery(child)
+		if err != nil {
+			return "", err
+		}
+		if part == "" {
+			continue
+		}
+		if _, ok := child.(*notNode); ok {
+			excluded = append(excluded, part)
+		} else {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	res := "(" + strings.Join(parts, op) + ")"
+	if len(excluded) == 0 {
+		return res, nil
+	}
+	return "(" + res + " & " + strings.Join(excluded, " & ") + ")", nil
+}
+
+// tsLexeme - слово в кавычках, чтобы его символы не читались как операторы
+func tsLexeme(word string) string {
+	word = strings.ReplaceAll(word, `\`, `\\`)
+	return "'" + strings.ReplaceAll(word, "'", "''") + "'"
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTextIndex struct {
+	searchTextFn  func(ctx context.Context, query string, limit, offset int) (SearchResult, error)
+	suggestTextFn func(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+	textStatsFn   func(ctx context.Context) (IndexStats, error)
+}
+
+func (m *mockTextIndex) TextStats(ctx context.Context) (IndexStats, error) {
+	return m.textStatsFn(ctx)
+}
+
+func (m *mockTextIndex) SearchText(ctx context.Context, query string, limit, offset int) (SearchResult, error) {
+	return m.searchTextFn(ctx, query, limit, offset)
+}
+
+func (m *mockTextIndex) SuggestText(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+	return m.suggestTextFn(ctx, prefix, limit)
+}
+
+// слова нормализуются в нижний регистр, стоп-слово "the" выкидывается
+var ftsWords = &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+	var res []string
+	for _, w := range strings.Fields(strings.ToLower(phrase)) {
+		if w != "the" {
+			res = append(res, w)
+		}
+	}
+	return res, nil
+}}
+
+func TestTSQuery(t *testing.T) {
+	testCases := []struct {
+		query string
+		want  string
+	}{
+		{"linux cpu", "('linux' | 'cpu')"},
+		{"linux AND cpu", "(('linux') & ('cpu'))"},
+		{"linux -windows", "((('linux')) & !('windows'))"},
+		{"linux OR cpu NOT windows", "((('linux') | ('cpu')) & !('windows'))"},
+		{"(a OR b) AND c", "((('a') | ('b')) & ('c'))"},
+		{`"Bobby Tables"`, "('bobby' <1> 'tables')"},
+		{`"bobby tables"~2`, "('bobby' & 'tables')"},
+		{"it's", "('it''s')"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			node, err := prepareQuery(context.Background(), ftsWords, tc.query)
+			require.NoError(t, err)
+			got, err := tsQuery(node)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFTSServiceIndexSearch(t *testing.T) {
+	var gotQuery string
+	var gotLimit, gotOffset int
+	text := &mockTextIndex{searchTextFn: func(ctx context.Context, query string, limit, offset int) (SearchResult, error) {
+		gotQuery, gotLimit, gotOffset = query, limit, offset
+		return SearchResult{Comics: []Comic{{ID: 7, Score: 0.5, Meta: ComicMeta{Title: "Linux"}}}, Total: 11}, nil
+	}}
+	svc := NewFTSService(newTestLogger(), &mockDB{}, text, ftsWords)
+
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "Linux", Limit: 5, Offset: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "('linux')", gotQuery)
+	assert.Equal(t, 5, gotLimit)
+	assert.Equal(t, 10, gotOffset)
+	assert.Equal(t, 11, res.Total)
+	require.Len(t, res.Comics, 1)
+	require.NotNil(t, res.Comics[0].Snippet)
+	assert.Equal(t, "title", res.Comics[0].Snippet.Field)
+
+	// только стоп-слова - искать нечего
+	res, err = svc.IndexSearch(context.Background(), SearchQuery{Phrase: "the", Limit: 5})
+	require.NoError(t, err)
+	assert.Empty(t, res.Comics)
+
+	for _, q := range []SearchQuery{
+		{Phrase: "", Limit: 5},
+		{Phrase: "linux", Limit: 5, Fuzziness: 1},
+		{Phrase: "id:1-10", Limit: 5},
+		{Phrase: "title:linux", Limit: 5},
+		{Phrase: `linux OR alt:"bobby tables"`, Limit: 5},
+	} {
+		_, err := svc.IndexSearch(context.Background(), q)
+		assert.ErrorIs(t, err, ErrBadArguments, q.Phrase)
+	}
+}
+
+func TestFTSServiceSimilar(t *testing.T) {
+	db := &mockDB{getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+		if ids[0] != 1 {
+			return nil, nil
+		}
+		return []Comic{{ID: 1, Words: []string{"foo", "bar"}}}, nil
+	}}
+	var gotQuery string
+	text := &mockTextIndex{searchTextFn: func(ctx context.Context, query string, limit, offset int) (SearchResult, error) {
+		gotQuery = query
+		assert.Equal(t, 3, limit)
+		return SearchResult{Comics: []Comic{{ID: 1}, {ID: 4}, {ID: 2}}, Total: 3}, nil
+	}}
+	svc := NewFTSService(newTestLogger(), db, text, ftsWords)
+
+	res, err := svc.Similar(context.Background(), 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "'foo' | 'bar'", gotQuery)
+	require.Len(t, res, 2)
+	assert.Equal(t, 4, res[0].ID)
+	assert.Equal(t, 2, res[1].ID)
+
+	_, err = svc.Similar(context.Background(), 2, 2)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = svc.Similar(context.Background(), 1, 0)
+	assert.ErrorIs(t, err, ErrBadArguments)
+}
+
+func TestFTSServiceSuggest(t *testing.T) {
+	text := &mockTextIndex{suggestTextFn: func(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+		assert.Equal(t, "fo", prefix)
+		return []Suggestion{{Word: "foo", Count: 3}}, nil
+	}}
+	svc := NewFTSService(newTestLogger(), &mockDB{}, text, ftsWords)
+
+	res, err := svc.Suggest(context.Background(), " Fo ", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Suggestion{{Word: "foo", Count: 3}}, res)
+
+	_, err = svc.Suggest(context.Background(), " ", 10)
+	assert.ErrorIs(t, err, ErrBadArguments)
+}
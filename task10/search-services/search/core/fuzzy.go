@@ -0,0 +1,147 @@
+package core
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+const (
+	// FuzzinessAuto - число опечаток зависит от длины слова
+	FuzzinessAuto = -1
+	MaxFuzziness  = 2
+
+	// вклад слова с одной опечаткой относительно точного совпадения,
+	// с двумя опечатками - fuzzyWeight в квадрате
+	fuzzyWeight = 0.5
+	// сколько похожих слов максимум подставляется вместо одного слова запроса
+	maxFuzzyExpansions = 50
+)
+
+// maxEdits - сколько опечаток допускается в слове при заданной нечёткости
+func maxEdits(word string, fuzziness int) int {
+	if fuzziness != FuzzinessAuto {
+		return fuzziness
+	}
+	switch n := len([]rune(word)); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func editWeight(dist int) float64 {
+	return math.Pow(fuzzyWeight, float64(dist))
+}
+
+// levenshtein - расстояние редактирования между словами в символах
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// bkTree - словарь индекса для поиска слов на расстоянии не больше заданного.
+// Потомки узла разложены по расстоянию до него, поэтому при поиске
+// по неравенству треугольника обходится только малая часть дерева.
+type bkTree struct {
+	root *bkNode
+}
+
+type bkNode struct {
+	word     string
+	children map[int]*bkNode
+}
+
+func (t *bkTree) add(word string) {
+	if t.root == nil {
+		t.root = &bkNode{word: word}
+		return
+	}
+	node := t.root
+	for {
+		d := levenshtein(word, node.word)
+		if d == 0 {
+			return
+		}
+		child, ok := node.children[d]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[d] = &bkNode{word: word}
+			return
+		}
+		node = child
+	}
+}
+
+type fuzzyMatch struct {
+	word string
+	dist int
+}
+
+func (t *bkTree) search(word string, maxDist int) []fuzzyMatch {
+	if t.root == nil {
+		return nil
+	}
+	var res []fuzzyMatch
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		d := levenshtein(word, node.word)
+		if d <= maxDist {
+			res = append(res, fuzzyMatch{word: node.word, dist: d})
+		}
+		for edge, child := range node.children {
+			if edge >= d-maxDist && edge <= d+maxDist {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return res
+}
+
+// fuzzyTerms - слова индекса, похожие на stem, без него самого.
+// Сначала ближайшие, среди равных - более частые.
+func (ix *invertedIndex) fuzzyTerms(stem string, maxDist int) []fuzzyMatch {
+	if maxDist <= 0 {
+		return nil
+	}
+	matches := slices.DeleteFunc(ix.vocab.search(stem, maxDist), func(m fuzzyMatch) bool {
+		return m.dist == 0 || ix.df(m.word) == 0
+	})
+	slices.SortFunc(matches, func(a, b fuzzyMatch) int {
+		if a.dist != b.dist {
+			return cmp.Compare(a.dist, b.dist)
+		}
+		if da, db := ix.df(a.word), ix.df(b.word); da != db {
+			return cmp.Compare(db, da)
+		}
+		return cmp.Compare(a.word, b.word)
+	})
+	if len(matches) > maxFuzzyExpansions {
+		matches = matches[:maxFuzzyExpansions]
+	}
+	return matches
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevenshtein(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		dist int
+	}{
+		{"raptor", "raptor", 0},
+		{"raptr", "raptor", 1},  // пропуск
+		{"kerbal", "kernel", 2}, // две замены
+		{"", "abc", 3},
+		{"ёжик", "ежик", 1}, // считаем по символам, а не байтам
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.dist, levenshtein(tc.a, tc.b), "%s -> %s", tc.a, tc.b)
+		assert.Equal(t, tc.dist, levenshtein(tc.b, tc.a), "%s -> %s", tc.b, tc.a)
+	}
+}
+
+func TestMaxEdits(t *testing.T) {
+	assert.Equal(t, 0, maxEdits("ab", FuzzinessAuto))
+	assert.Equal(t, 1, maxEdits("raptr", FuzzinessAuto))
+	assert.Equal(t, 2, maxEdits("kerbal", FuzzinessAuto))
+	assert.Equal(t, 1, maxEdits("kerbal", 1))
+}
+
+func TestBKTree_Search(t *testing.T) {
+	vocab := []string{"raptor", "rapture", "capture", "kernel", "linux", "lint", "link"}
+
+	var tree bkTree
+	for _, w := range vocab {
+		tree.add(w)
+	}
+	tree.add("linux") // повтор не ломает дерево
+
+	// сверяем с полным перебором словаря
+	for _, query := range []string{"raptr", "linx", "kerbal", "zzz"} {
+		for dist := 0; dist <= MaxFuzziness; dist++ {
+			var want []fuzzyMatch
+			for _, w := range vocab {
+				if d := levenshtein(query, w); d <= dist {
+					want = append(want, fuzzyMatch{word: w, dist: d})
+				}
+			}
+			assert.ElementsMatch(t, want, tree.search(query, dist), "%s within %d", query, dist)
+		}
+	}
+}
+
+func newFuzzyTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		return []Comic{
+			{ID: 1, URL: "u1", Words: []string{"raptor", "fenc"}},
+			{ID: 2, URL: "u2", Words: []string{"raptr", "fenc"}},
+			{ID: 3, URL: "u3", Words: []string{"kernel", "linux"}},
+			{ID: 4, URL: "u4", Words: []string{"capture", "flag"}},
+		}, nil
+	}}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return strings.Fields(strings.ToLower(phrase)), nil
+	}}
+
+	svc := newTestService(t, db, words)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	return svc
+}
+
+func TestServiceIndexSearch_Fuzzy(t *testing.T) {
+	svc := newFuzzyTestService(t)
+
+	testCases := []struct {
+		name      string
+		phrase    string
+		fuzziness int
+		ids       []int
+	}{
+		{"exact only", "raptor", 0, []int{1}},
+		{"one typo", "raptor", 1, []int{1, 2}},
+		{"auto", "kerbal", FuzzinessAuto, []int{3}},
+		{"too many typos", "kerbal", 1, nil},
+		{"short word is exact", "fl", FuzzinessAuto, nil},
+		{"auto for short word", "fla", FuzzinessAuto, []int{4}},
+		{"negation is not expanded", "fenc -raptor", 1, []int{2}},
+		{"phrase is not expanded", `"kerbal linux"`, 2, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: tc.phrase, Limit: 10, Fuzziness: tc.fuzziness})
+			require.NoError(t, err)
+
+			var ids []int
+			for _, c := range res.Comics {
+				ids = append(ids, c.ID)
+			}
+			assert.ElementsMatch(t, tc.ids, ids)
+		})
+	}
+}
+
+func TestServiceIndexSearch_FuzzyRankedBelowExact(t *testing.T) {
+	svc := newFuzzyTestService(t)
+
+	// комикс с точным совпадением выше, хотя у raptr тот же idf и та же длина документа
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "raptr", Limit: 10, Fuzziness: 1})
+	require.NoError(t, err)
+	require.Len(t, res.Comics, 2)
+	assert.Equal(t, 2, res.Comics[0].ID)
+	assert.Equal(t, 1, res.Comics[1].ID)
+
+	res, err = svc.Search(context.Background(), SearchQuery{Phrase: "raptr", Limit: 10, Fuzziness: 1})
+	require.NoError(t, err)
+	require.Len(t, res.Comics, 2)
+	assert.Equal(t, 2, res.Comics[0].ID)
+}
+
+func TestServiceIndexSearch_BadFuzziness(t *testing.T) {
+	svc := newFuzzyTestService(t)
+
+	for _, fuzziness := range []int{-2, MaxFuzziness + 1} {
+		res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "raptor", Limit: 10, Fuzziness: fuzziness})
+		require.ErrorIs(t, err, ErrBadArguments)
+		assert.Nil(t, res.Comics)
+	}
+}
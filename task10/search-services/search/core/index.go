@@ -0,0 +1,317 @@
+package core
+
+import (
+	"iter"
+	"slices"
+	"sync/atomic"
+)
+
+// indexField - поле комикса, вхождения слов в которое хранятся отдельно
+type indexField int
+
+const (
+	fieldTitle indexField = iota
+	fieldAlt
+	fieldTranscript
+	numFields
+)
+
+var fieldNames = [numFields]string{FieldTitle, FieldAlt, FieldTranscript}
+
+func fieldByName(name string) (indexField, bool) {
+	for f, n := range fieldNames {
+		if n == name {
+			return indexField(f), true
+		}
+	}
+	return 0, false
+}
+
+// invertedIndex - обратный индекс: слово -> сжатый список комиксов с позициями,
+// плюс длины документов для нормализации в BM25 и словарь для нечёткого поиска.
+// Вхождения в название, alt и расшифровку дополнительно лежат в fields,
+// позиции там считаются внутри поля.
+type invertedIndex struct {
+	dict     termDict
+	lists    []postingList            // по номеру слова в dict
+	fields   [numFields][]postingList // тоже по номеру слова
+	noFields map[int]struct{}         // комиксы без слов по полям, поле в них ищется по всему тексту
+	live     int                      // сколько слов встречается хотя бы в одном комиксе
+	docLen   map[int]int
+	totalLen int
+	vocab    bkTree
+	norms    atomic.Pointer[map[int]float64] // длины векторов TF-IDF для Similar, nil - пересчитать
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		docLen:   make(map[int]int),
+		noFields: make(map[int]struct{}),
+	}
+}
+
+// list - вхождения слова, nil если слова нет в словаре
+func (ix *invertedIndex) list(term string) *postingList {
+	id, ok := ix.dict.id(term)
+	if !ok {
+		return nil
+	}
+	return &ix.lists[id]
+}
+
+// listFor - вхождения слова, новое слово заносится в словарь
+func (ix *invertedIndex) listFor(term string) (*postingList, string) {
+	id := ix.intern(term)
+	return &ix.lists[id], ix.dict.terms[id]
+}
+
+func (ix *invertedIndex) intern(term string) int {
+	id, added := ix.dict.intern(term)
+	if added {
+		ix.lists = append(ix.lists, postingList{})
+		for f := range ix.fields {
+			ix.fields[f] = append(ix.fields[f], postingList{})
+		}
+		ix.vocab.add(ix.dict.terms[id])
+	}
+	return id
+}
+
+// add индексирует комикс и возвращает его в виде для хранения:
+// слова ссылаются на словарь индекса, позиции остаются только в индексе.
+// Тексты и написания слов нужны только карточкам выдачи, их читает из базы loadCards.
+func (ix *invertedIndex) add(c Comic) Comic {
+	stored := c
+	stored.Positions = nil
+	stored.Fields = FieldWords{}
+	stored.Surfaces = nil
+	stored.Meta = ComicMeta{}
+	if _, ok := ix.docLen[c.ID]; ok {
+		return stored
+	}
+	ix.norms.Store(nil)
+	stored.Words = make([]string, 0, len(c.Words))
+	length := 0
+	for _, w := range c.Words {
+		list, term := ix.listFor(w)
+		if list.df == 0 {
+			ix.live++
+		}
+		list.add(c.ID, c.Positions[w])
+		stored.Words = append(stored.Words, term)
+		length += max(len(c.Positions[w]), 1)
+	}
+	ix.docLen[c.ID] = length
+	ix.totalLen += length
+	ix.addFields(c)
+	return stored
+}
+
+// addFields индексирует слова полей. Берутся только слова из Words,
+// чтобы remove мог найти все вхождения комикса по ним.
+func (ix *invertedIndex) addFields(c Comic) {
+	if c.Fields.empty() {
+		ix.noFields[c.ID] = struct{}{}
+		return
+	}
+	known := make(map[string]bool, len(c.Words))
+	for _, w := range c.Words {
+		known[w] = true
+	}
+	for f, words := range c.Fields.byField() {
+		positions := make(map[string][]int, len(words))
+		order := make([]string, 0, len(words))
+		for pos, w := range words {
+			if !known[w] {
+				continue
+			}
+			if _, ok := positions[w]; !ok {
+				order = append(order, w)
+			}
+			positions[w] = append(positions[w], pos)
+		}
+		for _, w := range order {
+			ix.fields[f][ix.intern(w)].add(c.ID, positions[w])
+		}
+	}
+}
+
+func (ix *invertedIndex) remove(c Comic) {
+	length, ok := ix.docLen[c.ID]
+	if !ok {
+		return
+	}
+	ix.norms.Store(nil)
+	for _, w := range c.Words {
+		// из словаря слово не удаляется, в нечётком поиске и подсказках оно отсекается по df
+		id, ok := ix.dict.id(w)
+		if !ok {
+			continue
+		}
+		if ix.lists[id].remove(c.ID) && ix.lists[id].df == 0 {
+			ix.live--
+		}
+		if _, ok := ix.noFields[c.ID]; !ok {
+			for f := range ix.fields {
+				ix.fields[f][id].remove(c.ID)
+			}
+		}
+	}
+	delete(ix.noFields, c.ID)
+	delete(ix.docLen, c.ID)
+	ix.totalLen -= length
+}
+
+// compact освобождает запас памяти списков после полной сборки индекса
+// и сразу считает длины векторов для Similar
+func (ix *invertedIndex) compact() {
+	for i := range ix.lists {
+		ix.lists[i].compact()
+		for f := range ix.fields {
+			ix.fields[f][i].compact()
+		}
+	}
+	ix.vectorNorms()
+}
+
+// df - в скольких документах встречается слово
+func (ix *invertedIndex) df(term string) int {
+	if list := ix.list(term); list != nil {
+		return list.df
+	}
+	return 0
+}
+
+func (ix *invertedIndex) docs() int {
+	return len(ix.docLen)
+}
+
+// words - сколько разных слов есть в проиндексированных комиксах
+func (ix *invertedIndex) words() int {
+	return ix.live
+}
+
+// postings - сколько всего пар слово-комикс в индексе
+func (ix *invertedIndex) postings() int {
+	n := 0
+	for i := range ix.lists {
+		n += ix.lists[i].df
+	}
+	return n
+}
+
+// terms - слова, которые встречаются хотя бы в одном комиксе
+func (ix *invertedIndex) terms() iter.Seq2[string, *postingList] {
+	return func(yield func(string, *postingList) bool) {
+		for id, term := range ix.dict.terms {
+			if ix.lists[id].df > 0 && !yield(term, &ix.lists[id]) {
+				return
+			}
+		}
+	}
+}
+
+func (ix *invertedIndex) avgDocLen() float64 {
+	if len(ix.docLen) == 0 {
+		return 0
+	}
+	return float64(ix.totalLen) / float64(len(ix.docLen))
+}
+
+// lookup - вхождения слова в указанном поле, пустое поле - во всём комиксе.
+// В комиксах без слов по полям поле ищется по всему тексту, как раньше.
+func (ix *invertedIndex) lookup(field, term string) iter.Seq[posting] {
+	id, ok := ix.dict.id(term)
+	if !ok {
+		return func(func(posting) bool) {}
+	}
+	f, ok := fieldByName(field)
+	if !ok {
+		return ix.lists[id].all()
+	}
+	return func(yield func(posting) bool) {
+		for p := range ix.fields[f][id].all() {
+			if !yield(p) {
+				return
+			}
+		}
+		if len(ix.noFields) == 0 {
+			return
+		}
+		for p := range ix.lists[id].all() {
+			if _, ok := ix.noFields[p.id]; ok && !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+// hasFields - есть ли у комикса слова по полям
+func (ix *invertedIndex) hasFields(id int) bool {
+	_, ok := ix.noFields[id]
+	return !ok
+}
+
+// matchPhrase проверяет, что слова фразы стоят в комиксе в том же порядке
+// и на тех же расстояниях (offsets), что и в запросе, с допуском slop на каждое слово.
+// Если позиции у комикса не сохранены, достаточно наличия всех слов.
+func matchPhrase(lists [][]int, offsets []int, slop int) bool {
+	for _, list := range lists {
+		if len(list) == 0 {
+			return true
+		}
+	}
+
+	for _, start := range lists[0] {
+		ok := true
+		for i := 1; i < len(lists) && ok; i++ {
+			want := start + offsets[i] - offsets[0]
+			ok = slices.ContainsFunc(lists[i], func(p int) bool {
+				return p >= want-slop && p <= want+slop
+			})
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// minSpan - минимальное окно позиций, в котором встречаются все переданные слова.
+// Возвращает -1, если позиций нет хотя бы у одного слова.
+func minSpan(lists [][]int) int {
+	type occurrence struct{ pos, term int }
+
+	var all []occurrence
+	for term, list := range lists {
+		if len(list) == 0 {
+			return -1
+		}
+		for _, p := range list {
+			all = append(all, occurrence{pos: p, term: term})
+		}
+	}
+	slices.SortFunc(all, func(a, b occurrence) int { return a.pos - b.pos })
+
+	best := -1
+	counts := make([]int, len(lists))
+	covered := 0
+	left := 0
+	for _, o := range all {
+		if counts[o.term] == 0 {
+			covered++
+		}
+		counts[o.term]++
+		for covered == len(lists) {
+			if span := o.pos - all[left].pos; best < 0 || span < best {
+				best = span
+			}
+			counts[all[left].term]--
+			if counts[all[left].term] == 0 {
+				covered--
+			}
+			left++
+		}
+	}
+	return best
+}
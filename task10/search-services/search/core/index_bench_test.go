@@ -0,0 +1,337 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Сравнение сжатых списков вхождений с прежним устройством индекса:
+// map слово -> []{id, []позиции} и комиксы со своими картами позиций.
+//
+//	go test -run xxx -bench Index -benchmem ./search/core/
+
+const (
+	benchComics = 20000
+	benchVocab  = 50000
+	benchLen    = 120 // слов в комиксе с расшифровкой
+)
+
+// benchCorpus - комиксы со словами по закону Ципфа, как в живом тексте
+func benchCorpus(n int) []Comic {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, benchVocab-1)
+	comics := make([]Comic, n)
+	for i := range comics {
+		c := Comic{ID: i + 1, Positions: make(map[string][]int)}
+		for pos := range benchLen {
+			// строки создаются заново, как после разбора ответа базы
+			w := fmt.Sprintf("w%d", zipf.Uint64())
+			if _, ok := c.Positions[w]; !ok {
+				c.Words = append(c.Words, w)
+			}
+			c.Positions[w] = append(c.Positions[w], pos)
+		}
+		comics[i] = c
+	}
+	return comics
+}
+
+type plainPosting struct {
+	id        int
+	positions []int
+}
+
+type plainIndex struct {
+	postings map[string][]plainPosting
+	docLen   map[int]int
+	comics   map[int]Comic
+}
+
+func newPlainIndex(comics []Comic) *plainIndex {
+	ix := &plainIndex{
+		postings: make(map[string][]plainPosting),
+		docLen:   make(map[int]int),
+		comics:   make(map[int]Comic, len(comics)),
+	}
+	for _, c := range comics {
+		for _, w := range c.Words {
+			ix.postings[w] = append(ix.postings[w], plainPosting{id: c.ID, positions: c.Positions[w]})
+			ix.docLen[c.ID] += len(c.Positions[w])
+		}
+		ix.comics[c.ID] = c
+	}
+	return ix
+}
+
+// add и remove - как в прежнем индексе: дописывание в конец и удаление со сдвигом
+func (ix *plainIndex) add(c Comic) {
+	if _, ok := ix.docLen[c.ID]; ok {
+		return
+	}
+	for _, w := range c.Words {
+		ix.postings[w] = append(ix.postings[w], plainPosting{id: c.ID, positions: c.Positions[w]})
+		ix.docLen[c.ID] += len(c.Positions[w])
+	}
+	ix.comics[c.ID] = c
+}
+
+func (ix *plainIndex) remove(c Comic) {
+	if _, ok := ix.docLen[c.ID]; !ok {
+		return
+	}
+	for _, w := range c.Words {
+		list := slices.DeleteFunc(ix.postings[w], func(p plainPosting) bool { return p.id == c.ID })
+		if len(list) == 0 {
+			delete(ix.postings, w)
+			continue
+		}
+		ix.postings[w] = list
+	}
+	delete(ix.docLen, c.ID)
+	delete(ix.comics, c.ID)
+}
+
+type compressedIndex struct {
+	index  *invertedIndex
+	comics map[int]Comic
+}
+
+func newCompressedIndex(comics []Comic) *compressedIndex {
+	ix := &compressedIndex{index: newInvertedIndex(), comics: make(map[int]Comic, len(comics))}
+	for _, c := range comics {
+		ix.comics[c.ID] = ix.index.add(c)
+	}
+	ix.index.compact()
+	return ix
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// cloneCorpus - свежие копии строк и карт, чтобы индекс не делил их с эталоном
+func cloneCorpus(comics []Comic) []Comic {
+	res := make([]Comic, len(comics))
+	for i, c := range comics {
+		clone := Comic{ID: c.ID, Words: make([]string, len(c.Words)), Positions: make(map[string][]int, len(c.Positions))}
+		for j, w := range c.Words {
+			w = string([]byte(w))
+			clone.Words[j] = w
+			clone.Positions[w] = append([]int(nil), c.Positions[w]...)
+		}
+		res[i] = clone
+	}
+	return res
+}
+
+func BenchmarkIndexMemory(b *testing.B) {
+	corpus := benchCorpus(benchComics)
+
+	for _, bc := range []struct {
+		name  string
+		build func([]Comic) any
+	}{
+		{"plain", func(c []Comic) any { return newPlainIndex(c) }},
+		{"compressed", func(c []Comic) any { return newCompressedIndex(c) }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var inUse uint64
+			for b.Loop() {
+				b.StopTimer()
+				// комиксы из базы после сборки отпускаются, в замер попадает только то, что держит индекс
+				before := heapInUse()
+				comics := cloneCorpus(corpus)
+				b.StartTimer()
+
+				ix := bc.build(comics)
+
+				b.StopTimer()
+				comics = nil
+				inUse = heapInUse() - before
+				runtime.KeepAlive(ix)
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(inUse)/benchComics, "heap-B/comic")
+		})
+	}
+}
+
+// запросы из частого, среднего и редкого слов
+var benchQueries = [][]string{
+	{"w1", "w50", "w5000"},
+	{"w2", "w3"},
+	{"w100", "w700"},
+}
+
+func BenchmarkIndexLookup(b *testing.B) {
+	corpus := benchCorpus(benchComics)
+
+	b.Run("plain", func(b *testing.B) {
+		ix := newPlainIndex(corpus)
+		for b.Loop() {
+			for _, q := range benchQueries {
+				scores := make(map[int]float64)
+				for _, term := range q {
+					postings := ix.postings[term]
+					termIDF := idf(len(ix.docLen), len(postings))
+					for _, p := range postings {
+						scores[p.id] += testRanking.bm25(max(len(p.positions), 1), ix.docLen[p.id], benchLen, termIDF)
+					}
+				}
+			}
+		}
+	})
+
+	b.Run("compressed", func(b *testing.B) {
+		ix := newCompressedIndex(corpus).index
+		for b.Loop() {
+			for _, q := range benchQueries {
+				scores := make(map[int]float64)
+				for _, term := range q {
+					termIDF := idf(ix.docs(), ix.df(term))
+					for p := range ix.lookup("", term) {
+						scores[p.id] += testRanking.bm25(p.tf(), ix.docLen[p.id], benchLen, termIDF)
+					}
+				}
+			}
+		}
+	})
+}
+
+// Обновление: каждый сотый комикс удаляется и добавляется заново, как правка
+// в UpdateIndex. Частые слова есть почти в каждом комиксе, их списки длинные.
+func BenchmarkIndexUpdate(b *testing.B) {
+	corpus := benchCorpus(benchComics)
+	var changed []Comic
+	for i := 0; i < len(corpus); i += 100 {
+		changed = append(changed, corpus[i])
+	}
+
+	b.Run("plain", func(b *testing.B) {
+		ix := newPlainIndex(corpus)
+		for b.Loop() {
+			for _, c := range changed {
+				ix.remove(c)
+				ix.add(c)
+			}
+		}
+	})
+
+	b.Run("compressed", func(b *testing.B) {
+		ix := newCompressedIndex(corpus).index
+		for b.Loop() {
+			for _, c := range changed {
+				ix.remove(c)
+				ix.add(c)
+			}
+		}
+	})
+}
+
+func BenchmarkIndexSearch(b *testing.B) {
+	corpus := benchCorpus(benchComics)
+	svc, err := NewService(newTestLogger(), &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) { return corpus, nil },
+	}, &mockWords{
+		normFn: func(ctx context.Context, phrase string) ([]string, error) { return strings.Fields(phrase), nil },
+	}, testRanking, Shard{}, QueryCache{}, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := svc.RebuildIndex(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, phrase := range []string{"w1", "w50", "\"w1 w2\""} {
+		b.Run(phrase, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: phrase, Limit: 10}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchMetaCorpus - комиксы с текстами и написаниями слов, как их отдаёт база
+func benchMetaCorpus(n int) []Comic {
+	comics := benchCorpus(n)
+	for i := range comics {
+		c := &comics[i]
+		text := make([]string, benchLen)
+		c.Surfaces = make(map[string][]string, len(c.Words))
+		for _, w := range c.Words {
+			surface := strings.ToUpper(w[:1]) + w[1:]
+			c.Surfaces[w] = []string{surface}
+			for _, pos := range c.Positions[w] {
+				text[pos] = surface
+			}
+		}
+		c.URL = fmt.Sprintf("https://imgs.xkcd.com/comics/comic_%d.png", c.ID)
+		c.Meta = ComicMeta{
+			SafeTitle:  strings.Join(text[:3], " "),
+			Title:      strings.Join(text[:3], " "),
+			Alt:        strings.Join(text[3:25], " "),
+			Transcript: strings.Join(text, " "),
+		}
+	}
+	return comics
+}
+
+// Память, которую держит сервис поиска после перестройки индекса по базе:
+//
+//	go test -run xxx -bench ServiceMemory ./search/core/
+func BenchmarkServiceMemory(b *testing.B) {
+	corpus := benchMetaCorpus(benchComics)
+
+	var inUse uint64
+	for b.Loop() {
+		b.StopTimer()
+		before := heapInUse()
+		comics := cloneMetaCorpus(corpus)
+		db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return comics, nil }}
+		svc, err := NewService(newTestLogger(), db, &mockWords{}, testRanking, Shard{}, QueryCache{}, nil, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		if err := svc.RebuildIndex(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		comics = nil
+		inUse = heapInUse() - before
+		runtime.KeepAlive(svc)
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(inUse)/benchComics, "heap-B/comic")
+}
+
+// cloneMetaCorpus - как cloneCorpus, но с текстами и написаниями
+func cloneMetaCorpus(comics []Comic) []Comic {
+	res := cloneCorpus(comics)
+	for i, c := range comics {
+		res[i].URL = strings.Clone(c.URL)
+		res[i].Meta = ComicMeta{
+			SafeTitle:  strings.Clone(c.Meta.SafeTitle),
+			Title:      strings.Clone(c.Meta.Title),
+			Alt:        strings.Clone(c.Meta.Alt),
+			Transcript: strings.Clone(c.Meta.Transcript),
+		}
+		res[i].Surfaces = make(map[string][]string, len(c.Surfaces))
+		for w, ss := range c.Surfaces {
+			res[i].Surfaces[strings.Clone(w)] = []string{strings.Clone(ss[0])}
+		}
+	}
+	return res
+}
@@ -1,7 +1,133 @@
 package core
 
+import (
+	"fmt"
+	"time"
+)
+
 type Comic struct {
-	ID    int
-	URL   string
-	Words []string
+	ID        int
+	URL       string
+	Words     []string
+	Positions map[string][]int    // может отсутствовать у старых записей
+	Surfaces  map[string][]string // написания слов в текстах, тоже может отсутствовать
+	Score     float64             // оценка в выдаче, по ней сливаются ответы шардов
+	Snippet   *Snippet            // кусок текста с найденными словами, только в выдаче
+	Explain   *Explanation        // разбор оценки, только в выдаче по запросу с Explain
+	Fields    FieldWords          // как и позиции, хранится только в индексе
+	Meta      ComicMeta
+}
+
+// FieldWords - нормализованные слова названия, alt и расшифровки по отдельности,
+// в порядке текста и с повторами. Пусто у записей, сохранённых до появления полей.
+type FieldWords struct {
+	Title      []string
+	Alt        []string
+	Transcript []string
+}
+
+func (f FieldWords) empty() bool {
+	return len(f.Title) == 0 && len(f.Alt) == 0 && len(f.Transcript) == 0
+}
+
+// byField - слова по номеру поля индекса
+func (f FieldWords) byField() [numFields][]string {
+	return [numFields][]string{fieldTitle: f.Title, fieldAlt: f.Alt, fieldTranscript: f.Transcript}
+}
+
+// ComicMeta - сведения о комиксе для карточки в выдаче, в поиске не участвуют.
+// В памяти сервиса не хранятся: их читают из базы только для страницы выдачи.
+type ComicMeta struct {
+	SafeTitle  string
+	Title      string
+	Alt        string
+	Transcript string
+	Published  time.Time // нулевое время, если дата неизвестна
+	Link       string
+	News       string
+}
+
+// Snippet - кусок названия, alt или расшифровки комикса, где нашлись слова запроса.
+// Highlights - байтовые отрезки [Start, End) найденных слов внутри Text.
+type Snippet struct {
+	Field      string
+	Text       string
+	Highlights []Span
+}
+
+type Span struct {
+	Start int
+	End   int
+}
+
+// Explanation - из чего сложилась оценка комикса и почему он стоит на своём месте.
+// TieBreak - признак, по которому комикс оказался выше следующего в выдаче,
+// пусто у последнего комикса.
+type Explanation struct {
+	Terms     []string    // слова запроса после нормализации, с найденными опечатками
+	Matched   []TermScore // совпавшие слова
+	Proximity float64     // множитель за близость совпавших слов, 1 - без бонуса
+	Matches   int
+	Ratio     float64
+	Score     float64
+	TieBreak  TieBreak
+}
+
+// TermScore - вклад слова запроса в оценку по BM25, до множителя за близость
+type TermScore struct {
+	Term   string
+	Field  string
+	Dist   int     // число опечаток, 0 - точное совпадение
+	Weight float64 // понижение за опечатки
+	TF     int
+	IDF    float64
+	Score  float64
+}
+
+// IndexChanges - какие комиксы изменились в базе с прошлого обновления индекса.
+// Dropped - база очищена целиком.
+type IndexChanges struct {
+	Added   []int
+	Changed []int
+	Removed []int
+	Dropped bool
+}
+
+// SearchQuery - параметры поискового запроса
+type SearchQuery struct {
+	Phrase    string
+	Limit     int
+	Offset    int  // сколько первых найденных комиксов пропустить
+	Fuzziness int  // число допустимых опечаток в слове, FuzzinessAuto - по длине слова
+	Explain   bool // приложить к комиксам разбор оценки
+}
+
+func (q SearchQuery) validate() error {
+	if q.Phrase == "" || q.Limit <= 0 || q.Offset < 0 {
+		return ErrBadArguments
+	}
+	if q.Fuzziness < FuzzinessAuto || q.Fuzziness > MaxFuzziness {
+		return fmt.Errorf("%w: fuzziness must be from %d to %d", ErrBadArguments, FuzzinessAuto, MaxFuzziness)
+	}
+	return nil
+}
+
+// SearchResult - страница найденных комиксов и сколько всего нашлось.
+// Generation - поколение индекса, по которому посчитана выдача, 0 - неизвестно.
+type SearchResult struct {
+	Comics     []Comic
+	Total      int
+	Generation uint64
+}
+
+// Suggestion - подсказка к началу слова и в скольких комиксах оно встречается
+type Suggestion struct {
+	Word  string
+	Count int
+}
+
+// Token - нормализованное слово и его позиция в исходном тексте
+type Token struct {
+	Word string
+	Pos  int
 }
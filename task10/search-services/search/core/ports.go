@@ -3,18 +3,53 @@ package core
 import "context"
 
 type DB interface {
-	Search(ctx context.Context) ([]Comic, error)
+	// Scan по одному передаёт в fn все комиксы базы, не загружая их разом.
+	// Ошибка fn прерывает обход и возвращается из Scan.
+	Scan(ctx context.Context, fn func(Comic) error) error
+	Get(ctx context.Context, ids []int) ([]Comic, error)
 }
 
 type Words interface {
 	Norm(ctx context.Context, phrase string) ([]string, error)
+	Tokens(ctx context.Context, phrase string) ([]Token, error)
+}
+
+// SnapshotStore хранит снимок индекса между перезапусками
+type SnapshotStore interface {
+	Save(ctx context.Context, data []byte) error
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// SynonymStore хранит словарь синонимов в текстовом виде
+type SynonymStore interface {
+	Save(ctx context.Context, data []byte) error
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// SynonymEditor - просмотр и замена словаря синонимов
+type SynonymEditor interface {
+	Synonyms(ctx context.Context) (string, error)
+	ReplaceSynonyms(ctx context.Context, text string) error
 }
 
 type Searcher interface {
-	Search(ctx context.Context, phrase string, limit int) ([]Comic, error)
-	IndexSearch(ctx context.Context, phrase string, limit int) ([]Comic, error)
+	Search(ctx context.Context, query SearchQuery) (SearchResult, error)
+	IndexSearch(ctx context.Context, query SearchQuery) (SearchResult, error)
+	Suggest(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+	Similar(ctx context.Context, id, limit int) ([]Comic, error)
+	Stats(ctx context.Context) (IndexStats, error)
 }
 
 type Indexer interface {
 	RebuildIndex(ctx context.Context) error
+	UpdateIndex(ctx context.Context, changes IndexChanges) error
+}
+
+// TextIndex - полнотекстовый индекс в базе по нормализованным словам комиксов.
+// query - запрос в синтаксисе to_tsquery, комиксы ранжируются по ts_rank.
+type TextIndex interface {
+	SearchText(ctx context.Context, query string, limit, offset int) (SearchResult, error)
+	SuggestText(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+	// TextStats - число комиксов, слов словаря и пар слово-комикс в базе
+	TextStats(ctx context.Context) (IndexStats, error)
 }
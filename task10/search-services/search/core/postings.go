@@ -0,0 +1,361 @@
+package core
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// posting - вхождение слова в комикс. Позиции лежат сжатыми
+// и разжимаются только когда нужны, например для фраз.
+type posting struct {
+	id    int
+	count int    // сколько позиций сохранено, 0 - позиций нет
+	raw   []byte // позиции разницами с предыдущей, varint
+}
+
+func (p posting) tf() int {
+	return max(p.count, 1)
+}
+
+func (p posting) positions() []int {
+	if p.count == 0 {
+		return nil
+	}
+	res := make([]int, 0, p.count)
+	data, pos := p.raw, 0
+	for range p.count {
+		delta, n := binary.Varint(data)
+		data = data[n:]
+		pos += int(delta)
+		res = append(res, pos)
+	}
+	return res
+}
+
+func appendPositions(buf []byte, positions []int) []byte {
+	prev := 0
+	for _, pos := range positions {
+		buf = binary.AppendVarint(buf, int64(pos-prev))
+		prev = pos
+	}
+	return buf
+}
+
+// postingList - вхождения слова по возрастанию id комикса одним куском байт.
+// На каждый комикс: uvarint разницы id с предыдущим, uvarint числа позиций,
+// uvarint длины позиций в байтах и сами позиции.
+type postingList struct {
+	data   []byte
+	df     int
+	lastID int
+	// skips делят длинный список на куски: поиск, вставка и удаление
+	// разбирают один кусок, а не весь список. У коротких списков nil.
+	skips []skip
+}
+
+// skip - кусок списка: его первое вхождение начинается в data с off,
+// id этого вхождения считается от prev, n - вхождений в куске
+type skip struct {
+	id, prev, off, n int
+}
+
+// skipEvery - размер куска. Куски растут вставками до 2*skipEvery и тогда делятся.
+const skipEvery = 64
+
+var errBadPostings = errors.New("bad posting list")
+
+// read разбирает вхождение, которое начинается в data с off, id
+// предыдущего - prev. Вторым значением - где начинается следующее.
+func (l *postingList) read(off, prev int) (posting, int) {
+	delta, n1 := binary.Uvarint(l.data[off:])
+	count, n2 := binary.Uvarint(l.data[off+n1:])
+	size, n3 := binary.Uvarint(l.data[off+n1+n2:])
+	start := off + n1 + n2 + n3
+	end := start + int(size)
+	return posting{id: prev + int(delta), count: int(count), raw: l.data[start:end:end]}, end
+}
+
+func appendPosting(buf []byte, delta, count int, raw []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(delta))
+	buf = binary.AppendUvarint(buf, uint64(count))
+	buf = binary.AppendUvarint(buf, uint64(len(raw)))
+	return append(buf, raw...)
+}
+
+func (l *postingList) appendRaw(id, count int, raw []byte) {
+	off, prev := len(l.data), l.lastID
+	l.data = appendPosting(l.data, id-prev, count, raw)
+	l.df++
+	l.lastID = id
+
+	switch {
+	case l.skips != nil && l.skips[len(l.skips)-1].n < skipEvery:
+		l.skips[len(l.skips)-1].n++
+	case l.skips != nil:
+		l.skips = append(l.skips, skip{id: id, prev: prev, off: off, n: 1})
+	case l.df > skipEvery:
+		l.buildSkips()
+	}
+}
+
+// buildSkips заново делит список на куски по skipEvery вхождений
+func (l *postingList) buildSkips() {
+	l.skips = nil
+	if l.df <= skipEvery {
+		return
+	}
+	l.skips = make([]skip, 0, (l.df+skipEvery-1)/skipEvery)
+	off, prev := 0, 0
+	for i := 0; off < len(l.data); i++ {
+		p, end := l.read(off, prev)
+		if i%skipEvery == 0 {
+			l.skips = append(l.skips, skip{id: p.id, prev: prev, off: off})
+		}
+		l.skips[len(l.skips)-1].n++
+		off, prev = end, p.id
+	}
+}
+
+// seek - откуда разбирать список в поисках id: начало последнего куска,
+// первый id которого не больше искомого. Без кусков - начало списка и chunk -1.
+func (l *postingList) seek(id int) (off, prev, chunk int) {
+	if len(l.skips) == 0 {
+		return 0, 0, -1
+	}
+	i, found := slices.BinarySearchFunc(l.skips, id, func(s skip, id int) int {
+		return cmp.Compare(s.id, id)
+	})
+	if !found {
+		i = max(i-1, 0)
+	}
+	return l.skips[i].off, l.skips[i].prev, i
+}
+
+// splice заменяет data[from:to] на repl и сдвигает начала следующих кусков
+func (l *postingList) splice(from, to int, repl []byte) {
+	l.data = slices.Replace(l.data, from, to, repl...)
+	shift := len(repl) - (to - from)
+	for i := range l.skips {
+		if l.skips[i].off >= to {
+			l.skips[i].off += shift
+		}
+	}
+}
+
+// add вставляет комикс на его место в списке. Комиксы приходят в основном
+// по возрастанию id и дописываются в конец, вставка в середину
+// переписывает одно вхождение и сдвигает хвост данных.
+func (l *postingList) add(id int, positions []int) {
+	raw := appendPositions(nil, positions)
+	if l.df == 0 || id > l.lastID {
+		l.appendRaw(id, len(positions), raw)
+		return
+	}
+
+	off, prev, chunk := l.seek(id)
+	for {
+		p, end := l.read(off, prev)
+		if p.id == id {
+			return
+		}
+		if p.id < id {
+			off, prev = end, p.id
+			continue
+		}
+		// новое вхождение встаёт перед p, разница id у p теперь считается от id
+		_, n := binary.Uvarint(l.data[off:])
+		repl := appendPosting(nil, id-prev, len(positions), raw)
+		size := len(repl)
+		repl = binary.AppendUvarint(repl, uint64(p.id-id))
+		// p начинал следующий кусок - новое вхождение дописывается в конец этого
+		nextChunk := chunk >= 0 && chunk+1 < len(l.skips) && l.skips[chunk+1].off == off
+		l.splice(off, off+n, repl)
+		l.df++
+		if chunk < 0 {
+			if l.df > skipEvery {
+				l.buildSkips()
+			}
+			return
+		}
+		if nextChunk {
+			l.skips[chunk+1].off, l.skips[chunk+1].prev = off+size, id
+		}
+		// или этот: тогда кусок начинается с нового
+		s := &l.skips[chunk]
+		if s.off == off {
+			s.id = id
+		}
+		if s.n++; s.n > 2*skipEvery {
+			l.split(chunk)
+		}
+		return
+	}
+}
+
+// split делит разросшийся кусок пополам
+func (l *postingList) split(chunk int) {
+	s := l.skips[chunk]
+	half := s.n / 2
+	off, prev := s.off, s.prev
+	for range half {
+		p, end := l.read(off, prev)
+		off, prev = end, p.id
+	}
+	p, _ := l.read(off, prev)
+	l.skips[chunk].n = half
+	l.skips = slices.Insert(l.skips, chunk+1, skip{id: p.id, prev: prev, off: off, n: s.n - half})
+}
+
+// remove убирает комикс из списка, возвращает false, если его там не было
+func (l *postingList) remove(id int) bool {
+	if l.df == 0 || id > l.lastID {
+		return false
+	}
+
+	off, prev, chunk := l.seek(id)
+	for off < len(l.data) {
+		p, end := l.read(off, prev)
+		if p.id < id {
+			off, prev = end, p.id
+			continue
+		}
+		if p.id > id {
+			return false
+		}
+		if l.df == 1 {
+			*l = postingList{}
+			return true
+		}
+
+		if end == len(l.data) {
+			l.splice(off, end, nil)
+			l.lastID = prev
+		} else {
+			// следующее вхождение теперь считается от предыдущего удалённому
+			next, _ := l.read(end, p.id)
+			_, n := binary.Uvarint(l.data[end:])
+			// next начинал следующий кусок - тот теперь начинается с off
+			nextChunk := chunk >= 0 && chunk+1 < len(l.skips) && l.skips[chunk+1].off == end
+			l.splice(off, end+n, binary.AppendUvarint(nil, uint64(next.id-prev)))
+			if nextChunk {
+				l.skips[chunk+1].off, l.skips[chunk+1].prev = off, prev
+			}
+			// удалённый начинал этот кусок - теперь его начинает next
+			if chunk >= 0 && l.skips[chunk].id == id {
+				l.skips[chunk].id = next.id
+			}
+		}
+		l.df--
+		if chunk >= 0 {
+			if l.skips[chunk].n--; l.skips[chunk].n == 0 {
+				l.skips = slices.Delete(l.skips, chunk, chunk+1)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (l *postingList) get(id int) (posting, bool) {
+	if l.df == 0 || id > l.lastID {
+		return posting{}, false
+	}
+	off, prev, _ := l.seek(id)
+	for off < len(l.data) {
+		p, end := l.read(off, prev)
+		if p.id >= id {
+			return p, p.id == id
+		}
+		off, prev = end, p.id
+	}
+	return posting{}, false
+}
+
+func (l *postingList) all() iter.Seq[posting] {
+	return func(yield func(posting) bool) {
+		off, id := 0, 0
+		for off < len(l.data) {
+			p, end := l.read(off, id)
+			if !yield(p) {
+				return
+			}
+			off, id = end, p.id
+		}
+	}
+}
+
+// check проверяет список, пришедший извне, например из снимка:
+// после него all() не выйдет за границы данных
+func (l *postingList) check() error {
+	data, id, df := l.data, 0, 0
+	for len(data) > 0 {
+		var fields [3]uint64
+		for i := range fields {
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return errBadPostings
+			}
+			fields[i], data = v, data[n:]
+		}
+		delta, count, size := fields[0], fields[1], fields[2]
+		if (df > 0 && delta == 0) || size > uint64(len(data)) || count > size {
+			return errBadPostings
+		}
+		raw := data[:size]
+		for range count {
+			_, n := binary.Varint(raw)
+			if n <= 0 {
+				return errBadPostings
+			}
+			raw = raw[n:]
+		}
+		id += int(delta)
+		df++
+		data = data[size:]
+	}
+	if df != l.df || id != l.lastID {
+		return errBadPostings
+	}
+	return nil
+}
+
+// compact отдаёт запас ёмкости, оставшийся от дописывания
+func (l *postingList) compact() {
+	if cap(l.data) > len(l.data) {
+		l.data = slices.Clone(l.data)
+	}
+	if cap(l.skips) > len(l.skips) {
+		l.skips = slices.Clone(l.skips)
+	}
+}
+
+// termDict - интернированный словарь: каждое слово хранится один раз,
+// на него ссылаются списки вхождений, комиксы и словарь нечёткого поиска
+type termDict struct {
+	ids   map[string]int
+	terms []string
+}
+
+func (d *termDict) id(term string) (int, bool) {
+	id, ok := d.ids[term]
+	return id, ok
+}
+
+func (d *termDict) intern(term string) (int, bool) {
+	if id, ok := d.ids[term]; ok {
+		return id, false
+	}
+	if d.ids == nil {
+		d.ids = make(map[string]int)
+	}
+	// копия, чтобы не держать в памяти строку, из которой слово вырезано
+	term = strings.Clone(term)
+	id := len(d.terms)
+	d.ids[term] = id
+	d.terms = append(d.terms, term)
+	return id, true
+}
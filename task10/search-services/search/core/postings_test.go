@@ -0,0 +1,177 @@
+package core
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(l *postingList) map[int][]int {
+	res := make(map[int][]int)
+	for p := range l.all() {
+		res[p.id] = p.positions()
+	}
+	return res
+}
+
+func TestPostingList_AddRemove(t *testing.T) {
+	var l postingList
+	l.add(3, []int{5, 1, 300})
+	l.add(10, nil)
+	l.add(1, []int{0}) // вставка в начало
+	l.add(7, []int{2}) // вставка в середину
+	l.add(7, []int{9}) // повтор не добавляется
+
+	assert.Equal(t, 4, l.df)
+	assert.Equal(t, 10, l.lastID)
+	assert.Equal(t, map[int][]int{1: {0}, 3: {5, 1, 300}, 7: {2}, 10: nil}, collect(&l))
+
+	var ids []int
+	for p := range l.all() {
+		ids = append(ids, p.id)
+	}
+	assert.Equal(t, []int{1, 3, 7, 10}, ids)
+
+	p, ok := l.get(3)
+	require.True(t, ok)
+	assert.Equal(t, 3, p.tf())
+	_, ok = l.get(4)
+	assert.False(t, ok)
+
+	assert.False(t, l.remove(4))
+	assert.True(t, l.remove(10))
+	assert.True(t, l.remove(1))
+	assert.Equal(t, 2, l.df)
+	assert.Equal(t, 7, l.lastID)
+	assert.Equal(t, map[int][]int{3: {5, 1, 300}, 7: {2}}, collect(&l))
+	require.NoError(t, l.check())
+
+	assert.True(t, l.remove(3))
+	assert.True(t, l.remove(7))
+	assert.Zero(t, l.df)
+	assert.Empty(t, l.data)
+}
+
+func TestPostingList_Check(t *testing.T) {
+	var l postingList
+	l.add(1, []int{0, 4})
+	l.add(5, nil)
+	require.NoError(t, l.check())
+
+	testCases := []struct {
+		name string
+		list postingList
+	}{
+		{"truncated", postingList{data: l.data[:len(l.data)-1], df: l.df, lastID: l.lastID}},
+		{"wrong df", postingList{data: l.data, df: l.df + 1, lastID: l.lastID}},
+		{"wrong last id", postingList{data: l.data, df: l.df, lastID: 4}},
+		{"positions out of bounds", postingList{data: []byte{1, 5, 1, 0}, df: 1, lastID: 1}},
+		{"bad varint", postingList{data: []byte{0xff}, df: 1, lastID: 1}},
+		{"repeated id", postingList{data: []byte{1, 0, 0, 0, 0, 0}, df: 2, lastID: 1}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, tc.list.check(), errBadPostings)
+		})
+	}
+}
+
+// checkSkips - куски покрывают список целиком по порядку и начинаются с вхождений
+func checkSkips(t *testing.T, l *postingList) {
+	t.Helper()
+	off, prev, total := 0, 0, 0
+	for _, s := range l.skips {
+		require.Equal(t, off, s.off)
+		require.Equal(t, prev, s.prev)
+		require.Positive(t, s.n)
+		require.LessOrEqual(t, s.n, 2*skipEvery)
+		for i := range s.n {
+			p, end := l.read(off, prev)
+			if i == 0 {
+				require.Equal(t, s.id, p.id)
+			}
+			off, prev = end, p.id
+		}
+		total += s.n
+	}
+	if l.skips != nil {
+		require.Equal(t, len(l.data), off)
+		require.Equal(t, l.df, total)
+	}
+}
+
+func TestPostingList_Skips(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var l postingList
+	model := make(map[int][]int)
+
+	for i := range 20000 {
+		id := r.Intn(2000) + 1
+		// сначала в основном дописывание, потом вперемешку вставки и удаления
+		if i < 1500 {
+			id = i + 1
+		}
+		if i >= 1500 && r.Intn(2) == 0 {
+			_, ok := model[id]
+			assert.Equal(t, ok, l.remove(id))
+			delete(model, id)
+		} else {
+			positions := []int{r.Intn(100), 100 + r.Intn(100)}
+			if _, ok := model[id]; !ok {
+				model[id] = positions
+			}
+			l.add(id, positions)
+		}
+
+		if i%100 == 0 {
+			checkSkips(t, &l)
+			require.Equal(t, len(model), l.df)
+			require.NoError(t, l.check())
+			for range 50 {
+				id := r.Intn(2100)
+				p, ok := l.get(id)
+				want, has := model[id]
+				require.Equal(t, has, ok, "id %d", id)
+				if has {
+					require.Equal(t, want, p.positions())
+				}
+			}
+		}
+	}
+	checkSkips(t, &l)
+	assert.Equal(t, model, collect(&l))
+	assert.NotEmpty(t, l.skips)
+
+	// после снимка куски строятся заново
+	loaded := postingList{data: l.data, df: l.df, lastID: l.lastID}
+	loaded.buildSkips()
+	checkSkips(t, &loaded)
+}
+
+func TestInvertedIndex_InternsWords(t *testing.T) {
+	ix := newInvertedIndex()
+	// слова приходят отдельными строками от разных комиксов
+	a := ix.add(Comic{ID: 1, Words: []string{string([]byte("foo"))}, Positions: map[string][]int{"foo": {0}}})
+	b := ix.add(Comic{ID: 2, Words: []string{string([]byte("foo")), "bar"}})
+
+	assert.Nil(t, a.Positions)
+	assert.Same(t, unsafe.StringData(a.Words[0]), unsafe.StringData(b.Words[0]))
+	assert.Equal(t, 2, ix.words())
+	assert.Equal(t, 2, ix.df("foo"))
+
+	ix.remove(b)
+	assert.Equal(t, 1, ix.words())
+	assert.Zero(t, ix.df("bar"))
+	assert.Equal(t, []string{"foo"}, liveTerms(ix))
+}
+
+func liveTerms(ix *invertedIndex) []string {
+	var terms []string
+	for term := range ix.terms() {
+		terms = append(terms, term)
+	}
+	return terms
+}
@@ -0,0 +1,444 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Язык запросов:
+//
+//	linux cpu            - любое из слов (как и раньше)
+//	linux AND cpu        - оба слова
+//	linux NOT windows    - исключение, то же что linux -windows
+//	"bobby tables"       - фраза, слова рядом и в том же порядке
+//	"bobby tables"~2     - фраза, каждое слово может сдвинуться на 2 позиции
+//	(a OR b) AND c       - группировка
+//	title:foo alt:"a b"  - поиск по полю (title, alt или transcript)
+//	Re: foo, http://x    - двоеточие не после имени поля - просто часть текста
+//	id:100..200          - диапазон номеров, границы можно опускать
+//
+// Приоритет операторов: NOT, затем AND, затем OR.
+// Соседние условия без оператора объединяются через OR.
+
+const (
+	FieldTitle      = "title"
+	FieldAlt        = "alt"
+	FieldTranscript = "transcript"
+	FieldID         = "id"
+)
+
+// QueryError - синтаксическая ошибка в запросе, Pos считается в символах с 1
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("bad query at position %d: %s", e.Pos, e.Msg)
+}
+
+func (e *QueryError) Unwrap() error {
+	return ErrBadArguments
+}
+
+type queryNode interface {
+	isQueryNode()
+}
+
+// termNode - слово или фраза в кавычках, stems и tokens заполняются при нормализации
+type termNode struct {
+	pos    int
+	field  string
+	text   string
+	phrase bool
+	slop   int
+	stems  []string
+	tokens []Token      // слова фразы с позициями
+	fuzzy  []fuzzyMatch // похожие слова индекса, если разрешены опечатки
+	// синонимы из словаря: нормализованные слова каждого, нужны все сразу
+	synonyms [][]string
+}
+
+type rangeNode struct {
+	pos      int
+	from, to int
+}
+
+type andNode struct {
+	pos      int
+	children []queryNode
+}
+
+type orNode struct {
+	pos      int
+	children []queryNode
+}
+
+type notNode struct {
+	pos   int
+	child queryNode
+}
+
+func (*termNode) isQueryNode()  {}
+func (*rangeNode) isQueryNode() {}
+func (*andNode) isQueryNode()   {}
+func (*orNode) isQueryNode()    {}
+func (*notNode) isQueryNode()   {}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField
+	tokMinus
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	slop int
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && r != '(' && r != ')' && r != '"' && r != ':'
+}
+
+// isQueryField - известное поле запроса, остальные слова с двоеточием ищутся как текст
+func isQueryField(name string) bool {
+	switch strings.ToLower(name) {
+	case FieldTitle, FieldAlt, FieldTranscript, FieldID:
+		return true
+	}
+	return false
+}
+
+// isFieldValueRune - может ли с этого символа начинаться значение поля
+func isFieldValueRune(r rune) bool {
+	return r != ':' && r != ')' && !unicode.IsSpace(r)
+}
+
+func lexQuery(query string) ([]token, error) {
+	runes := []rune(query)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, pos: pos})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &QueryError{Pos: pos, Msg: "unterminated quote"}
+			}
+			phrase := token{kind: tokPhrase, text: string(runes[i+1 : end]), pos: pos}
+			i = end + 1
+			if i < len(runes) && runes[i] == '~' {
+				j := i + 1
+				for j < len(runes) && unicode.IsDigit(runes[j]) {
+					j++
+				}
+				slop, err := strconv.Atoi(string(runes[i+1 : j]))
+				if err != nil {
+					return nil, &QueryError{Pos: i + 1, Msg: "expected number of positions after '~'"}
+				}
+				phrase.slop = slop
+				i = j
+			}
+			tokens = append(tokens, phrase)
+		case r == '-' && i+1 < len(runes) && (isWordRune(runes[i+1]) && runes[i+1] != '-' || runes[i+1] == '"' || runes[i+1] == '('):
+			// минус в начале слова - исключение, внутри слова (x-ray) - часть слова
+			tokens = append(tokens, token{kind: tokMinus, pos: pos})
+			i++
+		default:
+			end := i
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			text := string(runes[i:end])
+			if end < len(runes) && runes[end] == ':' {
+				if isQueryField(text) && end+1 < len(runes) && isFieldValueRune(runes[end+1]) {
+					tokens = append(tokens, token{kind: tokField, text: strings.ToLower(text), pos: pos})
+					i = end + 1
+					continue
+				}
+				// не поле ("Re: foo", "http://xkcd.com", "12:30") - двоеточие часть слова
+				for end < len(runes) && (isWordRune(runes[end]) || runes[end] == ':') {
+					end++
+				}
+				tokens = append(tokens, token{kind: tokWord, text: string(runes[i:end]), pos: pos})
+				i = end
+				continue
+			}
+			kind := tokWord
+			switch text {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: pos})
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+type queryParser struct {
+	tokens []token
+	cur    int
+	field  string
+}
+
+func parseQuery(query string) (queryNode, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &QueryError{Pos: t.pos, Msg: "unexpected " + describe(t)}
+	}
+	if err := checkNegations(node, false); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// checkNegations запрещает запросы, в которых исключать не из чего: "-foo", "NOT (a OR b)"
+func checkNegations(node queryNode, inGroup bool) error {
+	switch n := node.(type) {
+	case *notNode:
+		if !inGroup {
+			return &QueryError{Pos: n.pos, Msg: "negation needs a positive term to exclude from"}
+		}
+		return checkNegations(n.child, false)
+	case *andNode:
+		return checkGroupNegations(n.pos, n.children)
+	case *orNode:
+		return checkGroupNegations(n.pos, n.children)
+	}
+	return nil
+}
+
+func checkGroupNegations(pos int, children []queryNode) error {
+	positive := false
+	for _, child := range children {
+		if _, ok := child.(*notNode); !ok {
+			positive = true
+		}
+		if err := checkNegations(child, true); err != nil {
+			return err
+		}
+	}
+	if !positive {
+		return &QueryError{Pos: pos, Msg: "group has only negated terms"}
+	}
+	return nil
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokLParen:
+		return "'('"
+	case tokRParen:
+		return "')'"
+	case tokMinus:
+		return "'-'"
+	case tokPhrase:
+		return "phrase"
+	case tokField:
+		return fmt.Sprintf("field %q", t.text)
+	default:
+		return strconv.Quote(t.text)
+	}
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.cur]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.cur]
+	if t.kind != tokEOF {
+		p.cur++
+	}
+	return t
+}
+
+// начинает ли токен новый операнд
+func startsOperand(t token) bool {
+	switch t.kind {
+	case tokWord, tokPhrase, tokField, tokMinus, tokLParen, tokNot:
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	pos := p.peek().pos
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []queryNode{first}
+	for {
+		t := p.peek()
+		if t.kind == tokOr {
+			p.next()
+		} else if !startsOperand(t) {
+			break
+		}
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &orNode{pos: pos, children: children}, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	pos := p.peek().pos
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []queryNode{first}
+	for p.peek().kind == tokAnd {
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &andNode{pos: pos, children: children}, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	t := p.peek()
+	if t.kind == tokNot || t.kind == tokMinus {
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{pos: t.pos, child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokWord:
+		return &termNode{pos: t.pos, field: p.field, text: t.text}, nil
+	case tokPhrase:
+		return &termNode{pos: t.pos, field: p.field, text: t.text, phrase: true, slop: t.slop}, nil
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &QueryError{Pos: closing.pos, Msg: fmt.Sprintf("expected ')' to close '(' at position %d, got %s", t.pos, describe(closing))}
+		}
+		return node, nil
+	case tokField:
+		return p.parseField(t)
+	default:
+		return nil, &QueryError{Pos: t.pos, Msg: "unexpected " + describe(t)}
+	}
+}
+
+func (p *queryParser) parseField(t token) (queryNode, error) {
+	if p.field != "" {
+		return nil, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("field %q inside field %q", t.text, p.field)}
+	}
+	switch t.text {
+	case FieldID:
+		value := p.next()
+		if value.kind != tokWord {
+			return nil, &QueryError{Pos: value.pos, Msg: "expected id or id range after 'id:'"}
+		}
+		return parseIDRange(value)
+	case FieldTitle, FieldAlt, FieldTranscript:
+		value := p.peek()
+		if value.kind != tokWord && value.kind != tokPhrase && value.kind != tokLParen {
+			return nil, &QueryError{Pos: value.pos, Msg: fmt.Sprintf("expected word, phrase or group after '%s:'", t.text)}
+		}
+		p.field = t.text
+		defer func() { p.field = "" }()
+		return p.parsePrimary()
+	default:
+		return nil, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("unknown field %q", t.text)}
+	}
+}
+
+func parseIDRange(t token) (queryNode, error) {
+	bad := &QueryError{Pos: t.pos, Msg: fmt.Sprintf("bad id range %q", t.text)}
+
+	from, to, isRange := strings.Cut(t.text, "..")
+	if !isRange {
+		id, err := strconv.Atoi(t.text)
+		if err != nil || id < 0 {
+			return nil, bad
+		}
+		return &rangeNode{pos: t.pos, from: id, to: id}, nil
+	}
+	if from == "" && to == "" {
+		return nil, bad
+	}
+
+	n := &rangeNode{pos: t.pos, from: 0, to: int(^uint(0) >> 1)}
+	var err error
+	if from != "" {
+		if n.from, err = strconv.Atoi(from); err != nil || n.from < 0 {
+			return nil, bad
+		}
+	}
+	if to != "" {
+		if n.to, err = strconv.Atoi(to); err != nil || n.to < 0 {
+			return nil, bad
+		}
+	}
+	if n.from > n.to {
+		return nil, bad
+	}
+	return n, nil
+}
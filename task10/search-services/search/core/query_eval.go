@@ -0,0 +1,324 @@
+package core
+
+import (
+	"context"
+	"slices"
+)
+
+type docSet map[int]struct{}
+
+// queryTerm - нормализованное слово запроса, участвующее в ранжировании,
+// dist - число опечаток, если слово подставлено нечётким поиском,
+// synonym - слово пришло из словаря синонимов
+type queryTerm struct {
+	field   string
+	stem    string
+	dist    int
+	synonym bool
+}
+
+func (t queryTerm) weight() float64 {
+	if t.synonym {
+		return synonymWeight
+	}
+	return editWeight(t.dist)
+}
+
+// prepareQuery разбирает запрос и нормализует его слова через words сервис.
+// Возвращает nil, если после нормализации в запросе ничего не осталось.
+func prepareQuery(ctx context.Context, words Words, phrase string) (queryNode, error) {
+	node, err := parseQuery(phrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// простой запрос из слов нормализуем одним вызовом, как и раньше
+	if isPlainQuery(node) {
+		stems, err := words.Norm(ctx, phrase)
+		if err != nil {
+			return nil, err
+		}
+		if len(stems) == 0 {
+			return nil, nil
+		}
+		return &termNode{pos: 1, text: phrase, stems: stems}, nil
+	}
+
+	return normalizeNode(ctx, words, node)
+}
+
+func isPlainQuery(node queryNode) bool {
+	switch n := node.(type) {
+	case *termNode:
+		return !n.phrase && n.field == ""
+	case *orNode:
+		for _, child := range n.children {
+			if !isPlainQuery(child) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// normalizeNode заполняет stems и выкидывает узлы, от которых после
+// нормализации ничего не осталось (например, стоп-слова)
+func normalizeNode(ctx context.Context, words Words, node queryNode) (queryNode, error) {
+	switch n := node.(type) {
+	case *termNode:
+		if n.phrase {
+			tokens, err := words.Tokens(ctx, n.text)
+			if err != nil || len(tokens) == 0 {
+				return nil, err
+			}
+			n.tokens = tokens
+			for _, t := range tokens {
+				if !slices.Contains(n.stems, t.Word) {
+					n.stems = append(n.stems, t.Word)
+				}
+			}
+			return n, nil
+		}
+		stems, err := words.Norm(ctx, n.text)
+		if err != nil {
+			return nil, err
+		}
+		if len(stems) == 0 {
+			return nil, nil
+		}
+		n.stems = stems
+		return n, nil
+	case *notNode:
+		child, err := normalizeNode(ctx, words, n.child)
+		if err != nil || child == nil {
+			return nil, err
+		}
+		n.child = child
+		return n, nil
+	case *andNode:
+		children, err := normalizeChildren(ctx, words, n.children)
+		if err != nil || len(children) == 0 {
+			return nil, err
+		}
+		n.children = children
+		return n, nil
+	case *orNode:
+		children, err := normalizeChildren(ctx, words, n.children)
+		if err != nil || len(children) == 0 {
+			return nil, err
+		}
+		n.children = children
+		return n, nil
+	}
+	return node, nil
+}
+
+func normalizeChildren(ctx context.Context, words Words, nodes []queryNode) ([]queryNode, error) {
+	res := make([]queryNode, 0, len(nodes))
+	for _, node := range nodes {
+		child, err := normalizeNode(ctx, words, node)
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			res = append(res, child)
+		}
+	}
+	return res, nil
+}
+
+// expandFuzzy добавляет к словам запроса похожие слова индекса.
+// Фразы и исключения не расширяются.
+func (ix *invertedIndex) expandFuzzy(node queryNode, fuzziness int) {
+	if fuzziness == 0 {
+		return
+	}
+	switch n := node.(type) {
+	case *termNode:
+		if n.phrase {
+			return
+		}
+		for _, stem := range n.stems {
+			n.fuzzy = append(n.fuzzy, ix.fuzzyTerms(stem, maxEdits(stem, fuzziness))...)
+		}
+	case *andNode:
+		for _, child := range n.children {
+			ix.expandFuzzy(child, fuzziness)
+		}
+	case *orNode:
+		for _, child := range n.children {
+			ix.expandFuzzy(child, fuzziness)
+		}
+	}
+}
+
+// eval возвращает множество комиксов, удовлетворяющих запросу
+func (ix *invertedIndex) eval(node queryNode) docSet {
+	switch n := node.(type) {
+	case *termNode:
+		return ix.evalTerm(n)
+	case *rangeNode:
+		res := make(docSet)
+		for id := range ix.docLen {
+			if id >= n.from && id <= n.to {
+				res[id] = struct{}{}
+			}
+		}
+		return res
+	case *andNode:
+		return ix.evalGroup(n.children, true)
+	case *orNode:
+		return ix.evalGroup(n.children, false)
+	case *notNode:
+		// отрицание вне группы отсекается при разборе запроса
+		return docSet{}
+	}
+	return docSet{}
+}
+
+func (ix *invertedIndex) evalTerm(n *termNode) docSet {
+	res := make(docSet)
+	if !n.phrase {
+		// слово, разбитое нормализатором на несколько, ищем по любому из них
+		for _, stem := range n.stems {
+			for p := range ix.lookup(n.field, stem) {
+				res[p.id] = struct{}{}
+			}
+		}
+		for _, m := range n.fuzzy {
+			for p := range ix.lookup(n.field, m.word) {
+				res[p.id] = struct{}{}
+			}
+		}
+		for _, stems := range n.synonyms {
+			for id := range ix.evalAll(n.field, stems) {
+				res[id] = struct{}{}
+			}
+		}
+		return res
+	}
+
+	// позиции каждого слова фразы по комиксам
+	byToken := make([]map[int][]int, len(n.tokens))
+	offsets := make([]int, len(n.tokens))
+	for i, t := range n.tokens {
+		byToken[i] = make(map[int][]int, ix.df(t.Word))
+		for p := range ix.lookup(n.field, t.Word) {
+			byToken[i][p.id] = p.positions()
+		}
+		offsets[i] = t.Pos
+	}
+
+	lists := make([][]int, len(n.tokens))
+	for id, first := range byToken[0] {
+		lists[0] = first
+		found := true
+		for i := 1; i < len(byToken) && found; i++ {
+			lists[i], found = byToken[i][id]
+		}
+		if found && matchPhrase(lists, offsets, n.slop) {
+			res[id] = struct{}{}
+		}
+	}
+	return res
+}
+
+// evalAll - комиксы, где есть все слова
+func (ix *invertedIndex) evalAll(field string, stems []string) docSet {
+	var res docSet
+	for _, stem := range stems {
+		set := make(docSet)
+		for p := range ix.lookup(field, stem) {
+			if _, ok := res[p.id]; res == nil || ok {
+				set[p.id] = struct{}{}
+			}
+		}
+		res = set
+		if len(res) == 0 {
+			break
+		}
+	}
+	return res
+}
+
+// evalGroup - AND или OR над положительными условиями минус все отрицания группы
+func (ix *invertedIndex) evalGroup(children []queryNode, all bool) docSet {
+	var res docSet
+	var excluded []docSet
+
+	for _, child := range children {
+		if not, ok := child.(*notNode); ok {
+			excluded = append(excluded, ix.eval(not.child))
+			continue
+		}
+		set := ix.eval(child)
+		switch {
+		case res == nil:
+			res = set
+		case all:
+			for id := range res {
+				if _, ok := set[id]; !ok {
+					delete(res, id)
+				}
+			}
+		default:
+			for id := range set {
+				res[id] = struct{}{}
+			}
+		}
+	}
+	if res == nil {
+		return docSet{}
+	}
+
+	for _, set := range excluded {
+		for id := range set {
+			delete(res, id)
+		}
+	}
+	return res
+}
+
+// positiveTerms - слова вне отрицаний, по ним считается релевантность
+func positiveTerms(node queryNode) []queryTerm {
+	var terms []queryTerm
+	// одно и то же слово может прийти и точно, и с опечаткой или синонимом - берём лучшее
+	addTerm := func(t queryTerm) {
+		i := slices.IndexFunc(terms, func(o queryTerm) bool {
+			return o.field == t.field && o.stem == t.stem
+		})
+		if i < 0 {
+			terms = append(terms, t)
+		} else if t.weight() > terms[i].weight() {
+			terms[i] = t
+		}
+	}
+	var walk func(queryNode)
+	walk = func(node queryNode) {
+		switch n := node.(type) {
+		case *termNode:
+			for _, stem := range n.stems {
+				addTerm(queryTerm{field: n.field, stem: stem})
+			}
+			for _, m := range n.fuzzy {
+				addTerm(queryTerm{field: n.field, stem: m.word, dist: m.dist})
+			}
+			for _, stems := range n.synonyms {
+				for _, stem := range stems {
+					addTerm(queryTerm{field: n.field, stem: stem, synonym: true})
+				}
+			}
+		case *andNode:
+			for _, child := range n.children {
+				walk(child)
+			}
+		case *orNode:
+			for _, child := range n.children {
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+	return terms
+}
@@ -0,0 +1,314 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery_Errors(t *testing.T) {
+	testCases := []struct {
+		query string
+		pos   int
+	}{
+		{`foo (bar`, 9},        // не закрыта скобка
+		{`foo)`, 4},            // лишняя скобка
+		{`"foo bar`, 1},        // не закрыта кавычка
+		{`foo AND`, 8},         // нет правого операнда
+		{`OR foo`, 1},          // нет левого операнда
+		{`-foo`, 1},            // исключать не из чего
+		{`foo NOT`, 8},         // отрицание без операнда
+		{`(-foo -bar) baz`, 2}, // группа из одних отрицаний
+		{`id:10..1`, 4},        // перевёрнутый диапазон
+		{`id:abc`, 4},          // не число
+		{`title:(alt:foo)`, 8},
+		{`"foo bar"~x`, 10}, // после ~ нужно число
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := parseQuery(tc.query)
+			require.ErrorIs(t, err, ErrBadArguments)
+
+			var qerr *QueryError
+			require.ErrorAs(t, err, &qerr)
+			assert.Equal(t, tc.pos, qerr.Pos)
+			assert.Contains(t, err.Error(), "position")
+		})
+	}
+}
+
+func TestParseQuery_Precedence(t *testing.T) {
+	// NOT сильнее AND, AND сильнее OR, соседние слова через OR
+	node, err := parseQuery(`a b AND NOT c OR -d`)
+	require.NoError(t, err)
+
+	or, ok := node.(*orNode)
+	require.True(t, ok)
+	require.Len(t, or.children, 3)
+
+	and, ok := or.children[1].(*andNode)
+	require.True(t, ok)
+	require.Len(t, and.children, 2)
+	_, ok = and.children[1].(*notNode)
+	assert.True(t, ok)
+
+	_, ok = or.children[2].(*notNode)
+	assert.True(t, ok)
+}
+
+func TestParseQuery_Fields(t *testing.T) {
+	node, err := parseQuery(`title:(foo "bar baz") id:..200 x-ray`)
+	require.NoError(t, err)
+
+	or, ok := node.(*orNode)
+	require.True(t, ok)
+	require.Len(t, or.children, 3)
+
+	group, ok := or.children[0].(*orNode)
+	require.True(t, ok)
+	for _, child := range group.children {
+		assert.Equal(t, FieldTitle, child.(*termNode).field)
+	}
+	assert.True(t, group.children[1].(*termNode).phrase)
+
+	rng, ok := or.children[1].(*rangeNode)
+	require.True(t, ok)
+	assert.Equal(t, 0, rng.from)
+	assert.Equal(t, 200, rng.to)
+
+	// минус внутри слова - не исключение
+	term, ok := or.children[2].(*termNode)
+	require.True(t, ok)
+	assert.Equal(t, "x-ray", term.text)
+}
+
+func TestParseQuery_ColonLiterals(t *testing.T) {
+	// двоеточие без известного поля или без значения - часть текста, а не ошибка
+	testCases := []struct {
+		query string
+		words []string
+	}{
+		{`Re: something`, []string{"Re:", "something"}},
+		{`http://xkcd.com`, []string{"http://xkcd.com"}},
+		{`color:red`, []string{"color:red"}},
+		{`title: foo`, []string{"title:", "foo"}},
+		{`title:`, []string{"title:"}},
+		{`:foo`, []string{":foo"}},
+		{`at 12:30`, []string{"at", "12:30"}},
+		{`note: title:bar`, []string{"note:", "bar"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			node, err := parseQuery(tc.query)
+			require.NoError(t, err)
+
+			var terms []*termNode
+			switch n := node.(type) {
+			case *termNode:
+				terms = append(terms, n)
+			case *orNode:
+				for _, child := range n.children {
+					terms = append(terms, child.(*termNode))
+				}
+			default:
+				t.Fatalf("unexpected node %T", node)
+			}
+			var words []string
+			for _, term := range terms {
+				words = append(words, term.text)
+			}
+			assert.Equal(t, tc.words, words)
+		})
+	}
+
+	// известное поле перед URL по-прежнему поле
+	node, err := parseQuery(`title:http://xkcd.com`)
+	require.NoError(t, err)
+	term, ok := node.(*termNode)
+	require.True(t, ok)
+	assert.Equal(t, FieldTitle, term.field)
+	assert.Equal(t, "http://xkcd.com", term.text)
+}
+
+func newQueryTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		return []Comic{
+			{ID: 1, URL: "u1", Words: []string{"linux", "kernel"}},
+			{ID: 2, URL: "u2", Words: []string{"linux", "window"}},
+			{ID: 3, URL: "u3", Words: []string{"window", "door"}},
+			{ID: 150, URL: "u150", Words: []string{"linux", "bobbi", "tabl"}},
+			{ID: 300, URL: "u300", Words: []string{"bobbi", "door", "tabl"}},
+		}, nil
+	}}
+	// нормализатор: нижний регистр и стоп-слово "the"
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		var res []string
+		for _, w := range strings.Fields(strings.ToLower(phrase)) {
+			if w != "the" {
+				res = append(res, w)
+			}
+		}
+		return res, nil
+	}}
+
+	svc := newTestService(t, db, words)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	return svc
+}
+
+func TestServiceIndexSearch_Query(t *testing.T) {
+	svc := newQueryTestService(t)
+
+	testCases := []struct {
+		query string
+		ids   []int
+	}{
+		{`linux`, []int{1, 2, 150}},
+		{`linux AND window`, []int{2}},
+		{`linux NOT window`, []int{1, 150}},
+		{`linux -window -kernel`, []int{150}},
+		{`(kernel OR door) AND NOT window`, []int{1, 300}},
+		{`"bobbi tabl"`, []int{150, 300}},
+		{`"bobbi tabl" -linux`, []int{300}},
+		{`linux id:100..200`, []int{1, 2, 150}},
+		{`id:2..3`, []int{2, 3}},
+		{`title:door`, []int{3, 300}},
+		{`the AND door`, []int{3, 300}}, // стоп-слово не ограничивает выдачу
+		{`the`, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: tc.query, Limit: 10})
+			require.NoError(t, err)
+
+			var ids []int
+			for _, c := range res.Comics {
+				ids = append(ids, c.ID)
+			}
+			assert.ElementsMatch(t, tc.ids, ids)
+		})
+	}
+}
+
+func TestServiceSearch_QueryError(t *testing.T) {
+	svc := newQueryTestService(t)
+
+	res, err := svc.Search(context.Background(), SearchQuery{Phrase: `linux AND (`, Limit: 10})
+	require.ErrorIs(t, err, ErrBadArguments)
+	assert.Nil(t, res.Comics)
+
+	res, err = svc.Search(context.Background(), SearchQuery{Phrase: `linux -window`, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, res.Comics, 2)
+	assert.Equal(t, 1, res.Comics[0].ID)
+	assert.Equal(t, 150, res.Comics[1].ID)
+}
+
+func TestParseQuery_Slop(t *testing.T) {
+	node, err := parseQuery(`"foo bar"~3 baz`)
+	require.NoError(t, err)
+
+	or, ok := node.(*orNode)
+	require.True(t, ok)
+	phrase := or.children[0].(*termNode)
+	assert.True(t, phrase.phrase)
+	assert.Equal(t, "foo bar", phrase.text)
+	assert.Equal(t, 3, phrase.slop)
+}
+
+func TestMatchPhrase(t *testing.T) {
+	offsets := []int{0, 1}
+
+	assert.True(t, matchPhrase([][]int{{4}, {5}}, offsets, 0))
+	assert.False(t, matchPhrase([][]int{{5}, {4}}, offsets, 0)) // обратный порядок
+	assert.False(t, matchPhrase([][]int{{1}, {4}}, offsets, 1))
+	assert.True(t, matchPhrase([][]int{{1}, {4}}, offsets, 2))
+	// позиции не сохранены - достаточно наличия слов
+	assert.True(t, matchPhrase([][]int{nil, {4}}, offsets, 0))
+}
+
+func TestMinSpan(t *testing.T) {
+	assert.Equal(t, 1, minSpan([][]int{{0, 10}, {11}}))
+	assert.Equal(t, 2, minSpan([][]int{{1, 20}, {3, 30}, {2}}))
+	assert.Equal(t, -1, minSpan([][]int{{1}, nil}))
+}
+
+func newPhraseTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		return []Comic{
+			// слова через одно
+			{ID: 1, URL: "u1", Words: []string{"bobbi", "drop", "tabl"},
+				Positions: map[string][]int{"bobbi": {0}, "drop": {1}, "tabl": {2}}},
+			// слова рядом
+			{ID: 2, URL: "u2", Words: []string{"bobbi", "tabl", "school"},
+				Positions: map[string][]int{"bobbi": {0}, "tabl": {1}, "school": {2}}},
+			// слова далеко друг от друга
+			{ID: 3, URL: "u3", Words: []string{"bobbi", "drop", "school", "tabl"},
+				Positions: map[string][]int{"bobbi": {0}, "drop": {1, 3, 4, 5}, "school": {2}, "tabl": {6}}},
+			// обратный порядок
+			{ID: 4, URL: "u4", Words: []string{"tabl", "bobbi"},
+				Positions: map[string][]int{"tabl": {0}, "bobbi": {1}}},
+		}, nil
+	}}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return strings.Fields(strings.ToLower(phrase)), nil
+	}}
+
+	svc := newTestService(t, db, words)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	return svc
+}
+
+func TestServiceIndexSearch_Phrase(t *testing.T) {
+	svc := newPhraseTestService(t)
+
+	testCases := []struct {
+		query string
+		ids   []int
+	}{
+		{`"bobbi tabl"`, []int{2}},
+		{`"bobbi tabl"~1`, []int{1, 2}},
+		{`"bobbi tabl"~5`, []int{1, 2, 3, 4}}, // с большим допуском порядок не важен
+		{`"tabl bobbi"`, []int{4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: tc.query, Limit: 10})
+			require.NoError(t, err)
+
+			var ids []int
+			for _, c := range res.Comics {
+				ids = append(ids, c.ID)
+			}
+			assert.ElementsMatch(t, tc.ids, ids)
+		})
+	}
+}
+
+func TestServiceIndexSearch_Proximity(t *testing.T) {
+	svc := newPhraseTestService(t)
+
+	// при прочих равных выше комиксы, где слова стоят ближе
+	// (комиксы 1 и 2 одной длины и отличаются только расстоянием между словами)
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: `bobbi AND tabl`, Limit: 10})
+	require.NoError(t, err)
+
+	var ids []int
+	for _, c := range res.Comics {
+		if c.ID != 4 {
+			ids = append(ids, c.ID)
+		}
+	}
+	assert.Equal(t, []int{2, 1, 3}, ids)
+}
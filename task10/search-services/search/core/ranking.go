@@ -0,0 +1,252 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+type RankingMode string
+
+const (
+	RankingBM25    RankingMode = "bm25"
+	RankingMatches RankingMode = "matches"
+)
+
+type Ranking struct {
+	Mode   RankingMode
+	K1     float64
+	B      float64
+	Fields FieldBoosts
+}
+
+// FieldBoosts - веса совпадений в названии, alt и расшифровке для BM25.
+// Все веса нулевые - поля не различаются, слово считается по всему комиксу.
+type FieldBoosts struct {
+	Title      float64
+	Alt        float64
+	Transcript float64
+}
+
+func (b FieldBoosts) enabled() bool {
+	return b != FieldBoosts{}
+}
+
+func (b FieldBoosts) weight(f indexField) float64 {
+	return [numFields]float64{fieldTitle: b.Title, fieldAlt: b.Alt, fieldTranscript: b.Transcript}[f]
+}
+
+func (r Ranking) validate() error {
+	switch r.Mode {
+	case RankingBM25:
+		if r.K1 < 0 || r.B < 0 || r.B > 1 {
+			return fmt.Errorf("wrong bm25 parameters: k1=%v b=%v", r.K1, r.B)
+		}
+		if f := r.Fields; f.Title < 0 || f.Alt < 0 || f.Transcript < 0 {
+			return fmt.Errorf("wrong field boosts: title=%v alt=%v transcript=%v", f.Title, f.Alt, f.Transcript)
+		}
+	case RankingMatches:
+	default:
+		return fmt.Errorf("unknown ranking mode: %q", r.Mode)
+	}
+	return nil
+}
+
+// idf по формуле Okapi BM25, всегда неотрицательный
+func idf(docs, df int) float64 {
+	n, d := float64(docs), float64(df)
+	return math.Log(1 + (n-d+0.5)/(d+0.5))
+}
+
+// bm25 - вклад одного слова запроса в оценку документа
+func (r Ranking) bm25(tf, docLen int, avgDocLen, idf float64) float64 {
+	if tf == 0 || avgDocLen == 0 {
+		return 0
+	}
+	f := float64(tf)
+	norm := 1 - r.B + r.B*float64(docLen)/avgDocLen
+	return idf * f * (r.K1 + 1) / (f + r.K1*norm)
+}
+
+// во сколько раз максимум поднимается оценка, если слова запроса стоят рядом
+const proximityBoost = 0.5
+
+type hit struct {
+	comic     Comic
+	matches   int
+	ratio     float64
+	score     float64
+	positions [][]int // позиции совпавших слов запроса
+	proximity float64
+	explain   *Explanation // заполняется, только если разбор запрошен
+}
+
+// TieBreak - признак, по которому compare упорядочил два комикса
+type TieBreak string
+
+const (
+	TieBreakScore   TieBreak = "score"
+	TieBreakMatches TieBreak = "matches"
+	TieBreakRatio   TieBreak = "ratio"
+	TieBreakID      TieBreak = "id"
+)
+
+func (r Ranking) compare(a, b hit) int {
+	res, _ := r.compareBy(a, b)
+	return res
+}
+
+func (r Ranking) compareBy(a, b hit) (int, TieBreak) {
+	if r.Mode == RankingBM25 && a.score != b.score {
+		return cmp.Compare(b.score, a.score), TieBreakScore // по убыванию
+	}
+	if a.matches != b.matches {
+		return cmp.Compare(b.matches, a.matches), TieBreakMatches // по убыванию
+	}
+	if a.ratio != b.ratio {
+		return cmp.Compare(b.ratio, a.ratio), TieBreakRatio // по убыванию
+	}
+	// при равенстве остального выше комикс с меньшим id
+	return cmp.Compare(a.comic.ID, b.comic.ID), TieBreakID
+}
+
+// rank оценивает найденные комиксы по словам запроса и сортирует их.
+// С explain к каждому комиксу прикладывается разбор оценки.
+func (r Ranking) rank(ix *invertedIndex, comics map[int]Comic, matched docSet, terms []queryTerm, explain bool) []hit {
+	docs, avgDocLen := ix.docs(), ix.avgDocLen()
+	byId := make(map[int]*hit, len(matched))
+
+	getHit := func(id int) *hit {
+		h, ok := byId[id]
+		if !ok {
+			c, exists := comics[id]
+			if !exists || ix.docLen[id] == 0 {
+				return nil
+			}
+			h = &hit{comic: c, proximity: 1}
+			if explain {
+				h.explain = &Explanation{}
+			}
+			byId[id] = h
+		}
+		return h
+	}
+
+	for _, t := range terms {
+		termIDF := idf(docs, ix.df(t.stem))
+		var boosted map[int]float64
+		if t.field == "" && r.Fields.enabled() {
+			boosted = r.fieldScores(ix, t.stem, matched, avgDocLen, termIDF)
+		}
+
+		for p := range ix.lookup(t.field, t.stem) {
+			if _, ok := matched[p.id]; !ok {
+				continue
+			}
+			h := getHit(p.id)
+			if h == nil {
+				continue
+			}
+			// совпадение с опечаткой или по синониму не считается полным
+			if t.dist == 0 && !t.synonym {
+				h.matches++
+			}
+			score := r.bm25(p.tf(), ix.docLen[p.id], avgDocLen, termIDF)
+			if r.Fields.enabled() && ix.hasFields(p.id) {
+				if f, ok := fieldByName(t.field); ok {
+					score *= r.Fields.weight(f)
+				} else {
+					// слово только в полях с нулевым весом - вклада нет
+					score = boosted[p.id]
+				}
+			}
+			score *= t.weight()
+			h.score += score
+			if explain {
+				h.explain.Matched = append(h.explain.Matched, TermScore{
+					Term:   t.stem,
+					Field:  t.field,
+					Dist:   t.dist,
+					Weight: t.weight(),
+					TF:     p.tf(),
+					IDF:    termIDF,
+					Score:  score,
+				})
+			}
+			h.positions = append(h.positions, p.positions())
+		}
+	}
+	// комиксы, попавшие в выдачу без слов запроса, например по id:
+	for id := range matched {
+		getHit(id)
+	}
+
+	hits := make([]hit, 0, len(byId))
+	for _, h := range byId {
+		h.ratio = float64(h.matches) / float64(len(h.comic.Words))
+		// чем плотнее стоят найденные слова, тем выше оценка
+		if span := minSpan(h.positions); len(h.positions) > 1 && span > 0 {
+			h.proximity = 1 + proximityBoost*float64(len(h.positions)-1)/float64(span)
+			h.score *= h.proximity
+		}
+		// оценка должна упорядочивать так же, как compare: ratio не больше 1,
+		// а при matches > 0 и не меньше доли одного слова
+		if r.Mode == RankingMatches {
+			h.score = float64(h.matches) + h.ratio
+		}
+		hits = append(hits, *h)
+	}
+
+	slices.SortFunc(hits, r.compare)
+	if explain {
+		r.explain(hits, terms)
+	}
+	return hits
+}
+
+// fieldScores - BM25 слова отдельно по каждому полю комикса, умноженный
+// на вес поля и сложенный по полям. Только для комиксов со словами по полям.
+func (r Ranking) fieldScores(ix *invertedIndex, term string, matched docSet, avgDocLen, idf float64) map[int]float64 {
+	id, ok := ix.dict.id(term)
+	if !ok {
+		return nil
+	}
+	res := make(map[int]float64)
+	for f := range numFields {
+		w := r.Fields.weight(f)
+		if w == 0 {
+			continue
+		}
+		for p := range ix.fields[f][id].all() {
+			if _, ok := matched[p.id]; ok {
+				res[p.id] += w * r.bm25(p.tf(), ix.docLen[p.id], avgDocLen, idf)
+			}
+		}
+	}
+	return res
+}
+
+// explain дописывает в разбор итоговые признаки и то, чем решилось
+// место каждого комикса относительно следующего
+func (r Ranking) explain(hits []hit, terms []queryTerm) {
+	names := make([]string, 0, len(terms))
+	for _, t := range terms {
+		name := t.stem
+		if t.field != "" {
+			name = t.field + ":" + name
+		}
+		names = append(names, name)
+	}
+	for i := range hits {
+		h := &hits[i]
+		h.explain.Terms = names
+		h.explain.Proximity = h.proximity
+		h.explain.Matches = h.matches
+		h.explain.Ratio = h.ratio
+		h.explain.Score = h.score
+		if i+1 < len(hits) {
+			_, h.explain.TieBreak = r.compareBy(*h, hits[i+1])
+		}
+	}
+}
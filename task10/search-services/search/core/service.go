@@ -1,225 +1,347 @@
 package core
 
 import (
-	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
+	"runtime"
 	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Service struct {
-	log   *slog.Logger
-	db    DB
-	words Words
-
-	mu     sync.RWMutex
-	index  map[string][]int
-	comics map[int]Comic
+	log       *slog.Logger
+	db        DB
+	words     Words
+	ranking   Ranking
+	shard     Shard
+	snapshots SnapshotStore // может быть nil, тогда снимки не сохраняются
+	cache     *resultCache  // сбрасывается под mu вместе с заменой или правкой индекса
+
+	synonymStore SynonymStore // может быть nil, тогда словарь пустой
+	synonyms     atomic.Pointer[Synonyms]
+
+	// индекс меняют по одному: полная перестройка или применение изменений
+	updateMu sync.Mutex
+
+	mu       sync.RWMutex
+	index    *invertedIndex
+	prefixes *prefixIndex
+	comics   map[int]Comic
+
+	// состояние индекса для Stats, меняется под mu
+	generation  uint64
+	lastRebuild time.Time
+	rebuildTook time.Duration
+	lastErr     error
+	lastErrAt   time.Time
 }
 
-func NewService(log *slog.Logger, db DB, words Words) *Service {
-	return &Service{
-		log:    log,
-		db:     db,
-		words:  words,
-		index:  make(map[string][]int),
-		comics: make(map[int]Comic),
+func NewService(log *slog.Logger, db DB, words Words, ranking Ranking, shard Shard, cache QueryCache, snapshots SnapshotStore, synonyms SynonymStore) (*Service, error) {
+	if err := ranking.validate(); err != nil {
+		return nil, fmt.Errorf("wrong ranking specified: %w", err)
+	}
+	if err := shard.validate(); err != nil {
+		return nil, fmt.Errorf("wrong shard specified: %w", err)
 	}
+	if err := cache.validate(); err != nil {
+		return nil, fmt.Errorf("wrong cache specified: %w", err)
+	}
+	return &Service{
+		log:          log,
+		db:           db,
+		words:        words,
+		ranking:      ranking,
+		shard:        shard,
+		snapshots:    snapshots,
+		synonymStore: synonyms,
+		cache:        newResultCache(cache),
+		index:        newInvertedIndex(),
+		prefixes:     &prefixIndex{},
+		comics:       make(map[int]Comic),
+	}, nil
 }
 
-func (s *Service) Search(ctx context.Context, phrase string, limit int) ([]Comic, error) {
+func (s *Service) Search(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	return s.cached(ctx, modeSearch, q, s.search)
+}
+
+func (s *Service) IndexSearch(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	return s.cached(ctx, modeIndexSearch, q, s.indexSearch)
+}
 
-	if phrase == "" || limit <= 0 {
-		return nil, ErrBadArguments
+// cached разбирает и нормализует запрос, отдаёт выдачу из кэша, а при промахе
+// считает её через find и запоминает. Ключ кэша - нормализованный запрос,
+// поэтому "Bobby Tables" и "tables bobby" попадают в одну запись.
+func (s *Service) cached(ctx context.Context, mode searchMode, q SearchQuery, find func(context.Context, SearchQuery, queryNode) (SearchResult, error)) (SearchResult, error) {
+	if err := q.validate(); err != nil {
+		return SearchResult{}, err
 	}
 
-	qwords, err := s.words.Norm(ctx, phrase)
+	query, err := prepareQuery(ctx, s.words, q.Phrase)
 	if err != nil {
-		return nil, err
+		return SearchResult{}, err
 	}
-	if len(qwords) == 0 {
-		return nil, nil
+	if query == nil {
+		return SearchResult{}, nil
 	}
 
-	qset := make(map[string]bool, len(qwords))
-	for _, w := range qwords {
-		qset[w] = true
+	key := newCacheKey(mode, q, query)
+	res, gen, ok := s.cache.get(key)
+	if ok {
+		return res, nil
 	}
-
-	comics, err := s.db.Search(ctx)
+	res, err = find(ctx, q, query)
 	if err != nil {
-		return nil, err
+		return SearchResult{}, err
 	}
+	s.cache.put(key, gen, res)
+	return res, nil
+}
 
-	type scored struct {
-		comic   Comic
-		matches int
-		ratio   float64
+func (s *Service) search(ctx context.Context, q SearchQuery, query queryNode) (SearchResult, error) {
+	// без готового индекса строим временный по всей базе
+	index := newInvertedIndex()
+	byId := make(map[int]Comic)
+	err := s.db.Scan(ctx, func(c Comic) error {
+		if s.shard.owns(c.ID) {
+			byId[c.ID] = index.add(c)
+		}
+		return nil
+	})
+	if err != nil {
+		return SearchResult{}, err
 	}
 
-	scoredList := make([]scored, 0, len(comics))
+	index.expandFuzzy(query, q.Fuzziness)
 
-	for _, c := range comics {
-		if len(c.Words) == 0 {
-			continue
-		}
-		matches := 0
-		for _, w := range c.Words {
-			if qset[w] {
-				matches++
-			}
-		}
-		if matches == 0 {
-			continue
-		}
+	// у полнотекстового поиска по базе всегда старое ранжирование по совпадениям
+	ranking := Ranking{Mode: RankingMatches}
+	terms := positiveTerms(query)
+	hits := ranking.rank(index, byId, index.eval(query), terms, q.Explain)
 
-		ratio := float64(matches) / float64(len(c.Words))
-		scoredList = append(scoredList, scored{
-			// у каждого комикса будет количество совпадений и кэф от общего текста
-			comic:   c,
-			matches: matches,
-			ratio:   ratio,
-		})
+	res := pageOf(hits, q.Offset, q.Limit)
+	if err := s.loadCards(ctx, res.Comics); err != nil {
+		return SearchResult{}, err
 	}
+	return withSnippets(res, terms), nil
+}
 
-	slices.SortFunc(scoredList, func(a, b scored) int {
-		if a.matches != b.matches {
-			return cmp.Compare(b.matches, a.matches) // по убыванию
-		}
-		if a.ratio != b.ratio {
-			return cmp.Compare(b.ratio, a.ratio) // по убыванию
+// loadCards дочитывает из базы тексты и написания слов комиксов страницы:
+// в памяти их не держим, а для карточек и сниппетов они нужны только здесь.
+// Комикс, которого в базе уже нет, остаётся без текста.
+func (s *Service) loadCards(ctx context.Context, comics []Comic) error {
+	if len(comics) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(comics))
+	for _, c := range comics {
+		ids = append(ids, c.ID)
+	}
+	cards, err := s.db.Get(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byId := make(map[int]Comic, len(cards))
+	for _, c := range cards {
+		byId[c.ID] = c
+	}
+	for i, c := range comics {
+		if card, ok := byId[c.ID]; ok {
+			comics[i].Meta = card.Meta
+			comics[i].Surfaces = card.Surfaces
 		}
-		// Если matches и ratio одинаковы, то с меньшим id будет выше
-		return cmp.Compare(a.comic.ID, b.comic.ID)
-
-	})
-
-	if limit > len(scoredList) {
-		limit = len(scoredList)
 	}
+	return nil
+}
 
-	res := make([]Comic, 0, limit)
-	for i := 0; i < limit; i++ {
-		res = append(res, scoredList[i].comic)
+// pageOf - limit комиксов начиная с offset и общее число найденных
+func pageOf(hits []hit, offset, limit int) SearchResult {
+	res := SearchResult{Total: len(hits)}
+	if offset >= len(hits) {
+		return res
 	}
-	return res, nil
+	end := min(offset+limit, len(hits))
+
+	res.Comics = make([]Comic, 0, end-offset)
+	for _, h := range hits[offset:end] {
+		c := h.comic
+		c.Score = h.score
+		c.Explain = h.explain
+		res.Comics = append(res.Comics, c)
+	}
+	return res
 }
 
 func (s *Service) RebuildIndex(ctx context.Context) error {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
 
-	comics, err := s.db.Search(ctx)
-	if err != nil {
+	if err := s.rebuildIndex(ctx); err != nil {
+		s.indexFailed(err)
 		return err
 	}
+	return nil
+}
 
-	newIndex := make(map[string][]int)
-	newComics := make(map[int]Comic, len(comics))
-
-	for _, comic := range comics {
-		newComics[comic.ID] = comic
-		for _, w := range comic.Words {
-			newIndex[w] = append(newIndex[w], comic.ID)
+func (s *Service) rebuildIndex(ctx context.Context) error {
+	start := time.Now()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	// комиксы добавляются по мере чтения, целиком база в памяти не собирается
+	newIndex := newInvertedIndex()
+	newComics := make(map[int]Comic)
+	err := s.db.Scan(ctx, func(c Comic) error {
+		if s.shard.owns(c.ID) {
+			newComics[c.ID] = newIndex.add(c)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	newIndex.compact()
+	newPrefixes := newPrefixIndex(newIndex)
+	finished := time.Now()
+	took := finished.Sub(start)
+
 	// пока выполняем, никто не может читать
 	s.mu.Lock()
 	s.index = newIndex
+	s.prefixes = newPrefixes
 	s.comics = newComics
+	s.lastRebuild = finished
+	s.rebuildTook = took
+	s.lastErr = nil
+	s.lastErrAt = time.Time{}
+	s.indexChanged()
 	s.mu.Unlock()
 
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
 	s.log.Info("search index rebuilt",
 		"comics", len(newComics),
-		"words", len(newIndex),
+		"words", newIndex.words(),
+		"duration", took,
+		"allocated_bytes", after.TotalAlloc-before.TotalAlloc,
+		"heap_bytes", after.HeapAlloc,
 	)
 
+	s.saveSnapshot(ctx, newIndex, newComics)
+
 	return nil
 }
 
-func (s *Service) IndexSearch(ctx context.Context, phrase string, limit int) ([]Comic, error) {
-
-	if phrase == "" || limit <= 0 {
-		return nil, ErrBadArguments
-	}
+// updateBatch - сколько комиксов UpdateIndex читает из базы за раз, как Scan
+const updateBatch = 500
 
-	qwords, err := s.words.Norm(ctx, phrase)
-	if err != nil {
-		return nil, err
-	}
-	if len(qwords) == 0 {
-		return nil, nil
+// UpdateIndex перечитывает из базы только изменившиеся комиксы и правит ими
+// текущий индекс. После очистки базы индекс перестраивается целиком.
+func (s *Service) UpdateIndex(ctx context.Context, changes IndexChanges) error {
+	if changes.Dropped {
+		return s.RebuildIndex(ctx)
 	}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
 
-	if len(s.index) == 0 || len(s.comics) == 0 {
-		return nil, nil
+	ids := slices.DeleteFunc(slices.Concat(changes.Added, changes.Changed), func(id int) bool {
+		return !s.shard.owns(id)
+	})
+	// комиксы читаются пачками: первое обновление пустого индекса присылает
+	// все id базы, и одним запросом она целиком оказалась бы в памяти
+	batches := slices.Collect(slices.Chunk(ids, updateBatch))
+	if len(batches) == 0 {
+		batches = [][]int{nil}
 	}
+	var total int
+	for i, batch := range batches {
+		var comics []Comic
+		if len(batch) > 0 {
+			var err error
+			if comics, err = s.db.Get(ctx, batch); err != nil {
+				s.indexFailed(err)
+				return err
+			}
+		}
 
-	type scored struct {
-		comic   Comic
-		matches int
-		ratio   float64
+		s.mu.Lock()
+		if i == 0 {
+			for _, id := range changes.Removed {
+				s.removeComic(id)
+			}
+		}
+		// добавленный комикс тоже мог уже попасть в индекс при полной перестройке
+		for _, id := range batch {
+			s.removeComic(id)
+		}
+		for _, c := range comics {
+			s.addComic(c)
+		}
+		// база поменялась, даже если ни один комикс не относится к этому шарду:
+		// Search ищет по ней напрямую, поэтому кэш сбрасывается всегда
+		s.indexChanged()
+		total = len(s.comics)
+		s.mu.Unlock()
 	}
 
-	byId := make(map[int]*scored)
-
-	for _, qword := range qwords {
-		ids := s.index[qword]
-
-		for _, id := range ids {
-			sc, ok := byId[id]
+	s.log.Info("search index updated",
+		"added", len(changes.Added),
+		"changed", len(changes.Changed),
+		"removed", len(changes.Removed),
+		"comics", total,
+	)
+	return nil
+}
 
-			if !ok {
-				c, exists := s.comics[id]
-				if !exists {
-					continue
-				}
-				sc = &scored{comic: c}
-				byId[id] = sc
-			}
-			sc.matches++
+// removeComic и addComic вызываются под s.mu
+func (s *Service) removeComic(id int) {
+	c, ok := s.comics[id]
+	if !ok {
+		return
+	}
+	s.index.remove(c)
+	for _, w := range c.Words {
+		if s.index.df(w) == 0 {
+			s.prefixes.remove(w)
 		}
 	}
+	delete(s.comics, id)
+}
 
-	if len(byId) == 0 {
-		return nil, nil
+func (s *Service) addComic(c Comic) {
+	c = s.index.add(c)
+	for _, w := range c.Words {
+		s.prefixes.insert(w)
 	}
+	s.comics[c.ID] = c
+}
 
-	scoredList := make([]scored, 0, len(byId))
-	for _, sc := range byId {
-		wordCount := len(sc.comic.Words)
-		if wordCount == 0 {
-			continue
-		}
-
-		sc.ratio = float64(sc.matches) / float64(wordCount)
-		scoredList = append(scoredList, *sc)
+func (s *Service) indexSearch(ctx context.Context, q SearchQuery, query queryNode) (SearchResult, error) {
+	if err := expandSynonyms(ctx, s.words, s.synonyms.Load(), query); err != nil {
+		return SearchResult{}, err
 	}
 
-	if len(scoredList) == 0 {
-		return nil, nil
+	s.mu.RLock()
+	if s.index.words() == 0 || len(s.comics) == 0 {
+		s.mu.RUnlock()
+		return SearchResult{}, nil
 	}
 
-	slices.SortFunc(scoredList, func(a, b scored) int {
-		if a.matches != b.matches {
-			return cmp.Compare(b.matches, a.matches) // по убыванию
-		}
-		if a.ratio != b.ratio {
-			return cmp.Compare(b.ratio, a.ratio) // по убыванию
-		}
-		// Если matches и ratio одинаковы, то с меньшим id будет выше
-		return cmp.Compare(a.comic.ID, b.comic.ID)
-	})
+	s.index.expandFuzzy(query, q.Fuzziness)
+	terms := positiveTerms(query)
+	hits := s.ranking.rank(s.index, s.comics, s.index.eval(query), terms, q.Explain)
 
-	if limit > len(scoredList) {
-		limit = len(scoredList)
-	}
+	res := pageOf(hits, q.Offset, q.Limit)
+	res.Generation = s.generation
+	s.mu.RUnlock()
 
-	res := make([]Comic, 0, limit)
-	for i := 0; i < limit; i++ {
-		res = append(res, scoredList[i].comic)
+	// база читается без блокировки, чтобы не задерживать правки индекса
+	if err := s.loadCards(ctx, res.Comics); err != nil {
+		return SearchResult{}, err
 	}
-	return res, nil
+	return withSnippets(res, terms), nil
 }
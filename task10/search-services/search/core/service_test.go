@@ -5,6 +5,8 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,11 +15,38 @@ import (
 
 // Мокаем DB и Words
 type mockDB struct {
-	searchFn func(ctx context.Context) ([]Comic, error)
+	scanFn func(ctx context.Context) ([]Comic, error)
+	getFn  func(ctx context.Context, ids []int) ([]Comic, error)
 }
 
-func (m *mockDB) Search(ctx context.Context) ([]Comic, error) {
-	return m.searchFn(ctx)
+func (m *mockDB) Scan(ctx context.Context, fn func(Comic) error) error {
+	comics, err := m.scanFn(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range comics {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get без getFn отдаёт комиксы из scanFn, например карточки страницы выдачи
+func (m *mockDB) Get(ctx context.Context, ids []int) ([]Comic, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, ids)
+	}
+	if m.scanFn == nil {
+		return nil, nil
+	}
+	comics, err := m.scanFn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(slices.Clone(comics), func(c Comic) bool {
+		return !slices.Contains(ids, c.ID)
+	}), nil
 }
 
 type mockWords struct {
@@ -28,19 +57,36 @@ func (m *mockWords) Norm(ctx context.Context, phrase string) ([]string, error) {
 	return m.normFn(ctx, phrase)
 }
 
+// Tokens - слова из normFn с позициями по порядку
+func (m *mockWords) Tokens(ctx context.Context, phrase string) ([]Token, error) {
+	words, err := m.normFn(ctx, phrase)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]Token, 0, len(words))
+	for i, w := range words {
+		tokens = append(tokens, Token{Word: w, Pos: i})
+	}
+	return tokens, nil
+}
+
 func newTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
 
+var testRanking = Ranking{Mode: RankingBM25, K1: 1.2, B: 0.75}
+
 func newTestService(t *testing.T, db DB, words Words) *Service {
 	t.Helper()
-	return NewService(newTestLogger(), db, words)
+	svc, err := NewService(newTestLogger(), db, words, testRanking, Shard{}, QueryCache{}, nil, nil)
+	require.NoError(t, err)
+	return svc
 }
 
 func TestServiceSearch_BadArguments(t *testing.T) {
 	// готовим сервис с пустыми моками
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
 		&mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) { return nil, nil }},
 	)
 
@@ -58,12 +104,12 @@ func TestServiceSearch_BadArguments(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := svc.Search(ctx, tc.phrase, tc.limit)
+			got, err := svc.Search(ctx, SearchQuery{Phrase: tc.phrase, Limit: tc.limit})
 
 			// проверяем, что сервис возвращает ErrBadArguments (покрытие 34 строки сервиса)
 			require.ErrorIs(t, err, ErrBadArguments)
 			// при ошибке ожидаем nil-результат
-			assert.Nil(t, got)
+			assert.Nil(t, got.Comics)
 		})
 	}
 }
@@ -74,36 +120,36 @@ func TestServiceSearch_WordsError(t *testing.T) {
 	expErr := errors.New("norm failed")
 
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
 		// изменяем поведение мока Words, возвращаем ошибку
 		&mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
 			return nil, expErr
 		}},
 	)
 
-	got, err := svc.Search(context.Background(), "foo", 10)
+	got, err := svc.Search(context.Background(), SearchQuery{Phrase: "foo", Limit: 10})
 	// Метод Search должен просто пробросить ошибку наружу
 	require.ErrorIs(t, err, expErr)
 	// вернуть Nil
-	assert.Nil(t, got)
+	assert.Nil(t, got.Comics)
 }
 
 func TestServiceSearch_NoWordsAfterNorm(t *testing.T) {
 	// Покрытие 42 строки сервиса
 	// Никаких ошибок, просто пустой слайс
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
 		&mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
 			// имитируем, что после нормализации слов не осталось
 			return []string{}, nil
 		}},
 	)
 
-	res, err := svc.Search(context.Background(), "foo", 10)
+	res, err := svc.Search(context.Background(), SearchQuery{Phrase: "foo", Limit: 10})
 	// Ошибок не должно быть
 	require.NoError(t, err)
 	// при отсутствии слов ожидаем nil-результат
-	assert.Nil(t, res)
+	assert.Nil(t, res.Comics)
 }
 
 func TestServiceSearch_DBError(t *testing.T) {
@@ -111,7 +157,7 @@ func TestServiceSearch_DBError(t *testing.T) {
 	expErr := errors.New("db error")
 
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) {
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
 			// Имитируем ошибку в бд
 			return nil, expErr
 		}},
@@ -121,9 +167,9 @@ func TestServiceSearch_DBError(t *testing.T) {
 		}},
 	)
 
-	res, err := svc.Search(context.Background(), "foo", 10)
+	res, err := svc.Search(context.Background(), SearchQuery{Phrase: "foo", Limit: 10})
 	require.ErrorIs(t, err, expErr)
-	assert.Nil(t, res)
+	assert.Nil(t, res.Comics)
 }
 
 func TestServiceSearch_ScoringAndLimit(t *testing.T) {
@@ -138,7 +184,7 @@ func TestServiceSearch_ScoringAndLimit(t *testing.T) {
 	}}
 
 	// БД возвращает нам несколько комиксов с разным числом совпадений и ratio
-	db := &mockDB{searchFn: func(ctx context.Context) ([]Comic, error) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
 		return []Comic{
 			{ID: 1, URL: "u1", Words: []string{"foo", "baz"}},      // 1 совпадение, ratio 0.5
 			{ID: 2, URL: "u2", Words: []string{"foo"}},             // 1 совпадение, ratio 1.0
@@ -153,27 +199,27 @@ func TestServiceSearch_ScoringAndLimit(t *testing.T) {
 	svc := newTestService(t, db, words)
 
 	// limit = 3 — ждём топ-3 результата
-	res, err := svc.Search(context.Background(), "foo bar", 3)
+	res, err := svc.Search(context.Background(), SearchQuery{Phrase: "foo bar", Limit: 3})
 	require.NoError(t, err)
-	require.Len(t, res, 3)
+	require.Len(t, res.Comics, 3)
 
 	// Проверяем порядок по matches, потом по ratio, потом по ID
 	// Ожидаем:
 	// ID 3: matches=2, ratio=1.0
 	// ID 6: matches=2, ratio=2/3
 	// ID 2: matches=1, ratio=1.0 - именно его, а не c ID 7
-	assert.Equal(t, 3, res[0].ID)
-	assert.Equal(t, 6, res[1].ID)
-	assert.Equal(t, 2, res[2].ID)
+	assert.Equal(t, 3, res.Comics[0].ID)
+	assert.Equal(t, 6, res.Comics[1].ID)
+	assert.Equal(t, 2, res.Comics[2].ID)
 }
 
 // Тесты для метода Service.RebuildIndex.
 func TestServiceRebuildIndex_DBError(t *testing.T) {
 	// 113 строка сервиса
-	// Если db.Search возвращает ошибку — RebuildIndex должен её вернуть.
+	// Если db.Scan возвращает ошибку — RebuildIndex должен её вернуть.
 	expErr := errors.New("db error")
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) { return nil, expErr }},
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, expErr }},
 		&mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) { return nil, nil }},
 	)
 
@@ -183,7 +229,7 @@ func TestServiceRebuildIndex_DBError(t *testing.T) {
 
 func TestServiceRebuildIndex_Success(t *testing.T) {
 
-	db := &mockDB{searchFn: func(ctx context.Context) ([]Comic, error) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
 		return []Comic{
 			{ID: 1, URL: "u1", Words: []string{"foo", "bar"}},
 			{ID: 2, URL: "u2", Words: []string{"bar"}},
@@ -201,15 +247,27 @@ func TestServiceRebuildIndex_Success(t *testing.T) {
 	require.Len(t, svc.comics, 2)
 
 	// слово "bar" должно ссылаться на оба ID
-	idsForBar := svc.index["bar"]
-	require.Len(t, idsForBar, 2)
+	require.Equal(t, 2, svc.index.df("bar"))
+}
+
+func TestServiceRebuildIndex_ScanErrorKeepsIndex(t *testing.T) {
+	comics := []Comic{{ID: 1, URL: "u1", Words: []string{"foo"}}}
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return comics, nil }}
+	svc := newTestService(t, db, &mockWords{})
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	// недочитанная база не заменяет собранный ранее индекс
+	db.scanFn = func(ctx context.Context) ([]Comic, error) { return nil, assert.AnError }
+	require.ErrorIs(t, svc.RebuildIndex(context.Background()), assert.AnError)
+	assert.Len(t, svc.comics, 1)
+	assert.Equal(t, 1, svc.index.df("foo"))
 }
 
 // Тесты для метода Service.IndexSearch.
 func TestServiceIndexSearch_BadArguments(t *testing.T) {
 	// 142 строка сервиса
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
 		&mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) { return nil, nil }},
 	)
 
@@ -226,10 +284,10 @@ func TestServiceIndexSearch_BadArguments(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			res, err := svc.IndexSearch(ctx, tc.phrase, tc.limit)
+			res, err := svc.IndexSearch(ctx, SearchQuery{Phrase: tc.phrase, Limit: tc.limit})
 
 			require.ErrorIs(t, err, ErrBadArguments)
-			assert.Nil(t, res)
+			assert.Nil(t, res.Comics)
 		})
 	}
 }
@@ -239,44 +297,44 @@ func TestServiceIndexSearch_WordsError(t *testing.T) {
 	expErr := errors.New("norm error")
 
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
 		&mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
 			return nil, expErr
 		}},
 	)
 
-	res, err := svc.IndexSearch(context.Background(), "foo", 10)
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo", Limit: 10})
 	require.ErrorIs(t, err, expErr)
-	assert.Nil(t, res)
+	assert.Nil(t, res.Comics)
 }
 
 func TestServiceIndexSearch_NoWordsAfterNorm(t *testing.T) {
 	// 150 строка сервиса
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
 		&mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
 			return []string{}, nil
 		}},
 	)
 
-	res, err := svc.IndexSearch(context.Background(), "foo", 10)
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo", Limit: 10})
 	require.NoError(t, err)
-	assert.Nil(t, res)
+	assert.Nil(t, res.Comics)
 }
 
 func TestServiceIndexSearch_EmptyIndexOrComics(t *testing.T) {
 	// 157 строка сервиса
 	// индекс ещё не построен, карты пустые - возвращаем nil без ошибок.
 	svc := newTestService(t,
-		&mockDB{searchFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
+		&mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, nil }},
 		&mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
 			return []string{"foo"}, nil
 		}},
 	)
 
-	res, err := svc.IndexSearch(context.Background(), "foo", 10)
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo", Limit: 10})
 	require.NoError(t, err)
-	assert.Nil(t, res)
+	assert.Nil(t, res.Comics)
 }
 
 func TestServiceIndexSearch_NoMatchesAfterIndex(t *testing.T) {
@@ -285,7 +343,7 @@ func TestServiceIndexSearch_NoMatchesAfterIndex(t *testing.T) {
 	// 1) строим индекс по слову "foo";
 	// 2) ищем по слову "bar", которого в индексе нет;
 	// 3) ожидаем nil-результат без ошибок.
-	db := &mockDB{searchFn: func(ctx context.Context) ([]Comic, error) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
 		return []Comic{
 			{ID: 1, URL: "u1", Words: []string{"foo"}},
 		}, nil
@@ -300,15 +358,15 @@ func TestServiceIndexSearch_NoMatchesAfterIndex(t *testing.T) {
 	err := svc.RebuildIndex(context.Background())
 	require.NoError(t, err)
 
-	res, err := svc.IndexSearch(context.Background(), "bar", 10)
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "bar", Limit: 10})
 	require.NoError(t, err)
-	assert.Nil(t, res)
+	assert.Nil(t, res.Comics)
 }
 
 func TestServiceIndexSearch_ScoringAndLimit(t *testing.T) {
 	// Аналогичный тест для IndexSearch:
 	// проверяем сортировку, подсчёт matches/ratio и limit.
-	db := &mockDB{searchFn: func(ctx context.Context) ([]Comic, error) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
 		return []Comic{
 			{ID: 1, URL: "u1", Words: []string{"foo", "baz"}},      // 1 совпадение, ratio 0.5
 			{ID: 2, URL: "u2", Words: []string{"foo"}},             // 1 совпадение, ratio 1.0
@@ -329,18 +387,18 @@ func TestServiceIndexSearch_ScoringAndLimit(t *testing.T) {
 	err := svc.RebuildIndex(context.Background())
 	require.NoError(t, err)
 
-	res, err := svc.IndexSearch(context.Background(), "foo bar", 3)
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo bar", Limit: 3})
 	require.NoError(t, err)
-	require.Len(t, res, 3)
+	require.Len(t, res.Comics, 3)
 
 	// Проверяем порядок по matches, потом по ratio, потом по ID
 	// Ожидаем:
 	// ID 3: matches=2, ratio=1.0
 	// ID 6: matches=2, ratio=2/3
 	// ID 2: matches=1, ratio=1.0 - именно его, а не c ID 7
-	assert.Equal(t, 3, res[0].ID)
-	assert.Equal(t, 6, res[1].ID)
-	assert.Equal(t, 2, res[2].ID)
+	assert.Equal(t, 3, res.Comics[0].ID)
+	assert.Equal(t, 6, res.Comics[1].ID)
+	assert.Equal(t, 2, res.Comics[2].ID)
 }
 
 func TestServiceIndexSearch_SkipZeroWordCount(t *testing.T) {
@@ -348,21 +406,296 @@ func TestServiceIndexSearch_SkipZeroWordCount(t *testing.T) {
 	// попал в byId, но у него Words = nil
 	// Этот комикс должен быть пропущен при подсчёте ratio и формировании результата.
 	svc := &Service{
-		db: nil, // в этом тесте БД не используется
+		db: &mockDB{}, // карточек в базе нет, выдача без текстов
 		words: &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
 			return []string{"foo"}, nil
 		}},
-		index: map[string][]int{
-			"foo": {1, 2}, // оба ID связаны со словом foo
-		},
+		ranking: testRanking,
+		index: func() *invertedIndex {
+			ix := newInvertedIndex()
+			// оба ID связаны со словом foo
+			ix.add(Comic{ID: 2, Words: []string{"foo"}})
+			foo, _ := ix.listFor("foo")
+			foo.add(1, nil)
+			ix.docLen[1] = 0
+			return ix
+		}(),
 		comics: map[int]Comic{
 			1: {ID: 1, URL: "u1", Words: nil},             // будет пропущен по wordCount == 0
 			2: {ID: 2, URL: "u2", Words: []string{"foo"}}, // останется в выдаче
 		},
 	}
 
-	res, err := svc.IndexSearch(context.Background(), "foo", 10)
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, res.Comics, 1)
+	assert.Equal(t, 2, res.Comics[0].ID)
+}
+
+func TestNewService_BadRanking(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ranking Ranking
+	}{
+		{"unknown mode", Ranking{Mode: "tfidf"}},
+		{"negative k1", Ranking{Mode: RankingBM25, K1: -1, B: 0.75}},
+		{"b out of range", Ranking{Mode: RankingBM25, K1: 1.2, B: 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, err := NewService(newTestLogger(), &mockDB{}, &mockWords{}, tc.ranking, Shard{}, QueryCache{}, nil, nil)
+			require.Error(t, err)
+			assert.Nil(t, svc)
+		})
+	}
+}
+
+func TestServiceIndexSearch_BM25RareWordWins(t *testing.T) {
+	// "foo" встречается почти везде, "bar" - только в одном комиксе.
+	// По числу совпадений комиксы 1 и 2 равны, но BM25 поднимает
+	// комикс с редким словом, несмотря на больший ID.
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		return []Comic{
+			{ID: 1, URL: "u1", Words: []string{"foo", "x"}},
+			{ID: 2, URL: "u2", Words: []string{"bar", "x"}},
+			{ID: 3, URL: "u3", Words: []string{"foo", "y"}},
+			{ID: 4, URL: "u4", Words: []string{"foo", "z"}},
+		}, nil
+	}}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{"foo", "bar"}, nil
+	}}
+
+	svc := newTestService(t, db, words)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo bar", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, res.Comics, 2)
+	assert.Equal(t, 2, res.Comics[0].ID)
+	assert.Equal(t, 1, res.Comics[1].ID)
+}
+
+func TestServiceIndexSearch_MatchesRanking(t *testing.T) {
+	// в старом режиме ранжирования всё решает число совпадений, потом ID
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		return []Comic{
+			{ID: 1, URL: "u1", Words: []string{"foo", "x"}},
+			{ID: 2, URL: "u2", Words: []string{"bar", "x"}},
+			{ID: 3, URL: "u3", Words: []string{"foo", "y"}},
+			{ID: 4, URL: "u4", Words: []string{"foo", "z"}},
+		}, nil
+	}}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{"foo", "bar"}, nil
+	}}
+
+	svc, err := NewService(newTestLogger(), db, words, Ranking{Mode: RankingMatches}, Shard{}, QueryCache{}, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo bar", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, res.Comics, 2)
+	assert.Equal(t, 1, res.Comics[0].ID)
+	assert.Equal(t, 2, res.Comics[1].ID)
+}
+
+func TestServiceIndexSearch_Pagination(t *testing.T) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		var comics []Comic
+		for id := 1; id <= 5; id++ {
+			comics = append(comics, Comic{ID: id, URL: "u", Words: []string{"foo"}})
+		}
+		return comics, nil
+	}}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{"foo"}, nil
+	}}
+	svc := newTestService(t, db, words)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	testCases := []struct {
+		offset int
+		ids    []int
+	}{
+		{0, []int{1, 2}},
+		{2, []int{3, 4}},
+		{4, []int{5}},
+		{5, nil}, // за последней страницей пусто, но total известен
+	}
+	for _, tc := range testCases {
+		res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo", Limit: 2, Offset: tc.offset})
+		require.NoError(t, err)
+		assert.Equal(t, 5, res.Total)
+
+		var ids []int
+		for _, c := range res.Comics {
+			ids = append(ids, c.ID)
+		}
+		assert.Equal(t, tc.ids, ids, "offset %d", tc.offset)
+	}
+
+	_, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo", Limit: 2, Offset: -1})
+	require.ErrorIs(t, err, ErrBadArguments)
+}
+
+func TestServiceUpdateIndex(t *testing.T) {
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{
+				{ID: 1, URL: "u1", Words: []string{"foo", "bar"}},
+				{ID: 2, URL: "u2", Words: []string{"bar"}},
+				{ID: 3, URL: "u3", Words: []string{"baz"}},
+			}, nil
+		},
+		getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+			// перечитываются только изменившиеся комиксы
+			assert.ElementsMatch(t, []int{4, 2}, ids)
+			return []Comic{
+				{ID: 4, URL: "u4", Words: []string{"qux", "foo"}},
+				{ID: 2, URL: "u2", Words: []string{"quux"}},
+			}, nil
+		},
+	}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{phrase}, nil
+	}}
+	svc := newTestService(t, db, words)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	err := svc.UpdateIndex(context.Background(), IndexChanges{
+		Added:   []int{4},
+		Changed: []int{2},
+		Removed: []int{3},
+	})
+	require.NoError(t, err)
+	// дальше из базы читаются только карточки выдачи
+	db.getFn = nil
+
+	search := func(word string) []int {
+		res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: word, Limit: 10})
+		require.NoError(t, err)
+		var ids []int
+		for _, c := range res.Comics {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []int{1, 4}, search("foo"))
+	assert.ElementsMatch(t, []int{1}, search("bar")) // у комикса 2 слова поменялись
+	assert.Empty(t, search("baz"))                   // комикс 3 удалён
+	assert.ElementsMatch(t, []int{2}, search("quux"))
+
+	assert.Len(t, svc.comics, 3)
+	assert.Equal(t, 5, svc.index.totalLen)
+	assert.Zero(t, svc.index.df("baz"))
+
+	// подсказки тоже обновились
+	sg, err := svc.Suggest(context.Background(), "q", 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Suggestion{{Word: "qux", Count: 1}, {Word: "quux", Count: 1}}, sg)
+	sg, err = svc.Suggest(context.Background(), "ba", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Suggestion{{Word: "bar", Count: 1}}, sg)
+}
+
+func TestServiceUpdateIndex_ReadsInBatches(t *testing.T) {
+	var batches []int
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) { return nil, nil },
+		getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+			batches = append(batches, len(ids))
+			comics := make([]Comic, 0, len(ids))
+			for _, id := range ids {
+				comics = append(comics, Comic{ID: id, Words: []string{"foo"}})
+			}
+			return comics, nil
+		},
+	}
+	svc := newTestService(t, db, &mockWords{})
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	// первое обновление пустого индекса - все комиксы базы
+	added := make([]int, 0, 1200)
+	for id := 1; id <= 1200; id++ {
+		added = append(added, id)
+	}
+	require.NoError(t, svc.UpdateIndex(context.Background(), IndexChanges{Added: added}))
+
+	assert.Equal(t, []int{500, 500, 200}, batches)
+	assert.Len(t, svc.comics, 1200)
+	assert.Equal(t, 1200, svc.index.df("foo"))
+}
+
+func TestServiceUpdateIndex_DroppedRebuilds(t *testing.T) {
+	calls := 0
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			calls++
+			return nil, nil
+		},
+	}
+	svc := newTestService(t, db, &mockWords{})
+
+	require.NoError(t, svc.UpdateIndex(context.Background(), IndexChanges{Dropped: true}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestServiceUpdateIndex_DBError(t *testing.T) {
+	db := &mockDB{
+		getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+			return nil, assert.AnError
+		},
+	}
+	svc := newTestService(t, db, &mockWords{})
+
+	err := svc.UpdateIndex(context.Background(), IndexChanges{Added: []int{1}})
+	require.ErrorIs(t, err, assert.AnError)
+}
+
+func TestServiceIndexSearch_Explain(t *testing.T) {
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{
+				{ID: 1, URL: "u1", Words: []string{"foo", "bar"}},
+				{ID: 2, URL: "u2", Words: []string{"foo"}},
+				{ID: 3, URL: "u3", Words: []string{"foo"}},
+			}, nil
+		},
+	}
+	svc := newTestService(t, db, &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return strings.Fields(phrase), nil
+	}})
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo bar", Limit: 10})
+	require.NoError(t, err)
+	for _, c := range res.Comics {
+		assert.Nil(t, c.Explain)
+	}
+
+	res, err = svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo bar", Limit: 10, Explain: true})
 	require.NoError(t, err)
-	require.Len(t, res, 1)
-	assert.Equal(t, 2, res[0].ID)
+	require.Len(t, res.Comics, 3)
+
+	first := res.Comics[0].Explain
+	require.NotNil(t, first)
+	assert.Equal(t, []string{"foo", "bar"}, first.Terms)
+	require.Len(t, first.Matched, 2)
+	assert.Equal(t, "foo", first.Matched[0].Term)
+	assert.Equal(t, "bar", first.Matched[1].Term)
+	assert.Equal(t, 1, first.Matched[1].TF)
+	assert.Equal(t, 1.0, first.Matched[1].Weight)
+	assert.InDelta(t, first.Matched[0].Score+first.Matched[1].Score, first.Score/first.Proximity, 1e-9)
+	assert.Equal(t, res.Comics[0].Score, first.Score)
+	assert.Equal(t, 2, first.Matches)
+	assert.Equal(t, TieBreakScore, first.TieBreak)
+
+	// у 2 и 3 одинаковые оценки, порядок решает id
+	assert.Equal(t, []string{"foo", "bar"}, res.Comics[1].Explain.Terms)
+	require.Len(t, res.Comics[1].Explain.Matched, 1)
+	assert.Equal(t, TieBreakID, res.Comics[1].Explain.TieBreak)
+	assert.Empty(t, res.Comics[2].Explain.TieBreak)
 }
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+type ShardMode string
+
+const (
+	ShardNone  ShardMode = "none"
+	ShardHash  ShardMode = "hash"
+	ShardRange ShardMode = "range"
+)
+
+// Shard - какая часть комиксов по id достаётся этому экземпляру поиска.
+// Пустой режим равносилен ShardNone: индексируются все комиксы.
+type Shard struct {
+	Mode ShardMode
+
+	// hash: комикс принадлежит шарду Index из Count
+	Index int
+	Count int
+
+	// range: комиксы с id из [From, To), To = 0 - без верхней границы
+	From int
+	To   int
+}
+
+func (s Shard) validate() error {
+	switch s.Mode {
+	case "", ShardNone:
+	case ShardHash:
+		if s.Count < 1 || s.Index < 0 || s.Index >= s.Count {
+			return fmt.Errorf("wrong hash shard: index=%d count=%d", s.Index, s.Count)
+		}
+	case ShardRange:
+		if s.From < 0 || s.To != 0 && s.To <= s.From {
+			return fmt.Errorf("wrong range shard: from=%d to=%d", s.From, s.To)
+		}
+	default:
+		return fmt.Errorf("unknown shard mode: %q", s.Mode)
+	}
+	return nil
+}
+
+func (s Shard) owns(id int) bool {
+	switch s.Mode {
+	case ShardHash:
+		h := fnv.New32a()
+		_, _ = h.Write(strconv.AppendInt(nil, int64(id), 10))
+		return int(h.Sum32()%uint32(s.Count)) == s.Index
+	case ShardRange:
+		return id >= s.From && (s.To == 0 || id < s.To)
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShard_Validate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		shard Shard
+		ok    bool
+	}{
+		{"zero value", Shard{}, true},
+		{"none", Shard{Mode: ShardNone}, true},
+		{"hash", Shard{Mode: ShardHash, Index: 2, Count: 3}, true},
+		{"hash index out of range", Shard{Mode: ShardHash, Index: 3, Count: 3}, false},
+		{"hash without count", Shard{Mode: ShardHash}, false},
+		{"range", Shard{Mode: ShardRange, From: 100, To: 200}, true},
+		{"open range", Shard{Mode: ShardRange, From: 100}, true},
+		{"empty range", Shard{Mode: ShardRange, From: 200, To: 100}, false},
+		{"unknown", Shard{Mode: "modulo"}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.shard.validate()
+			if tc.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestShard_HashPartitions(t *testing.T) {
+	const count = 3
+	sizes := make([]int, count)
+	for id := 1; id <= 3000; id++ {
+		owners := 0
+		for i := range count {
+			if (Shard{Mode: ShardHash, Index: i, Count: count}).owns(id) {
+				owners++
+				sizes[i]++
+			}
+		}
+		// каждый комикс ровно в одном шарде
+		require.Equal(t, 1, owners, id)
+	}
+	for _, n := range sizes {
+		assert.InDelta(t, 1000, n, 150)
+	}
+}
+
+func TestShard_Range(t *testing.T) {
+	s := Shard{Mode: ShardRange, From: 10, To: 20}
+	assert.False(t, s.owns(9))
+	assert.True(t, s.owns(10))
+	assert.True(t, s.owns(19))
+	assert.False(t, s.owns(20))
+	assert.True(t, Shard{Mode: ShardRange, From: 10}.owns(1_000_000))
+}
+
+func TestServiceShard_IndexesOwnComics(t *testing.T) {
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{
+				{ID: 1, Words: []string{"foo"}},
+				{ID: 2, Words: []string{"foo"}},
+				{ID: 3, Words: []string{"foo"}},
+			}, nil
+		},
+		getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+			// чужие комиксы из базы не читаются
+			assert.Equal(t, []int{4}, ids)
+			return []Comic{{ID: 4, Words: []string{"foo"}}}, nil
+		},
+	}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{phrase}, nil
+	}}
+	svc, err := NewService(newTestLogger(), db, words, testRanking, Shard{Mode: ShardRange, From: 2, To: 5}, QueryCache{}, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	require.NoError(t, svc.UpdateIndex(context.Background(), IndexChanges{Added: []int{4, 5}}))
+	// дальше из базы читаются только карточки выдачи
+	db.getFn = nil
+
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "foo", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Total)
+	for _, c := range res.Comics {
+		assert.Contains(t, []int{2, 3, 4}, c.ID)
+		// оценка отдаётся наружу для слияния выдачи шардов
+		assert.Positive(t, c.Score)
+	}
+}
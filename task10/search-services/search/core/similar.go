@@ -0,0 +1,157 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+)
+
+// Similar - комиксы, больше всего похожие на комикс id по словам.
+// Похожесть - косинус между векторами TF-IDF комиксов, она же оценка в выдаче.
+// Комикс другого шарда читается из базы: похожие на него могут лежать и здесь,
+// поэтому шлюз опрашивает все шарды и сливает выдачу.
+func (s *Service) Similar(ctx context.Context, id, limit int) ([]Comic, error) {
+	if limit <= 0 {
+		return nil, ErrBadArguments
+	}
+
+	s.mu.RLock()
+	_, owned := s.comics[id]
+	s.mu.RUnlock()
+
+	var remote []Comic
+	if !owned {
+		var err error
+		if remote, err = s.db.Get(ctx, []int{id}); err != nil {
+			return nil, err
+		}
+		if len(remote) == 0 {
+			return nil, ErrNotFound
+		}
+	}
+
+	s.mu.RLock()
+	var tf []termFreq
+	if owned {
+		source, ok := s.comics[id]
+		if !ok {
+			// удалён, пока читали базу
+			s.mu.RUnlock()
+			return nil, ErrNotFound
+		}
+		tf = s.index.termFreqs(source)
+	} else {
+		tf = comicTermFreqs(remote[0])
+	}
+	res := s.index.similar(s.comics, id, tf, limit)
+	s.mu.RUnlock()
+
+	if err := s.loadCards(ctx, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// termFreq - слово исходного комикса и сколько раз оно в нём встречается
+type termFreq struct {
+	word string
+	tf   int
+}
+
+// termFreqs - частоты слов проиндексированного комикса
+func (ix *invertedIndex) termFreqs(c Comic) []termFreq {
+	tf := make([]termFreq, 0, len(c.Words))
+	for _, word := range c.Words {
+		if list := ix.list(word); list != nil {
+			if p, ok := list.get(c.ID); ok {
+				tf = append(tf, termFreq{word: word, tf: p.tf()})
+			}
+		}
+	}
+	return tf
+}
+
+// comicTermFreqs - частоты слов комикса из базы, как их посчитал бы индекс
+func comicTermFreqs(c Comic) []termFreq {
+	tf := make([]termFreq, 0, len(c.Words))
+	for _, word := range c.Words {
+		tf = append(tf, termFreq{word: word, tf: max(len(c.Positions[word]), 1)})
+	}
+	return tf
+}
+
+// similar сравнивает комиксы индекса с вектором исходного комикса sourceID,
+// заданным частотами слов tf. Слова, которых в индексе нет, удлиняют
+// вектор исходного комикса, но ни с чем не совпадают.
+func (ix *invertedIndex) similar(comics map[int]Comic, sourceID int, tf []termFreq, limit int) []Comic {
+	docs := ix.docs()
+	norms := ix.vectorNorms()
+
+	// скалярные произведения с исходным комиксом - только по его словам
+	dots := make(map[int]float64)
+	sourceNorm := 0.0
+	for _, t := range tf {
+		df := 0
+		list := ix.list(t.word)
+		if list != nil {
+			df = list.df
+		}
+		termIDF := idf(docs, df)
+		sw := float64(t.tf) * termIDF
+		sourceNorm += sw * sw
+		if df == 0 {
+			continue
+		}
+		for p := range list.all() {
+			if p.id != sourceID {
+				dots[p.id] += sw * float64(p.tf()) * termIDF
+			}
+		}
+	}
+
+	sourceNorm = math.Sqrt(sourceNorm)
+	res := make([]Comic, 0, len(dots))
+	for id, dot := range dots {
+		c, ok := comics[id]
+		if !ok || dot == 0 {
+			continue
+		}
+		c.Score = dot / (sourceNorm * norms[id])
+		res = append(res, c)
+	}
+	slices.SortFunc(res, func(a, b Comic) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+// vectorNorms - длины векторов TF-IDF всех комиксов. IDF зависит от числа
+// комиксов, поэтому любая правка индекса сбрасывает длины, и они считаются
+// заново один раз - при первом запросе после правки или в compact.
+// Параллельные запросы под RLock могут посчитать их одновременно, это безопасно.
+func (ix *invertedIndex) vectorNorms() map[int]float64 {
+	if norms := ix.norms.Load(); norms != nil {
+		return *norms
+	}
+	docs := ix.docs()
+	norms := make(map[int]float64, len(ix.docLen))
+	for _, list := range ix.terms() {
+		termIDF := idf(docs, list.df)
+		for p := range list.all() {
+			w := float64(p.tf()) * termIDF
+			norms[p.id] += w * w
+		}
+	}
+	for id, n := range norms {
+		norms[id] = math.Sqrt(n)
+	}
+	ix.norms.Store(&norms)
+	return norms
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceSimilar(t *testing.T) {
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{
+				{ID: 1, URL: "u1", Words: []string{"raptor", "attack", "door"}},
+				{ID: 2, URL: "u2", Words: []string{"raptor", "attack"}},
+				{ID: 3, URL: "u3", Words: []string{"door", "key", "lock", "house"}},
+				{ID: 4, URL: "u4", Words: []string{"physics"}},
+			}, nil
+		},
+	}
+	svc := newTestService(t, db, &mockWords{})
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	res, err := svc.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+
+	var ids []int
+	for _, c := range res {
+		ids = append(ids, c.ID)
+		assert.Greater(t, c.Score, 0.0)
+		assert.LessOrEqual(t, c.Score, 1.0)
+	}
+	// сам комикс и комиксы без общих слов не возвращаются
+	assert.Equal(t, []int{2, 3}, ids)
+
+	res, err = svc.Similar(context.Background(), 1, 1)
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	// у комикса 2 все слова есть в первом, но у первого есть лишнее
+	res, err = svc.Similar(context.Background(), 2, 1)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, 1, res[0].ID)
+	assert.Less(t, res[0].Score, 1.0)
+}
+
+func TestServiceSimilar_Errors(t *testing.T) {
+	db := &mockDB{getFn: func(ctx context.Context, ids []int) ([]Comic, error) { return nil, nil }}
+	svc := newTestService(t, db, &mockWords{})
+
+	_, err := svc.Similar(context.Background(), 1, 10)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = svc.Similar(context.Background(), 1, 0)
+	require.ErrorIs(t, err, ErrBadArguments)
+}
+
+func TestServiceSimilar_UpdatedIndex(t *testing.T) {
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{
+				{ID: 1, URL: "u1", Words: []string{"raptor", "attack"}},
+				{ID: 2, URL: "u2", Words: []string{"raptor", "door"}},
+			}, nil
+		},
+		getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+			return []Comic{{ID: 3, URL: "u3", Words: []string{"raptor", "attack"}}}, nil
+		},
+	}
+	svc := newTestService(t, db, &mockWords{})
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	res, err := svc.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, 2, res[0].ID)
+
+	// длины векторов пересчитываются после правки индекса, копия комикса 1 похожа на него полностью
+	require.NoError(t, svc.UpdateIndex(context.Background(), IndexChanges{Added: []int{3}}))
+	res, err = svc.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, 3, res[0].ID)
+	assert.InDelta(t, 1.0, res[0].Score, 1e-9)
+}
+
+func TestServiceSimilar_OtherShard(t *testing.T) {
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{
+				{ID: 2, URL: "u2", Words: []string{"raptor", "attack"}},
+				{ID: 4, URL: "u4", Words: []string{"door"}},
+			}, nil
+		},
+		getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+			if ids[0] != 1 {
+				return nil, nil
+			}
+			return []Comic{{ID: 1, URL: "u1", Words: []string{"raptor", "attack", "physics"}}}, nil
+		},
+	}
+	svc, err := NewService(newTestLogger(), db, &mockWords{}, testRanking, Shard{Mode: ShardRange, From: 2}, QueryCache{}, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	// комикс 1 лежит в другом шарде: его слова берутся из базы
+	res, err := svc.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, 2, res[0].ID)
+	assert.Less(t, res[0].Score, 1.0)
+
+	_, err = svc.Similar(context.Background(), 5, 10)
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,289 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"maps"
+	"slices"
+)
+
+// Формат снимка индекса:
+//
+//	magic | версия (uvarint) | комиксы | комиксы без полей | обратный индекс | crc32 всего предыдущего
+//
+// Числа пишутся как varint, строки - длина и байты. Позиции слов хранятся
+// только в индексе: списки вхождений пишутся как есть, в сжатом виде,
+// за списком слова по всему комиксу идут его списки по полям. Тексты комиксов
+// в снимок не попадают: как и в памяти, их читают из базы для карточек выдачи.
+// При изменении формата нужно поднять snapshotVersion, старые файлы будут отброшены.
+const (
+	snapshotMagic   = "XKCDIDX"
+	snapshotVersion = 6
+)
+
+func encodeSnapshot(ix *invertedIndex, comics map[int]Comic) []byte {
+	w := &snapshotWriter{buf: []byte(snapshotMagic)}
+	w.uint(snapshotVersion)
+
+	ids := slices.Sorted(maps.Keys(comics))
+	w.uint(uint64(len(ids)))
+	for _, id := range ids {
+		c := comics[id]
+		w.int(c.ID)
+		w.string(c.URL)
+		w.uint(uint64(len(c.Words)))
+		for _, word := range c.Words {
+			w.string(word)
+		}
+	}
+
+	noFields := slices.Sorted(maps.Keys(ix.noFields))
+	w.uint(uint64(len(noFields)))
+	for _, id := range noFields {
+		w.int(id)
+	}
+
+	w.uint(uint64(ix.words()))
+	for term, list := range ix.terms() {
+		w.string(term)
+		w.postings(list)
+		id, _ := ix.dict.id(term)
+		for f := range ix.fields {
+			w.postings(&ix.fields[f][id])
+		}
+	}
+
+	return binary.BigEndian.AppendUint32(w.buf, crc32.ChecksumIEEE(w.buf))
+}
+
+func decodeSnapshot(data []byte) (*invertedIndex, map[int]Comic, error) {
+	if len(data) < len(snapshotMagic)+4 || !bytes.HasPrefix(data, []byte(snapshotMagic)) {
+		return nil, nil, fmt.Errorf("%w: not an index snapshot", ErrBadSnapshot)
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, nil, fmt.Errorf("%w: checksum mismatch", ErrBadSnapshot)
+	}
+
+	r := &snapshotReader{buf: body[len(snapshotMagic):]}
+	if version := r.uint(); r.err == nil && version != snapshotVersion {
+		return nil, nil, fmt.Errorf("%w: version %d, expected %d", ErrBadSnapshot, version, snapshotVersion)
+	}
+
+	n := r.count()
+	comics := make(map[int]Comic, n)
+	for range n {
+		c := Comic{ID: r.int(), URL: r.string()}
+		words := r.count()
+		c.Words = make([]string, 0, words)
+		for range words {
+			c.Words = append(c.Words, r.string())
+		}
+		comics[c.ID] = c
+	}
+
+	ix := newInvertedIndex()
+	for range r.count() {
+		ix.noFields[r.int()] = struct{}{}
+	}
+
+	terms := r.count()
+	for range terms {
+		term := r.string()
+		id := ix.intern(term)
+		list := &ix.lists[id]
+		if r.err == nil && list.df > 0 {
+			return nil, nil, fmt.Errorf("%w: duplicate term %q", ErrBadSnapshot, term)
+		}
+		*list = r.postings()
+		for f := range ix.fields {
+			ix.fields[f][id] = r.postings()
+		}
+		if r.err != nil {
+			break
+		}
+		if err := list.check(); err != nil || list.df == 0 {
+			return nil, nil, fmt.Errorf("%w: term %q: %v", ErrBadSnapshot, term, errBadPostings)
+		}
+		list.buildSkips()
+		for f := range ix.fields {
+			if err := ix.fields[f][id].check(); err != nil {
+				return nil, nil, fmt.Errorf("%w: term %q in %s: %v", ErrBadSnapshot, term, fieldNames[f], err)
+			}
+			ix.fields[f][id].buildSkips()
+		}
+		ix.live++
+		for p := range list.all() {
+			ix.docLen[p.id] += p.tf()
+			ix.totalLen += p.tf()
+		}
+	}
+
+	if r.err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrBadSnapshot, r.err)
+	}
+	// слова комиксов - ссылки на словарь индекса, как после add
+	for _, c := range comics {
+		for i, word := range c.Words {
+			if id, ok := ix.dict.id(word); ok {
+				c.Words[i] = ix.dict.terms[id]
+			}
+		}
+	}
+	// комиксы без слов тоже считаются документами индекса
+	for id := range comics {
+		if _, ok := ix.docLen[id]; !ok {
+			ix.docLen[id] = 0
+		}
+	}
+	if len(r.buf) != 0 {
+		return nil, nil, fmt.Errorf("%w: %d unexpected trailing bytes", ErrBadSnapshot, len(r.buf))
+	}
+	return ix, comics, nil
+}
+
+type snapshotWriter struct {
+	buf []byte
+}
+
+func (w *snapshotWriter) uint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *snapshotWriter) int(v int) {
+	w.buf = binary.AppendVarint(w.buf, int64(v))
+}
+
+func (w *snapshotWriter) string(s string) {
+	w.uint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *snapshotWriter) bytes(b []byte) {
+	w.uint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *snapshotWriter) postings(l *postingList) {
+	w.uint(uint64(l.df))
+	w.int(l.lastID)
+	w.bytes(l.data)
+}
+
+// snapshotReader запоминает первую ошибку, после неё все чтения возвращают нули
+type snapshotReader struct {
+	buf []byte
+	err error
+}
+
+var errTruncated = errors.New("unexpected end of data")
+
+func (r *snapshotReader) uint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *snapshotReader) int() int {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return int(v)
+}
+
+// count - длина списка, которая не может быть больше оставшихся байт
+func (r *snapshotReader) count() int {
+	v := r.uint()
+	if v > uint64(len(r.buf)) {
+		r.err = errTruncated
+		return 0
+	}
+	return int(v)
+}
+
+func (r *snapshotReader) string() string {
+	n := r.count()
+	if r.err != nil {
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+// bytes копирует данные, чтобы индекс не держал весь прочитанный файл
+func (r *snapshotReader) bytes() []byte {
+	n := r.count()
+	if r.err != nil || n == 0 {
+		return nil
+	}
+	b := bytes.Clone(r.buf[:n])
+	r.buf = r.buf[n:]
+	return b
+}
+
+// LoadSnapshot заполняет индекс из сохранённого снимка, чтобы искать
+// сразу после старта, не дожидаясь перестройки по базе
+func (s *Service) LoadSnapshot(ctx context.Context) error {
+	if s.snapshots == nil {
+		return nil
+	}
+	data, err := s.snapshots.Load(ctx)
+	if err != nil {
+		return err
+	}
+	index, comics, err := decodeSnapshot(data)
+	if err != nil {
+		return err
+	}
+	prefixes := newPrefixIndex(index)
+
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	s.mu.Lock()
+	s.index = index
+	s.prefixes = prefixes
+	s.comics = comics
+	s.indexChanged()
+	s.mu.Unlock()
+
+	s.log.Info("search index loaded from snapshot",
+		"comics", len(comics),
+		"words", index.words(),
+	)
+	return nil
+}
+
+// saveSnapshot вызывается под updateMu, поэтому индекс не меняется во время записи
+func (s *Service) saveSnapshot(ctx context.Context, ix *invertedIndex, comics map[int]Comic) {
+	if s.snapshots == nil {
+		return
+	}
+	if err := s.snapshots.Save(ctx, encodeSnapshot(ix, comics)); err != nil {
+		s.log.Error("failed to save index snapshot", "error", err)
+	}
+}
+
+// postings - список вхождений, до check() ему нельзя доверять,
+// куски для поиска строит buildSkips после проверки
+func (r *snapshotReader) postings() postingList {
+	return postingList{df: r.count(), lastID: r.int(), data: r.bytes()}
+}
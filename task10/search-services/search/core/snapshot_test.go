@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSnapshots struct {
+	data    []byte
+	loadErr error
+}
+
+func (m *mockSnapshots) Save(ctx context.Context, data []byte) error {
+	m.data = data
+	return nil
+}
+
+func (m *mockSnapshots) Load(ctx context.Context) ([]byte, error) {
+	if m.loadErr != nil {
+		return nil, m.loadErr
+	}
+	return m.data, nil
+}
+
+func snapshotFixture() (*invertedIndex, map[int]Comic) {
+	comics := map[int]Comic{
+		1: {ID: 1, URL: "u1", Words: []string{"foo", "bar"}, Positions: map[string][]int{"foo": {0, 2}, "bar": {1}}, Surfaces: map[string][]string{"foo": {"foos", "foo"}},
+			Fields: FieldWords{Title: []string{"foo"}, Alt: []string{"bar"}},
+			Meta:   ComicMeta{Title: "Foo", Alt: "bar", Published: time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		2: {ID: 2, URL: "u2", Words: []string{"bar"}}, // без позиций, как старые записи
+		3: {ID: 3, URL: "u3", Words: []string{}},
+	}
+	ix := newInvertedIndex()
+	for _, id := range []int{1, 2, 3} {
+		comics[id] = ix.add(comics[id])
+	}
+	return ix, comics
+}
+
+func TestSnapshot_Roundtrip(t *testing.T) {
+	ix, comics := snapshotFixture()
+
+	gotIx, gotComics, err := decodeSnapshot(encodeSnapshot(ix, comics))
+	require.NoError(t, err)
+
+	assert.Equal(t, comics, gotComics)
+	assert.Equal(t, ix.dict, gotIx.dict)
+	assert.Equal(t, ix.lists, gotIx.lists)
+	assert.Equal(t, ix.fields, gotIx.fields)
+	assert.Equal(t, ix.noFields, gotIx.noFields)
+	assert.Equal(t, ix.words(), gotIx.words())
+	assert.Equal(t, ix.docLen, gotIx.docLen)
+	assert.Equal(t, ix.totalLen, gotIx.totalLen)
+	// словарь для нечёткого поиска восстановлен
+	assert.Equal(t, []fuzzyMatch{{word: "bar", dist: 1}}, gotIx.fuzzyTerms("baz", 1))
+	// позиции сохранились в сжатых списках
+	foo, ok := gotIx.list("foo").get(1)
+	require.True(t, ok)
+	assert.Equal(t, []int{0, 2}, foo.positions())
+}
+
+func TestSnapshot_Corrupted(t *testing.T) {
+	ix, comics := snapshotFixture()
+	data := encodeSnapshot(ix, comics)
+
+	// пересчитывает контрольную сумму, чтобы проверить разбор содержимого
+	resum := func(body []byte) []byte {
+		return binary.BigEndian.AppendUint32(body, crc32.ChecksumIEEE(body))
+	}
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)/2] ^= 0xff
+	newer := append([]byte(snapshotMagic), byte(snapshotVersion+1))
+	newer = resum(append(newer, data[len(snapshotMagic)+1:len(data)-4]...))
+	truncated := resum(append([]byte(nil), data[:len(data)/2]...))
+	trailing := resum(append(append([]byte(nil), data[:len(data)-4]...), 0))
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not a snapshot", []byte("hello, world")},
+		{"checksum mismatch", flipped},
+		{"unknown version", newer},
+		{"truncated", truncated},
+		{"trailing bytes", trailing},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := decodeSnapshot(tc.data)
+			require.ErrorIs(t, err, ErrBadSnapshot)
+		})
+	}
+}
+
+func TestServiceSnapshot_SaveAndLoad(t *testing.T) {
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{phrase}, nil
+	}}
+	store := &mockSnapshots{}
+
+	svc, err := NewService(newTestLogger(), &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{
+				{ID: 1, URL: "u1", Words: []string{"foo", "bar"}},
+				{ID: 2, URL: "u2", Words: []string{"bar"}},
+			}, nil
+		},
+	}, words, testRanking, Shard{}, QueryCache{}, store, nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	require.NotEmpty(t, store.data)
+
+	// новый сервис отвечает по снимку, в базу не ходит
+	restarted, err := NewService(newTestLogger(), &mockDB{}, words, testRanking, Shard{}, QueryCache{}, store, nil)
+	require.NoError(t, err)
+	require.NoError(t, restarted.LoadSnapshot(context.Background()))
+
+	res, err := restarted.IndexSearch(context.Background(), SearchQuery{Phrase: "bar", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Total)
+
+	sg, err := restarted.Suggest(context.Background(), "fo", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Suggestion{{Word: "foo", Count: 1}}, sg)
+}
+
+func TestServiceLoadSnapshot_Errors(t *testing.T) {
+	// без хранилища загружать нечего
+	svc := newTestService(t, &mockDB{}, &mockWords{})
+	require.NoError(t, svc.LoadSnapshot(context.Background()))
+
+	svc, err := NewService(newTestLogger(), &mockDB{}, &mockWords{}, testRanking, Shard{}, QueryCache{},
+		&mockSnapshots{loadErr: ErrSnapshotNotFound}, nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, svc.LoadSnapshot(context.Background()), ErrSnapshotNotFound)
+
+	svc, err = NewService(newTestLogger(), &mockDB{}, &mockWords{}, testRanking, Shard{}, QueryCache{},
+		&mockSnapshots{data: []byte("garbage")}, nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, svc.LoadSnapshot(context.Background()), ErrBadSnapshot)
+	assert.Empty(t, svc.comics)
+}
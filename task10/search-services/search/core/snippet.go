@@ -0,0 +1,115 @@
+package core
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// сколько слов исходного текста попадает в сниппет
+	snippetWords = 20
+	// сколько слов оставить перед первым найденным
+	snippetContext  = 4
+	snippetEllipsis = "…"
+)
+
+var (
+	surfaceWord = regexp.MustCompile(`[\p{L}\p{N}]+`)
+	spaces      = regexp.MustCompile(`\s+`)
+)
+
+// makeSnippet находит в названии, alt или расшифровке комикса слова запроса
+// и вырезает вокруг них кусок текста. Слова запроса - нормализованные, поэтому
+// в тексте ищутся их написания, запомненные при нормализации комикса.
+// Берётся поле с наибольшим числом совпадений, при равенстве - более раннее.
+func makeSnippet(c Comic, terms []queryTerm) *Snippet {
+	surfaces := make(map[string]bool)
+	for _, t := range terms {
+		// у старых записей написаний нет, но часто слово совпадает с основой
+		surfaces[t.stem] = true
+		for _, s := range c.Surfaces[t.stem] {
+			surfaces[s] = true
+		}
+	}
+	if len(surfaces) == 0 {
+		return nil
+	}
+
+	var best *Snippet
+	bestCount := 0
+	for _, field := range []struct{ name, text string }{
+		{FieldTitle, c.Meta.Title},
+		{FieldAlt, c.Meta.Alt},
+		{FieldTranscript, c.Meta.Transcript},
+	} {
+		snippet, count := cutSnippet(field.text, surfaces)
+		if count > bestCount {
+			snippet.Field = field.name
+			best, bestCount = snippet, count
+		}
+	}
+	return best
+}
+
+// cutSnippet - окно из snippetWords слов текста, где больше всего найденных слов
+func cutSnippet(text string, surfaces map[string]bool) (*Snippet, int) {
+	words := surfaceWord.FindAllStringIndex(text, -1)
+	var matched []int
+	for i, w := range words {
+		if surfaces[strings.ToLower(text[w[0]:w[1]])] {
+			matched = append(matched, i)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, 0
+	}
+
+	first, count := matched[0], 0
+	for i, m := range matched {
+		if n := sort.SearchInts(matched[i:], m+snippetWords-snippetContext); n > count {
+			first, count = m, n
+		}
+	}
+	from := max(first-snippetContext, 0)
+	to := min(from+snippetWords, len(words))
+
+	// переводы строк и отступы расшифровки схлопываются в пробелы
+	var b strings.Builder
+	snippet := &Snippet{}
+	if from > 0 {
+		b.WriteString(snippetEllipsis)
+	} else {
+		b.WriteString(strings.TrimLeft(spaces.ReplaceAllString(text[:words[0][0]], " "), " "))
+	}
+	next := 0
+	for i := from; i < to; i++ {
+		w := words[i]
+		if i > from {
+			b.WriteString(spaces.ReplaceAllString(text[words[i-1][1]:w[0]], " "))
+		}
+		for next < len(matched) && matched[next] < i {
+			next++
+		}
+		start := b.Len()
+		b.WriteString(text[w[0]:w[1]])
+		if next < len(matched) && matched[next] == i {
+			snippet.Highlights = append(snippet.Highlights, Span{Start: start, End: b.Len()})
+		}
+	}
+	if to < len(words) {
+		b.WriteString(snippetEllipsis)
+	} else {
+		b.WriteString(strings.TrimRight(spaces.ReplaceAllString(text[words[to-1][1]:], " "), " "))
+	}
+	snippet.Text = b.String()
+	return snippet, len(snippet.Highlights)
+}
+
+// withSnippets добавляет сниппеты к комиксам страницы выдачи
+func withSnippets(res SearchResult, terms []queryTerm) SearchResult {
+	for i, c := range res.Comics {
+		res.Comics[i].Snippet = makeSnippet(c, terms)
+	}
+	return res
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// marked размечает найденные слова, чтобы сниппеты было удобно сравнивать
+func marked(s *Snippet) string {
+	if s == nil {
+		return ""
+	}
+	res, last := "", 0
+	for _, h := range s.Highlights {
+		res += s.Text[last:h.Start] + "[" + s.Text[h.Start:h.End] + "]"
+		last = h.End
+	}
+	return res + s.Text[last:]
+}
+
+func TestMakeSnippet_Surfaces(t *testing.T) {
+	c := Comic{
+		Surfaces: map[string][]string{"tabl": {"tables"}, "bobbi": {"bobby"}},
+		Meta: ComicMeta{
+			Title: "Exploits of a Mom",
+			Alt:   "Her daughter is named Help I'm trapped in a driver's license factory.",
+			Transcript: "[[A woman is talking on the phone.]]\nPhone: Did you really\n" +
+				"name your son Robert'); DROP TABLES Students;-- ?\nMom: Oh, yes. Little Bobby Tables, we call him.",
+		},
+	}
+
+	s := makeSnippet(c, []queryTerm{{stem: "tabl"}, {stem: "bobbi"}})
+	require.NotNil(t, s)
+	assert.Equal(t, "transcript", s.Field)
+	// окно там, где совпадений больше, переводы строк схлопнуты
+	assert.Equal(t, "…your son Robert'); DROP [TABLES] Students;-- ? Mom: Oh, yes. Little [Bobby] [Tables], we call him.", marked(s))
+}
+
+func TestMakeSnippet_PrefersEarlierField(t *testing.T) {
+	c := Comic{
+		Meta: ComicMeta{
+			Title: "Linux User",
+			Alt:   "linux forever",
+		},
+	}
+	// написаний нет, слово ищется как есть
+	s := makeSnippet(c, []queryTerm{{stem: "linux"}})
+	require.NotNil(t, s)
+	assert.Equal(t, "title", s.Field)
+	assert.Equal(t, "[Linux] User", marked(s))
+
+	assert.Nil(t, makeSnippet(c, []queryTerm{{stem: "window"}}))
+}
+
+func TestCutSnippet_LongText(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve " +
+		"thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty " +
+		"target twentytwo twentythree twentyfour twentyfive"
+	s, count := cutSnippet(text, map[string]bool{"target": true})
+	require.Equal(t, 1, count)
+	assert.Equal(t, "…seventeen eighteen nineteen twenty [target] twentytwo twentythree twentyfour twentyfive", marked(s))
+}
+
+func TestServiceIndexSearch_Snippets(t *testing.T) {
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) {
+			return []Comic{{
+				ID:       1,
+				Words:    []string{"run"},
+				Surfaces: map[string][]string{"run": {"running"}},
+				Meta:     ComicMeta{Title: "Running"},
+			}}, nil
+		},
+	}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{"run"}, nil
+	}}
+	svc := newTestService(t, db, words)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "runs", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, res.Comics, 1)
+	assert.Equal(t, &Snippet{Field: "title", Text: "Running", Highlights: []Span{{0, 7}}}, res.Comics[0].Snippet)
+	// в индексе сниппет не остаётся, тексты для него читаются из базы
+	assert.Nil(t, svc.comics[1].Snippet)
+	assert.Empty(t, svc.comics[1].Meta.Title)
+	assert.Nil(t, svc.comics[1].Surfaces)
+
+	// карточки страницы не прочитались - выдачи нет
+	db.getFn = func(ctx context.Context, ids []int) ([]Comic, error) { return nil, assert.AnError }
+	_, err = svc.IndexSearch(context.Background(), SearchQuery{Phrase: "run", Limit: 10})
+	require.ErrorIs(t, err, assert.AnError)
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// IndexStats - что сейчас лежит в индексе и как прошла его последняя перестройка.
+// По ним видно, не отвечает ли поиск по устаревшему или пустому индексу.
+type IndexStats struct {
+	Comics   int
+	Words    int // размер словаря
+	Postings int // пар слово-комикс
+	// Generation растёт при каждой замене или правке индекса
+	Generation      uint64
+	LastRebuild     time.Time // нулевое, если индекс ещё не перестраивался
+	RebuildDuration time.Duration
+	// ошибка последней неудачной перестройки или правки индекса,
+	// успешная полная перестройка её сбрасывает
+	LastError   string
+	LastErrorAt time.Time
+	Cache       CacheStats
+}
+
+func (s *Service) Stats(_ context.Context) (IndexStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st := IndexStats{
+		Comics:          len(s.comics),
+		Words:           s.index.words(),
+		Postings:        s.index.postings(),
+		Generation:      s.generation,
+		LastRebuild:     s.lastRebuild,
+		RebuildDuration: s.rebuildTook,
+		LastErrorAt:     s.lastErrAt,
+		Cache:           s.cache.stats(),
+	}
+	if s.lastErr != nil {
+		st.LastError = s.lastErr.Error()
+	}
+	return st, nil
+}
+
+// indexChanged вызывается под mu после любой замены или правки индекса
+func (s *Service) indexChanged() {
+	s.generation++
+	s.cache.invalidate()
+}
+
+func (s *Service) indexFailed(err error) {
+	s.mu.Lock()
+	s.lastErr = err
+	s.lastErrAt = time.Now()
+	s.mu.Unlock()
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceStats(t *testing.T) {
+	comics := []Comic{
+		{ID: 1, URL: "u1", Words: []string{"foo", "bar"}},
+		{ID: 2, URL: "u2", Words: []string{"bar"}},
+	}
+	db := &mockDB{
+		scanFn: func(ctx context.Context) ([]Comic, error) { return comics, nil },
+		getFn: func(ctx context.Context, ids []int) ([]Comic, error) {
+			return []Comic{{ID: 3, URL: "u3", Words: []string{"baz"}}}, nil
+		},
+	}
+	svc := newTestService(t, db, &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return []string{phrase}, nil
+	}})
+
+	// индекс ещё не строился
+	st, err := svc.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, IndexStats{}, st)
+
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	st, err = svc.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, st.Comics)
+	assert.Equal(t, 2, st.Words)
+	assert.Equal(t, 3, st.Postings)
+	assert.Equal(t, uint64(1), st.Generation)
+	assert.False(t, st.LastRebuild.IsZero())
+	assert.Empty(t, st.LastError)
+
+	require.NoError(t, svc.UpdateIndex(context.Background(), IndexChanges{Added: []int{3}, Removed: []int{2}}))
+	st, err = svc.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, st.Comics)
+	assert.Equal(t, 3, st.Words)
+	assert.Equal(t, 3, st.Postings)
+	assert.Equal(t, uint64(2), st.Generation)
+
+	// выдача помечена поколением, по которому посчитана
+	res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: "baz", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), res.Generation)
+}
+
+func TestServiceStats_LastError(t *testing.T) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) { return nil, assert.AnError }}
+	svc := newTestService(t, db, &mockWords{})
+
+	require.Error(t, svc.RebuildIndex(context.Background()))
+	st, err := svc.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, assert.AnError.Error(), st.LastError)
+	assert.False(t, st.LastErrorAt.IsZero())
+	assert.Zero(t, st.Generation)
+
+	// успешная перестройка ошибку сбрасывает
+	db.scanFn = func(ctx context.Context) ([]Comic, error) { return nil, nil }
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+	st, err = svc.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, st.LastError)
+	assert.True(t, st.LastErrorAt.IsZero())
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+)
+
+// prefixIndex - отсортированный словарь индекса для подсказок по префиксу
+type prefixIndex struct {
+	terms []string
+}
+
+func newPrefixIndex(ix *invertedIndex) *prefixIndex {
+	p := &prefixIndex{terms: make([]string, 0, ix.words())}
+	for term := range ix.terms() {
+		p.terms = append(p.terms, term)
+	}
+	slices.Sort(p.terms)
+	return p
+}
+
+func (p *prefixIndex) insert(term string) {
+	if i, found := slices.BinarySearch(p.terms, term); !found {
+		p.terms = slices.Insert(p.terms, i, term)
+	}
+}
+
+func (p *prefixIndex) remove(term string) {
+	if i, found := slices.BinarySearch(p.terms, term); found {
+		p.terms = slices.Delete(p.terms, i, i+1)
+	}
+}
+
+// complete - слова с заданным префиксом, самые частые в индексе первыми
+func (p *prefixIndex) complete(ix *invertedIndex, prefix string, limit int) []Suggestion {
+	from, _ := slices.BinarySearch(p.terms, prefix)
+	to := from
+	for to < len(p.terms) && strings.HasPrefix(p.terms[to], prefix) {
+		to++
+	}
+
+	res := make([]Suggestion, 0, to-from)
+	for _, term := range p.terms[from:to] {
+		res = append(res, Suggestion{Word: term, Count: ix.df(term)})
+	}
+	slices.SortStableFunc(res, func(a, b Suggestion) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+// Suggest подсказывает слова индекса по началу слова. Слова возвращаются
+// в нормализованном виде, как они лежат в индексе, и годятся для поиска.
+func (s *Service) Suggest(_ context.Context, prefix string, limit int) ([]Suggestion, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" || limit <= 0 {
+		return nil, ErrBadArguments
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := s.prefixes.complete(s.index, prefix, limit)
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return res, nil
+}
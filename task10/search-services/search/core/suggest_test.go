@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceSuggest(t *testing.T) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		return []Comic{
+			{ID: 1, URL: "u1", Words: []string{"rapid", "raptor"}},
+			{ID: 2, URL: "u2", Words: []string{"raptor", "rain"}},
+			{ID: 3, URL: "u3", Words: []string{"raptor", "rapid", "ra"}},
+			{ID: 4, URL: "u4", Words: []string{"rope"}},
+		}, nil
+	}}
+	svc := newTestService(t, db, &mockWords{})
+
+	// до построения индекса подсказок нет
+	res, err := svc.Suggest(context.Background(), "rap", 10)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	res, err = svc.Suggest(context.Background(), " Rap", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Suggestion{
+		{Word: "raptor", Count: 3},
+		{Word: "rapid", Count: 2},
+	}, res)
+
+	// при равной частоте - по алфавиту
+	res, err = svc.Suggest(context.Background(), "ra", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []Suggestion{
+		{Word: "raptor", Count: 3},
+		{Word: "rapid", Count: 2},
+		{Word: "ra", Count: 1},
+	}, res)
+
+	res, err = svc.Suggest(context.Background(), "x", 10)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+}
+
+func TestServiceSuggest_BadArguments(t *testing.T) {
+	svc := newTestService(t, &mockDB{}, &mockWords{})
+
+	_, err := svc.Suggest(context.Background(), "  ", 10)
+	require.ErrorIs(t, err, ErrBadArguments)
+
+	_, err = svc.Suggest(context.Background(), "rap", 0)
+	require.ErrorIs(t, err, ErrBadArguments)
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Формат словаря синонимов: строка - группа равнозначных слов или словосочетаний
+// через запятую, пустые строки и всё после # пропускаются.
+//
+//	computer, pc
+//	ai, artificial intelligence
+//
+// Найденное в запросе словосочетание дополняется остальными членами группы.
+
+// synonymWeight - во сколько раз совпадение по синониму весит меньше,
+// чем по слову самого запроса
+const synonymWeight = 0.5
+
+// Synonyms - разобранный словарь, исходный текст хранится для просмотра
+type Synonyms struct {
+	text     string
+	groups   [][]string
+	byPhrase map[string][]int // словосочетание -> номера групп
+	maxWords int
+}
+
+// ParseSynonyms разбирает словарь. Пустой текст - пустой словарь.
+func ParseSynonyms(text string) (*Synonyms, error) {
+	d := &Synonyms{text: text, byPhrase: make(map[string][]int)}
+	for n, line := range strings.Split(text, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var group []string
+		for _, phrase := range strings.Split(line, ",") {
+			words := synonymWords(phrase)
+			if len(words) == 0 {
+				return nil, fmt.Errorf("%w: line %d: empty synonym", ErrBadArguments, n+1)
+			}
+			group = append(group, strings.Join(words, " "))
+			d.maxWords = max(d.maxWords, len(words))
+		}
+		if len(group) < 2 {
+			return nil, fmt.Errorf("%w: line %d: group needs at least two synonyms", ErrBadArguments, n+1)
+		}
+		for _, phrase := range group {
+			d.byPhrase[phrase] = append(d.byPhrase[phrase], len(d.groups))
+		}
+		d.groups = append(d.groups, group)
+	}
+	return d, nil
+}
+
+// synonymWords - слова в нижнем регистре без знаков препинания
+func synonymWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+func (d *Synonyms) Text() string {
+	if d == nil {
+		return ""
+	}
+	return d.text
+}
+
+// expansions - синонимы словосочетаний, найденных в тексте термина запроса.
+// Словосочетания ищутся подряд идущими словами, сначала самые длинные.
+func (d *Synonyms) expansions(text string) []string {
+	if d == nil || len(d.groups) == 0 {
+		return nil
+	}
+	words := synonymWords(text)
+	var res []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(words); {
+		matched := 0
+		for n := min(d.maxWords, len(words)-i); n > 0 && matched == 0; n-- {
+			phrase := strings.Join(words[i:i+n], " ")
+			for _, g := range d.byPhrase[phrase] {
+				matched = n
+				for _, syn := range d.groups[g] {
+					if syn != phrase && !seen[syn] {
+						seen[syn] = true
+						res = append(res, syn)
+					}
+				}
+			}
+		}
+		i += max(matched, 1)
+	}
+	return res
+}
+
+// expandSynonyms дополняет слова запроса синонимами, нормализованными
+// через words сервис. Синоним из нескольких слов находит комикс со всеми его словами.
+func expandSynonyms(ctx context.Context, words Words, d *Synonyms, node queryNode) error {
+	switch n := node.(type) {
+	case *termNode:
+		// фразы в кавычках ищутся как написаны
+		if n.phrase {
+			return nil
+		}
+		for _, syn := range d.expansions(n.text) {
+			stems, err := words.Norm(ctx, syn)
+			if err != nil {
+				return err
+			}
+			if len(stems) > 0 {
+				n.synonyms = append(n.synonyms, stems)
+			}
+		}
+	case *notNode:
+		return expandSynonyms(ctx, words, d, n.child)
+	case *andNode:
+		for _, child := range n.children {
+			if err := expandSynonyms(ctx, words, d, child); err != nil {
+				return err
+			}
+		}
+	case *orNode:
+		for _, child := range n.children {
+			if err := expandSynonyms(ctx, words, d, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Synonyms - текущий словарь синонимов
+func (s *Service) Synonyms(_ context.Context) (string, error) {
+	return s.synonyms.Load().Text(), nil
+}
+
+// ReplaceSynonyms проверяет и сохраняет новый словарь, сразу применяя его
+func (s *Service) ReplaceSynonyms(ctx context.Context, text string) error {
+	d, err := ParseSynonyms(text)
+	if err != nil {
+		return err
+	}
+	if s.synonymStore == nil {
+		return fmt.Errorf("%w: synonym dictionary is not configured", ErrBadArguments)
+	}
+	if err := s.synonymStore.Save(ctx, []byte(text)); err != nil {
+		return err
+	}
+	s.setSynonyms(d)
+	return nil
+}
+
+// ReloadSynonyms перечитывает словарь из хранилища, если он изменился.
+// Словарь с ошибкой не применяется, остаётся прежний.
+func (s *Service) ReloadSynonyms(ctx context.Context) error {
+	if s.synonymStore == nil {
+		return nil
+	}
+	data, err := s.synonymStore.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if string(data) == s.synonyms.Load().Text() {
+		return nil
+	}
+	d, err := ParseSynonyms(string(data))
+	if err != nil {
+		return err
+	}
+	s.setSynonyms(d)
+	return nil
+}
+
+func (s *Service) setSynonyms(d *Synonyms) {
+	// выдача по старому словарю больше не верна, но индекс тот же:
+	// поколение не меняется, курсоры остаются в силе
+	s.mu.Lock()
+	s.synonyms.Store(d)
+	s.cache.invalidate()
+	s.mu.Unlock()
+
+	s.log.Info("synonyms loaded", "groups", len(d.groups))
+}
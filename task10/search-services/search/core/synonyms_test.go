@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSynonyms struct {
+	data    []byte
+	loadErr error
+}
+
+func (m *mockSynonyms) Save(ctx context.Context, data []byte) error {
+	m.data = data
+	return nil
+}
+
+func (m *mockSynonyms) Load(ctx context.Context) ([]byte, error) {
+	return m.data, m.loadErr
+}
+
+func TestParseSynonyms(t *testing.T) {
+	d, err := ParseSynonyms("# железо\nPC, computer\n\nai, Artificial  Intelligence # ии\n")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"pc", "computer"}, {"ai", "artificial intelligence"}}, d.groups)
+	assert.Equal(t, 2, d.maxWords)
+
+	for _, text := range []string{"pc", "pc, , computer", "pc, !!!"} {
+		_, err := ParseSynonyms(text)
+		assert.ErrorIs(t, err, ErrBadArguments, text)
+	}
+
+	d, err = ParseSynonyms("")
+	require.NoError(t, err)
+	assert.Empty(t, d.expansions("pc"))
+}
+
+func TestSynonyms_Expansions(t *testing.T) {
+	d, err := ParseSynonyms("pc, computer\nai, artificial intelligence\nintelligence, mind")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"computer"}, d.expansions("PC"))
+	assert.Equal(t, []string{"artificial intelligence"}, d.expansions("ai"))
+	// самое длинное словосочетание важнее отдельных слов
+	assert.Equal(t, []string{"ai"}, d.expansions("artificial intelligence"))
+	assert.Equal(t, []string{"mind", "pc"}, d.expansions("intelligence computer"))
+	assert.Empty(t, d.expansions("linux"))
+	assert.Empty(t, (*Synonyms)(nil).expansions("pc"))
+}
+
+func TestServiceIndexSearch_Synonyms(t *testing.T) {
+	db := &mockDB{scanFn: func(ctx context.Context) ([]Comic, error) {
+		return []Comic{
+			{ID: 1, URL: "u1", Words: []string{"computer"}},
+			{ID: 2, URL: "u2", Words: []string{"pc"}},
+			{ID: 3, URL: "u3", Words: []string{"artificial", "intelligence"}},
+			{ID: 4, URL: "u4", Words: []string{"artificial"}},
+		}, nil
+	}}
+	words := &mockWords{normFn: func(ctx context.Context, phrase string) ([]string, error) {
+		return strings.Fields(strings.ToLower(phrase)), nil
+	}}
+	store := &mockSynonyms{}
+	svc, err := NewService(newTestLogger(), db, words, testRanking, Shard{}, QueryCache{Size: 10}, nil, store)
+	require.NoError(t, err)
+	require.NoError(t, svc.RebuildIndex(context.Background()))
+
+	ids := func(phrase string) []int {
+		res, err := svc.IndexSearch(context.Background(), SearchQuery{Phrase: phrase, Limit: 10})
+		require.NoError(t, err)
+		var ids []int
+		for _, c := range res.Comics {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int{2}, ids("pc"))
+	assert.Equal(t, []int(nil), ids("ai"))
+
+	require.NoError(t, svc.ReplaceSynonyms(context.Background(), "pc, computer\nai, artificial intelligence"))
+	assert.Equal(t, "pc, computer\nai, artificial intelligence", string(store.data))
+
+	// кэш сброшен, точное совпадение выше синонима
+	assert.Equal(t, []int{2, 1}, ids("pc"))
+	assert.Equal(t, []int{1, 2}, ids("computer"))
+	// синоним из нескольких слов требует их все
+	assert.Equal(t, []int{3}, ids("ai"))
+	// фразы в кавычках не расширяются
+	assert.Equal(t, []int{2}, ids(`"pc"`))
+
+	// кривой словарь не заменяет рабочий
+	require.ErrorIs(t, svc.ReplaceSynonyms(context.Background(), "pc"), ErrBadArguments)
+	text, err := svc.Synonyms(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "pc, computer\nai, artificial intelligence", text)
+}
+
+func TestServiceReloadSynonyms(t *testing.T) {
+	store := &mockSynonyms{data: []byte("car, auto")}
+	svc, err := NewService(newTestLogger(), &mockDB{}, &mockWords{}, testRanking, Shard{}, QueryCache{}, nil, store)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.ReloadSynonyms(context.Background()))
+	text, _ := svc.Synonyms(context.Background())
+	assert.Equal(t, "car, auto", text)
+	// индекс не менялся - курсоры прежних выдач остаются в силе
+	assert.Zero(t, svc.generation)
+
+	// файл поправили с ошибкой - остаётся прежний словарь
+	store.data = []byte("car")
+	require.ErrorIs(t, svc.ReloadSynonyms(context.Background()), ErrBadArguments)
+	text, _ = svc.Synonyms(context.Background())
+	assert.Equal(t, "car, auto", text)
+
+	store.loadErr = assert.AnError
+	require.ErrorIs(t, svc.ReloadSynonyms(context.Background()), assert.AnError)
+
+	// без хранилища словаря нет, заменить его нельзя
+	svc = newTestService(t, &mockDB{}, &mockWords{})
+	require.NoError(t, svc.ReloadSynonyms(context.Background()))
+	require.ErrorIs(t, svc.ReplaceSynonyms(context.Background(), "pc, computer"), ErrBadArguments)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -17,6 +18,8 @@ import (
 	"yadro.com/course/search/adapters/events"
 	searchgrpc "yadro.com/course/search/adapters/grpc"
 	"yadro.com/course/search/adapters/indexer"
+	"yadro.com/course/search/adapters/snapshot"
+	"yadro.com/course/search/adapters/synonyms"
 	"yadro.com/course/search/adapters/words"
 	"yadro.com/course/search/config"
 	"yadro.com/course/search/core"
@@ -51,7 +54,7 @@ func run() error {
 	}()
 
 	// words
-	wordsClient, err := words.NewClient(cfg.WordsAddress, log)
+	wordsClient, err := words.NewClient(cfg.WordsAddress, cfg.WordsAnalyzer, log)
 	if err != nil {
 		return fmt.Errorf("failed create Words client: %v", err)
 	}
@@ -61,25 +64,30 @@ func run() error {
 		}
 	}()
 
-	// service
-	searchService := core.NewService(log, storage, wordsClient)
-
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// инициатор индекса
-	go indexer.Run(ctx, log, cfg.IndexTTL, searchService)
-
-	// Подписчик на события изменения в бд
-	natsSubscriber, err := events.NewNatsSubscriber(cfg.BrokerAddress, log, searchService)
-	if err != nil {
-		return fmt.Errorf("failed create nats subscriber: %v", err)
-	}
-	defer func() {
-		if err := natsSubscriber.Close(); err != nil {
-			log.Error("failed to close nats subscriber", "error", err)
+	var searcher core.Searcher
+	switch cfg.Backend {
+	case config.BackendPostgres:
+		// каждый экземпляр ищет по всей базе: шард отдал бы чужие комиксы
+		// в выдачу своего шарда, и шлюз показал бы их по нескольку раз
+		if mode := core.ShardMode(cfg.Shard.Mode); mode != "" && mode != core.ShardNone {
+			return fmt.Errorf("sharding is not supported by postgres backend, got shard mode %q", cfg.Shard.Mode)
 		}
-	}()
+		// индекс живёт в базе: прогревать и обновлять по событиям нечего
+		log.Info("using postgres full-text search")
+		searcher = core.NewFTSService(log, storage, storage, wordsClient)
+	case config.BackendMemory:
+		searchService, closeIndex, err := runIndex(ctx, log, cfg, storage, wordsClient)
+		if err != nil {
+			return err
+		}
+		defer closeIndex()
+		searcher = searchService
+	default:
+		return fmt.Errorf("unknown search backend: %q", cfg.Backend)
+	}
 
 	// gRPC server
 	listener, err := net.Listen("tcp", cfg.Address)
@@ -88,7 +96,7 @@ func run() error {
 	}
 
 	s := grpc.NewServer()
-	searchpb.RegisterSearchServer(s, searchgrpc.NewServer(searchService))
+	searchpb.RegisterSearchServer(s, searchgrpc.NewServer(searcher))
 	reflection.Register(s)
 
 	go func() {
@@ -103,6 +111,75 @@ func run() error {
 	return nil
 }
 
+// runIndex поднимает индекс в памяти: загружает снимок, запускает
+// периодическую перестройку и подписку на изменения в базе
+func runIndex(ctx context.Context, log *slog.Logger, cfg config.Config, storage *db.DB, wordsClient *words.Client) (*core.Service, func(), error) {
+	// снимок индекса на диске
+	var snapshots core.SnapshotStore
+	if cfg.SnapshotPath != "" {
+		snapshots = snapshot.NewFile(cfg.SnapshotPath)
+	}
+	// словарь синонимов
+	var synonymStore core.SynonymStore
+	if cfg.Synonyms.Path != "" {
+		synonymStore = synonyms.NewFile(cfg.Synonyms.Path)
+	}
+
+	// service
+	searchService, err := core.NewService(log, storage, wordsClient, core.Ranking{
+		Mode: core.RankingMode(cfg.Ranking.Mode),
+		K1:   cfg.Ranking.K1,
+		B:    cfg.Ranking.B,
+		Fields: core.FieldBoosts{
+			Title:      cfg.Ranking.Fields.Title,
+			Alt:        cfg.Ranking.Fields.Alt,
+			Transcript: cfg.Ranking.Fields.Transcript,
+		},
+	}, core.Shard{
+		Mode:  core.ShardMode(cfg.Shard.Mode),
+		Index: cfg.Shard.Index,
+		Count: cfg.Shard.Count,
+		From:  cfg.Shard.From,
+		To:    cfg.Shard.To,
+	}, core.QueryCache{
+		Size: cfg.Cache.Size,
+		TTL:  cfg.Cache.TTL,
+	}, snapshots, synonymStore)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed create Search service: %v", err)
+	}
+
+	// отвечаем по снимку сразу, с базой индекс сверит indexer в фоне
+	switch err := searchService.LoadSnapshot(context.Background()); {
+	case errors.Is(err, core.ErrSnapshotNotFound):
+		log.Info("no index snapshot yet, waiting for rebuild")
+	case err != nil:
+		log.Error("failed to load index snapshot", "error", err)
+	}
+
+	// кривой словарь не мешает запуску, поиск работает без синонимов
+	if err := searchService.ReloadSynonyms(ctx); err != nil {
+		log.Error("failed to load synonyms", "error", err)
+	}
+	if synonymStore != nil {
+		go synonyms.Watch(ctx, log, cfg.Synonyms.ReloadInterval, searchService)
+	}
+
+	// инициатор индекса
+	go indexer.Run(ctx, log, cfg.IndexTTL, searchService)
+
+	// Подписчик на события изменения в бд
+	natsSubscriber, err := events.NewNatsSubscriber(cfg.BrokerAddress, log, searchService)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed create nats subscriber: %v", err)
+	}
+	return searchService, func() {
+		if err := natsSubscriber.Close(); err != nil {
+			log.Error("failed to close nats subscriber", "error", err)
+		}
+	}, nil
+}
+
 func mustMakeLogger(logLevel string) *slog.Logger {
 	var level slog.Level
 	switch logLevel {
@@ -12,6 +12,8 @@ import (
 	"yadro.com/course/telegramBot/adapters/rest"
 )
 
+const maxCaption = 1024
+
 // Признаю, сильно сделано
 const (
 	btnSearch = "🔎 Search"
@@ -259,7 +261,24 @@ func (b *Bot) handleSearch(msg *tgbotapi.Message, args string) {
 
 	// Потом картинки
 	for i, c := range res.Comics {
-		caption := fmt.Sprintf("#%d (%d/%d)\n%s", c.ID, i+1, len(res.Comics), phrase)
+		// карточка: номер, название и дата, ниже подпись автора;
+		// у старых записей без сведений - сам запрос
+		head := fmt.Sprintf("#%d", c.ID)
+		if c.Title != "" {
+			head += " " + c.Title
+		}
+		if c.Published != "" {
+			head += " [" + c.Published + "]"
+		}
+		text := c.Alt
+		if text == "" {
+			text = phrase
+		}
+		caption := fmt.Sprintf("%s (%d/%d)\n%s", head, i+1, len(res.Comics), text)
+		// длиннее телеграм подпись не примет
+		if r := []rune(caption); len(r) > maxCaption {
+			caption = string(r[:maxCaption-1]) + "…"
+		}
 
 		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(c.URL))
 		photo.Caption = caption
@@ -1,8 +1,11 @@
 package core
 
 type SearchComic struct {
-	ID  int    `json:"id"`
-	URL string `json:"url"`
+	ID        int    `json:"id"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Alt       string `json:"alt"`
+	Published string `json:"published"`
 }
 
 type SearchResponse struct {
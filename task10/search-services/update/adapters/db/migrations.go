@@ -11,6 +11,9 @@ import (
 //go:embed migrations/*.sql
 var migrationFiles embed.FS
 
+// Migrate накатывает схему общей базы. Update владеет всей схемой, в том числе
+// таблицами других сервисов: search читает comics, api пишет query_log.
+// Новые таблицы любого сервиса добавляются миграциями сюда, одной нумерацией.
 func (db *DB) Migrate() error {
 	db.log.Debug("running migration")
 	files, err := iofs.New(migrationFiles, "migrations") // get migrations from
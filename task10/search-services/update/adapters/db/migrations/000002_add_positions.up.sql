@@ -0,0 +1 @@
+ALTER TABLE comics ADD COLUMN positions JSONB;
\ No newline at end of file
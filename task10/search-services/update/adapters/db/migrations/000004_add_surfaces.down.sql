@@ -0,0 +1 @@
+ALTER TABLE comics DROP COLUMN IF EXISTS surfaces;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE comics ADD COLUMN surfaces JSONB;
\ No newline at end of file
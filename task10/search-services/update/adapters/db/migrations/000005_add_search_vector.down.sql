@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS comics_search_vector_idx;
+DROP TRIGGER IF EXISTS comics_search_vector ON comics;
+DROP FUNCTION IF EXISTS comics_search_vector_update();
+DROP FUNCTION IF EXISTS comics_search_vector(TEXT[], JSONB);
+ALTER TABLE comics DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
@@ -0,0 +1,31 @@
+ALTER TABLE comics ADD COLUMN search_vector tsvector;
+
+-- вектор из нормализованных слов: поиск ранжирует по ним так же, как
+-- индекс в памяти. Слово повторяется столько раз, сколько встречается
+-- в комиксе, чтобы ts_rank учитывал частоту; у старых записей без
+-- позиций каждое слово считается один раз.
+CREATE FUNCTION comics_search_vector(words TEXT[], positions JSONB) RETURNS tsvector AS $$
+    SELECT to_tsvector('simple', coalesce(
+        CASE WHEN jsonb_typeof(positions) = 'object' THEN
+            (SELECT string_agg(repeat(key || ' ', greatest(jsonb_array_length(value), 1)), '')
+             FROM jsonb_each(positions)
+             WHERE jsonb_typeof(value) = 'array')
+        END,
+        array_to_string(words, ' '),
+        ''))
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE FUNCTION comics_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := comics_search_vector(NEW.words, NEW.positions);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER comics_search_vector
+    BEFORE INSERT OR UPDATE OF words, positions ON comics
+    FOR EACH ROW EXECUTE FUNCTION comics_search_vector_update();
+
+UPDATE comics SET search_vector = comics_search_vector(words, positions);
+
+CREATE INDEX comics_search_vector_idx ON comics USING GIN (search_vector);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE comics
+    ADD COLUMN title_words TEXT[],
+    ADD COLUMN alt_words TEXT[],
+    ADD COLUMN transcript_words TEXT[];
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS query_log;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- журнал поисковых запросов для аналитики, пишет api.
+-- query - ключ группировки: нормализованные слова через пробел,
+-- а если нормализатор ничего не оставил - сам запрос в нижнем регистре.
+CREATE TABLE IF NOT EXISTS query_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    mode TEXT NOT NULL,
+    phrase TEXT NOT NULL,
+    query TEXT NOT NULL,
+    terms TEXT[] NOT NULL,
+    hits INT NOT NULL,
+    latency_ms DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS query_log_created_at_idx ON query_log (created_at);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE comics DROP COLUMN IF EXISTS words_version;
\ No newline at end of file
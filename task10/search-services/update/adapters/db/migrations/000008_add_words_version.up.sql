@@ -0,0 +1 @@
+ALTER TABLE comics ADD COLUMN words_version TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION comics_search_vector(words TEXT[], positions JSONB) RETURNS tsvector AS $$
+    SELECT to_tsvector('simple', coalesce(
+        CASE WHEN jsonb_typeof(positions) = 'object' THEN
+            (SELECT string_agg(repeat(key || ' ', greatest(jsonb_array_length(value), 1)), '')
+             FROM jsonb_each(positions)
+             WHERE jsonb_typeof(value) = 'array')
+        END,
+        array_to_string(words, ' '),
+        ''))
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE comics SET search_vector = comics_search_vector(words, positions);
\ No newline at end of file
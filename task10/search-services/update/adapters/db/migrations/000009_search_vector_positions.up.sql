@@ -0,0 +1,21 @@
+-- Вектор из настоящих позиций слов в тексте комикса: фраза в tsquery
+-- (<N>) должна совпадать только со словами, стоящими рядом в тексте.
+-- Позиции в tsvector начинаются с 1, больше 16383 база приводит к 16383.
+CREATE OR REPLACE FUNCTION comics_search_vector(words TEXT[], positions JSONB) RETURNS tsvector AS $$
+    SELECT CASE WHEN jsonb_typeof(positions) = 'object' THEN
+        coalesce((
+            SELECT string_agg(
+                '''' || replace(replace(key, '\', '\\'), '''', '''''') || '''' ||
+                coalesce(':' || (SELECT string_agg((least(p::int, 16382) + 1)::text, ',')
+                                 FROM jsonb_array_elements_text(value) AS p), ''),
+                ' ')
+            FROM jsonb_each(positions)
+            WHERE jsonb_typeof(value) = 'array'
+        ), '')::tsvector
+    ELSE
+        -- у старых записей без позиций слова без мест, фразы по ним не ищутся
+        array_to_tsvector(coalesce(words, '{}'))
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE comics SET search_vector = comics_search_vector(words, positions);
\ No newline at end of file
@@ -3,7 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"encoding/json"
 	"log/slog"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -31,15 +31,124 @@ func New(log *slog.Logger, address string) (*DB, error) {
 }
 
 func (db *DB) Add(ctx context.Context, comics core.Comics) error {
-	_, err := db.conn.ExecContext(
+	positions, err := json.Marshal(comics.Positions)
+	if err != nil {
+		return err
+	}
+
+	surfaces, err := json.Marshal(comics.Surfaces)
+	if err != nil {
+		return err
+	}
+
+	meta, fields := comics.Meta, comics.Fields
+	_, err = db.conn.ExecContext(
+		ctx,
+		`INSERT INTO comics (id, url, words, positions, surfaces, safe_title, title, alt, transcript, published, link, news,
+		title_words, alt_words, transcript_words, words_version)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		comics.ID, comics.URL, comics.Words, positions, surfaces,
+		meta.SafeTitle, comics.Title, meta.Alt, meta.Transcript,
+		sql.NullTime{Time: meta.Published, Valid: !meta.Published.IsZero()},
+		meta.Link, meta.News,
+		fields.Title, fields.Alt, fields.Transcript, comics.WordsVersion,
+	)
+
+	return err
+}
+
+// UpdateWords трогает только слова: текст и метаданные остаются как были
+func (db *DB) UpdateWords(ctx context.Context, comics core.Comics) error {
+	positions, err := json.Marshal(comics.Positions)
+	if err != nil {
+		return err
+	}
+
+	surfaces, err := json.Marshal(comics.Surfaces)
+	if err != nil {
+		return err
+	}
+
+	fields := comics.Fields
+	_, err = db.conn.ExecContext(
 		ctx,
-		"INSERT INTO comics (id, url, words) VALUES($1, $2, $3)",
-		comics.ID, comics.URL, comics.Words,
+		`UPDATE comics SET words = $2, positions = $3, surfaces = $4,
+		title_words = $5, alt_words = $6, transcript_words = $7, words_version = $8
+		WHERE id = $1`,
+		comics.ID, comics.Words, positions, surfaces,
+		fields.Title, fields.Alt, fields.Transcript, comics.WordsVersion,
 	)
+	return err
+}
+
+// Replace переписывает и текст с метаданными, и слова
+func (db *DB) Replace(ctx context.Context, comics core.Comics) error {
+	positions, err := json.Marshal(comics.Positions)
+	if err != nil {
+		return err
+	}
+
+	surfaces, err := json.Marshal(comics.Surfaces)
+	if err != nil {
+		return err
+	}
 
+	meta, fields := comics.Meta, comics.Fields
+	_, err = db.conn.ExecContext(
+		ctx,
+		`UPDATE comics SET url = $2, words = $3, positions = $4, surfaces = $5,
+		safe_title = $6, title = $7, alt = $8, transcript = $9, published = $10, link = $11, news = $12,
+		title_words = $13, alt_words = $14, transcript_words = $15, words_version = $16
+		WHERE id = $1`,
+		comics.ID, comics.URL, comics.Words, positions, surfaces,
+		meta.SafeTitle, comics.Title, meta.Alt, meta.Transcript,
+		sql.NullTime{Time: meta.Published, Valid: !meta.Published.IsZero()},
+		meta.Link, meta.News,
+		fields.Title, fields.Alt, fields.Transcript, comics.WordsVersion,
+	)
 	return err
 }
 
+type sourceRow struct {
+	ID         int          `db:"id"`
+	URL        string       `db:"url"`
+	SafeTitle  string       `db:"safe_title"`
+	Title      string       `db:"title"`
+	Alt        string       `db:"alt"`
+	Transcript string       `db:"transcript"`
+	Published  sql.NullTime `db:"published"`
+	Link       string       `db:"link"`
+	News       string       `db:"news"`
+}
+
+func (db *DB) Source(ctx context.Context, id int) (core.XKCDInfo, error) {
+	var row sourceRow
+	err := db.conn.GetContext(
+		ctx, &row,
+		`SELECT id, url, safe_title, title, alt, transcript, published, link, news
+		FROM comics WHERE id = $1`, id,
+	)
+	if err != nil {
+		return core.XKCDInfo{}, err
+	}
+
+	meta := core.ComicMeta{
+		SafeTitle:  row.SafeTitle,
+		Alt:        row.Alt,
+		Transcript: row.Transcript,
+		Published:  row.Published.Time,
+		Link:       row.Link,
+		News:       row.News,
+	}
+	return core.XKCDInfo{
+		ID:          row.ID,
+		URL:         row.URL,
+		Title:       row.Title,
+		Description: meta.Description(),
+		Meta:        meta,
+	}, nil
+}
+
 func (db *DB) Stats(ctx context.Context) (core.DBStats, error) {
 	var stats core.DBStats
 	err := db.conn.GetContext(
@@ -66,19 +175,32 @@ func (db *DB) Stats(ctx context.Context) (core.DBStats, error) {
 	return stats, nil
 }
 
-func (db *DB) IDs(ctx context.Context) ([]int, error) {
-	var IDs []int
-	err := db.conn.SelectContext(
-		ctx, &IDs,
-		"SELECT id FROM comics")
+// ScanIDs читает строки по мере прихода от сервера, весь список id не копится
+func (db *DB) ScanIDs(ctx context.Context, fn func(id int) error) error {
+	return db.scanIDs(ctx, fn, "SELECT id FROM comics")
+}
+
+func (db *DB) ScanStaleIDs(ctx context.Context, version string, fn func(id int) error) error {
+	return db.scanIDs(ctx, fn, "SELECT id FROM comics WHERE words_version <> $1", version)
+}
+
+func (db *DB) scanIDs(ctx context.Context, fn func(id int) error, query string, args ...any) error {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
+		return err
 	}
+	defer func() { _ = rows.Close() }()
 
-	return IDs, nil
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 func (db *DB) Drop(ctx context.Context) error {
@@ -2,13 +2,23 @@ package events
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 
 	"github.com/nats-io/nats.go"
+	"yadro.com/course/update/core"
 )
 
 const subjectDBUpdated = "xkcd.db.updated" // название топика, в который будем публиковать
 
+// dbUpdatedEvent - тело сообщения, search разбирает его такой же структурой
+type dbUpdatedEvent struct {
+	Added   []int `json:"added,omitempty"`
+	Changed []int `json:"changed,omitempty"`
+	Removed []int `json:"removed,omitempty"`
+	Dropped bool  `json:"dropped,omitempty"`
+}
+
 type NatsPublisher struct {
 	log *slog.Logger
 	nc  *nats.Conn
@@ -27,17 +37,26 @@ func NewNatsPublisher(address string, log *slog.Logger) (*NatsPublisher, error)
 	}, nil
 }
 
-func (p *NatsPublisher) NotifyDBChanged(ctx context.Context) error {
+func (p *NatsPublisher) NotifyDBChanged(ctx context.Context, changes core.DBChanges) error {
 
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	err := p.nc.Publish(subjectDBUpdated, []byte("XKCD DB has been updated"))
+	data, err := json.Marshal(dbUpdatedEvent{
+		Added:   changes.Added,
+		Changed: changes.Changed,
+		Removed: changes.Removed,
+		Dropped: changes.Dropped,
+	})
 	if err != nil {
 		return err
 	}
 
+	if err := p.nc.Publish(subjectDBUpdated, data); err != nil {
+		return err
+	}
+
 	return p.nc.Flush()
 }
 
@@ -2,23 +2,60 @@ package words
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	wordspb "yadro.com/course/proto/words"
+	"yadro.com/course/update/core"
 )
 
+// Client собирает фразы от всех воркеров обновления в пачки NormBatch:
+// пачка уходит, когда набралось batchSize фраз или прошло batchDelay
+// с первой из них. Фразы одного вызова всегда попадают в одну пачку.
+// Пачек в полёте может быть несколько.
 type Client struct {
 	log    *slog.Logger
 	client wordspb.WordsClient
 	conn   *grpc.ClientConn
+	calls  chan call
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	analyzer string
 }
 
-func NewClient(address string, log *slog.Logger) (*Client, error) {
+const (
+	batchSize    = 32 // столько принимает words за раз
+	batchDelay   = 5 * time.Millisecond
+	batchTimeout = 30 * time.Second
+)
+
+// call - фразы одного воркера, ответ приходит в res
+type call struct {
+	phrases []string
+	res     chan result
+}
+
+type result struct {
+	tokens [][]core.Token
+	err    error
+}
+
+// language - комиксы и запросы нормализуются одинаково:
+// язык выбирается по алфавиту каждого слова.
+// Анализатор тоже должен совпадать у update и search, пустой - default.
+const language = "auto"
+
+func NewClient(address, analyzer string, log *slog.Logger) (*Client, error) {
 
 	conn, err := grpc.NewClient(
 		address,
@@ -38,21 +75,143 @@ func NewClient(address string, log *slog.Logger) (*Client, error) {
 
 	conn.Connect()
 
-	return &Client{
-		client: wordspb.NewWordsClient(conn),
-		conn:   conn,
-		log:    log,
-	}, nil
+	return newClient(wordspb.NewWordsClient(conn), conn, analyzer, log), nil
 
 }
 
-func (c *Client) Norm(ctx context.Context, phrase string) ([]string, error) {
+func newClient(client wordspb.WordsClient, conn *grpc.ClientConn, analyzer string, log *slog.Logger) *Client {
+	c := &Client{
+		client:   client,
+		conn:     conn,
+		log:      log,
+		calls:    make(chan call),
+		done:     make(chan struct{}),
+		analyzer: analyzer,
+	}
+	c.wg.Go(c.batch)
+	return c
+}
 
-	resp, err := c.client.Norm(ctx, &wordspb.WordsRequest{Phrase: phrase})
+func (c *Client) Version(ctx context.Context) (string, error) {
+	resp, err := c.client.Info(ctx, &emptypb.Empty{})
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	return resp.GetDictionaryVersion(), nil
+}
+
+func (c *Client) NormBatch(ctx context.Context, phrases []string) ([][]core.Token, error) {
+	if len(phrases) > batchSize {
+		return nil, fmt.Errorf("words batch: %d phrases, at most %d", len(phrases), batchSize)
+	}
+	cl := call{phrases: phrases, res: make(chan result, 1)}
+	select {
+	case c.calls <- cl:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, errClosed
+	}
+	select {
+	case r := <-cl.res:
+		return r.tokens, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+var errClosed = errors.New("words client is closed")
+
+// batch копит фразы в пачки и отправляет каждую в отдельной горутине.
+// Вызов, который не влез в пачку, открывает следующую.
+func (c *Client) batch() {
+	var next *call
+	for {
+		var first call
+		if next != nil {
+			first, next = *next, nil
+		} else {
+			select {
+			case first = <-c.calls:
+			case <-c.done:
+				return
+			}
+		}
+
+		calls := []call{first}
+		size := len(first.phrases)
+		timer := time.NewTimer(batchDelay)
+	collect:
+		for size < batchSize {
+			select {
+			case cl := <-c.calls:
+				if size+len(cl.phrases) > batchSize {
+					next = &cl
+					break collect
+				}
+				calls = append(calls, cl)
+				size += len(cl.phrases)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		c.wg.Go(func() { c.send(calls, size) })
+	}
+}
+
+// send - одна пачка из size фраз. У каждого вызова свой ответ: если
+// слишком длинная фраза получила ошибку, её получает весь вызов,
+// остальные вызовы нормализуются как обычно.
+func (c *Client) send(calls []call, size int) {
+	ctx, cancel := context.WithTimeout(context.Background(), batchTimeout)
+	defer cancel()
+
+	req := &wordspb.WordsBatchRequest{Items: make([]*wordspb.WordsRequest, 0, size)}
+	for _, cl := range calls {
+		for _, phrase := range cl.phrases {
+			req.Items = append(req.Items, &wordspb.WordsRequest{Phrase: phrase, Language: language, Analyzer: c.analyzer, Positions: true})
+		}
+	}
+
+	resp, err := c.client.NormBatch(ctx, req)
+	if err == nil && len(resp.GetItems()) != size {
+		err = fmt.Errorf("words batch: %d replies for %d phrases", len(resp.GetItems()), size)
+	}
+	if err != nil {
+		c.log.Error("words batch failed", "size", size, "error", err)
+		for _, cl := range calls {
+			cl.res <- result{err: err}
+		}
+		return
+	}
+
+	items := resp.GetItems()
+	for _, cl := range calls {
+		var res result
+		for _, item := range items[:len(cl.phrases)] {
+			if code := codes.Code(item.GetCode()); code != codes.OK {
+				res = result{err: status.Error(code, item.GetError())}
+				break
+			}
+			res.tokens = append(res.tokens, tokens(item.GetReply()))
+		}
+		items = items[len(cl.phrases):]
+		cl.res <- res
+	}
+}
+
+func tokens(resp *wordspb.WordsReply) []core.Token {
+	tokens := make([]core.Token, 0, len(resp.GetTokens()))
+	for _, t := range resp.GetTokens() {
+		tokens = append(tokens, core.Token{
+			Word:     t.GetWord(),
+			Pos:      int(t.GetPosition()),
+			Original: t.GetOriginal(),
+		})
 	}
-	return resp.GetWords(), nil
+	return tokens
 }
 
 func (c *Client) Ping(ctx context.Context) error {
@@ -60,6 +219,9 @@ func (c *Client) Ping(ctx context.Context) error {
 	return err
 }
 
+// Close дожидается отправленных пачек и закрывает соединение
 func (c *Client) Close() error {
+	close(c.done)
+	c.wg.Wait()
 	return c.conn.Close()
 }
@@ -0,0 +1,208 @@
+package words
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	wordspb "yadro.com/course/proto/words"
+	"yadro.com/course/update/core"
+)
+
+type mockWordsClient struct {
+	wordspb.WordsClient
+	mu          sync.Mutex
+	batches     [][]string
+	normBatchFn func(req *wordspb.WordsBatchRequest) (*wordspb.WordsBatchReply, error)
+	infoFn      func() (*wordspb.InfoReply, error)
+}
+
+func (m *mockWordsClient) Info(_ context.Context, _ *emptypb.Empty, _ ...grpc.CallOption) (*wordspb.InfoReply, error) {
+	return m.infoFn()
+}
+
+func (m *mockWordsClient) NormBatch(_ context.Context, req *wordspb.WordsBatchRequest, _ ...grpc.CallOption) (*wordspb.WordsBatchReply, error) {
+	m.mu.Lock()
+	var phrases []string
+	for _, item := range req.GetItems() {
+		phrases = append(phrases, item.GetPhrase())
+	}
+	m.batches = append(m.batches, phrases)
+	m.mu.Unlock()
+	return m.normBatchFn(req)
+}
+
+func newTestClient(t *testing.T, m *mockWordsClient) *Client {
+	c := newClient(m, nil, "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() {
+		close(c.done)
+		c.wg.Wait()
+	})
+	return c
+}
+
+// echo отвечает словами фразы, слишком длинные фразы - ошибкой, как words
+func echo(req *wordspb.WordsBatchRequest) (*wordspb.WordsBatchReply, error) {
+	reply := &wordspb.WordsBatchReply{}
+	for _, item := range req.GetItems() {
+		if len(item.GetPhrase()) > 10 {
+			reply.Items = append(reply.Items, &wordspb.WordsItem{Code: int32(codes.ResourceExhausted), Error: "phrase too large"})
+			continue
+		}
+		words := &wordspb.WordsReply{}
+		for i, w := range strings.Fields(item.GetPhrase()) {
+			words.Tokens = append(words.Tokens, &wordspb.Token{Word: w, Position: int32(i), Original: w})
+		}
+		reply.Items = append(reply.Items, &wordspb.WordsItem{Reply: words})
+	}
+	return reply, nil
+}
+
+// normOne нормализует одну фразу
+func normOne(ctx context.Context, c *Client, phrase string) ([]core.Token, error) {
+	res, err := c.NormBatch(ctx, []string{phrase})
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+func TestClientNorm_BatchesAcrossCallers(t *testing.T) {
+	m := &mockWordsClient{normBatchFn: echo}
+	c := newTestClient(t, m)
+
+	phrases := []string{"linux", "cpu fan", strings.Repeat("x", 20), "kernel"}
+	res := make([][]core.Token, len(phrases))
+	errs := make([]error, len(phrases))
+	var wg sync.WaitGroup
+	for i, phrase := range phrases {
+		wg.Go(func() {
+			res[i], errs[i] = normOne(context.Background(), c, phrase)
+		})
+	}
+	wg.Wait()
+
+	// длинная фраза получила свою ошибку, остальные - свои слова
+	assert.Equal(t, codes.ResourceExhausted, status.Code(errs[2]))
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	require.NoError(t, errs[3])
+	assert.Equal(t, []core.Token{{Word: "linux", Original: "linux"}}, res[0])
+	assert.Equal(t, []core.Token{{Word: "cpu", Original: "cpu"}, {Word: "fan", Pos: 1, Original: "fan"}}, res[1])
+	assert.Equal(t, []core.Token{{Word: "kernel", Original: "kernel"}}, res[3])
+
+	// каждая фраза ушла ровно в одной пачке
+	items := 0
+	for _, b := range m.batches {
+		items += len(b)
+	}
+	assert.Equal(t, len(phrases), items)
+}
+
+func TestClientNormBatch_KeepsCallTogether(t *testing.T) {
+	m := &mockWordsClient{normBatchFn: echo}
+	c := newTestClient(t, m)
+
+	// две почти полные пачки: вторая целиком уходит в следующую
+	first := make([]string, batchSize-1)
+	second := make([]string, 2)
+	for i := range first {
+		first[i] = "a"
+	}
+	for i := range second {
+		second[i] = "b"
+	}
+	var wg sync.WaitGroup
+	var res [][]core.Token
+	var err error
+	wg.Go(func() {
+		_, err := c.NormBatch(context.Background(), first)
+		assert.NoError(t, err)
+	})
+	wg.Go(func() {
+		res, err = c.NormBatch(context.Background(), second)
+	})
+	wg.Wait()
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]core.Token{{{Word: "b", Original: "b"}}, {{Word: "b", Original: "b"}}}, res)
+	for _, b := range m.batches {
+		assert.LessOrEqual(t, len(b), batchSize)
+		if slices.Contains(b, "b") {
+			assert.Equal(t, []string{"b", "b"}, b)
+		}
+	}
+
+	// ошибка одной фразы - ошибка всего вызова
+	_, err = c.NormBatch(context.Background(), []string{"linux", strings.Repeat("x", 20)})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = c.NormBatch(context.Background(), make([]string, batchSize+1))
+	assert.Error(t, err)
+}
+
+func TestClientNorm_BatchFailure(t *testing.T) {
+	m := &mockWordsClient{normBatchFn: func(req *wordspb.WordsBatchRequest) (*wordspb.WordsBatchReply, error) {
+		return nil, status.Error(codes.Unavailable, "words is down")
+	}}
+	c := newTestClient(t, m)
+
+	_, err := normOne(context.Background(), c, "linux")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// ответов меньше, чем фраз - ошибка, а не чужие слова
+	m.normBatchFn = func(req *wordspb.WordsBatchRequest) (*wordspb.WordsBatchReply, error) {
+		return &wordspb.WordsBatchReply{}, nil
+	}
+	_, err = normOne(context.Background(), c, "linux")
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = normOne(ctx, c, "linux")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClientNorm_Analyzer(t *testing.T) {
+	var got []string
+	m := &mockWordsClient{normBatchFn: func(req *wordspb.WordsBatchRequest) (*wordspb.WordsBatchReply, error) {
+		for _, item := range req.GetItems() {
+			got = append(got, item.GetAnalyzer())
+		}
+		return echo(req)
+	}}
+	c := newClient(m, nil, "folded", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() {
+		close(c.done)
+		c.wg.Wait()
+	})
+
+	_, err := normOne(context.Background(), c, "linux")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"folded"}, got)
+}
+
+func TestClientVersion(t *testing.T) {
+	m := &mockWordsClient{infoFn: func() (*wordspb.InfoReply, error) {
+		return &wordspb.InfoReply{DictionaryVersion: "abc", Analyzers: []string{"default"}}, nil
+	}}
+	c := newTestClient(t, m)
+
+	version, err := c.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "abc", version)
+
+	m.infoFn = func() (*wordspb.InfoReply, error) { return nil, status.Error(codes.Unavailable, "down") }
+	_, err = c.Version(context.Background())
+	assert.Error(t, err)
+}
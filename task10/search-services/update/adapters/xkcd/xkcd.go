@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 
 	"yadro.com/course/update/core"
@@ -37,6 +36,16 @@ type xkcdResp struct {
 	SafeTitle  string `json:"safe_title"`
 	Alt        string `json:"alt"`
 	Transcript string `json:"transcript"`
+	Year       string `json:"year"`
+	Month      string `json:"month"`
+	Day        string `json:"day"`
+	Link       string `json:"link"`
+	News       string `json:"news"`
+}
+
+// published - дата выхода комикса, xkcd отдаёт её строками без ведущих нулей
+func (xr xkcdResp) published() (time.Time, error) {
+	return time.Parse("2006-1-2", xr.Year+"-"+xr.Month+"-"+xr.Day)
 }
 
 func (c Client) Get(ctx context.Context, id int) (core.XKCDInfo, error) {
@@ -63,23 +72,25 @@ func (c Client) Get(ctx context.Context, id int) (core.XKCDInfo, error) {
 		return core.XKCDInfo{}, err
 	}
 
-	parts := []string{}
-	if strings.TrimSpace(xr.SafeTitle) != "" {
-		parts = append(parts, xr.SafeTitle)
-	}
-	if strings.TrimSpace(xr.Transcript) != "" {
-		parts = append(parts, xr.Transcript)
-	}
-	if strings.TrimSpace(xr.Alt) != "" {
-		parts = append(parts, xr.Alt)
+	published, err := xr.published()
+	if err != nil {
+		c.log.Debug("bad publish date", "id", id, "error", err)
 	}
-	desc := strings.Join(parts, " ")
 
+	meta := core.ComicMeta{
+		SafeTitle:  xr.SafeTitle,
+		Alt:        xr.Alt,
+		Transcript: xr.Transcript,
+		Published:  published,
+		Link:       xr.Link,
+		News:       xr.News,
+	}
 	return core.XKCDInfo{
 		ID:          xr.Num,
 		URL:         xr.Img,
 		Title:       xr.Title,
-		Description: desc,
+		Description: meta.Description(),
+		Meta:        meta,
 	}, nil
 }
 
@@ -20,7 +20,10 @@ type Config struct {
 	XKCD          XKCD   `yaml:"xkcd"`
 	DBAddress     string `yaml:"db_address" env:"DB_ADDRESS" env-default:"localhost:82"`
 	WordsAddress  string `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"localhost:81"`
-	BrokerAddress string `yaml:"broker_address" env:"BROKER_ADDRESS" env-default:"nats://localhost:4222"`
+	WordsAnalyzer string `yaml:"words_analyzer" env:"WORDS_ANALYZER"` // пусто - default, как у search
+	// как часто сверять версию словарей words, чтобы нормализовать комиксы заново
+	WordsCheckPeriod time.Duration `yaml:"words_check_period" env:"WORDS_CHECK_PERIOD" env-default:"1m"`
+	BrokerAddress    string        `yaml:"broker_address" env:"BROKER_ADDRESS" env-default:"nats://localhost:4222"`
 }
 
 func MustLoad(configPath string) Config {
@@ -1,5 +1,10 @@
 package core
 
+import (
+	"strings"
+	"time"
+)
+
 type ServiceStatus string
 
 const (
@@ -18,12 +23,64 @@ type ServiceStats struct {
 	ComicsTotal int
 }
 
+// DBChanges - какие комиксы изменились в базе, чтобы поиск обновил только их.
+// Dropped - база очищена целиком.
+type DBChanges struct {
+	Added   []int
+	Changed []int
+	Removed []int
+	Dropped bool
+}
+
 type Comics struct {
 	ID          int
 	URL         string
 	Title       string
 	Description string
 	Words       []string
+	Positions   map[string][]int    // позиции каждого слова из Words в тексте
+	Surfaces    map[string][]string // как слова из Words написаны в тексте, в нижнем регистре
+	Fields      FieldWords
+	Meta        ComicMeta
+	// версия словарей words, при которой нормализованы слова
+	WordsVersion string
+}
+
+// FieldWords - нормализованные слова названия, alt и расшифровки по отдельности,
+// в порядке текста и с повторами, чтобы поиск мог взвешивать совпадения по полям
+type FieldWords struct {
+	Title      []string
+	Alt        []string
+	Transcript []string
+}
+
+// ComicMeta - сведения о комиксе для показа в выдаче
+type ComicMeta struct {
+	SafeTitle  string
+	Alt        string
+	Transcript string
+	Published  time.Time // нулевое время, если дата неизвестна
+	Link       string
+	News       string
+}
+
+// Description - текст комикса для нормализации: название, расшифровка и alt
+func (m ComicMeta) Description() string {
+	parts := []string{}
+	for _, part := range []string{m.SafeTitle, m.Transcript, m.Alt} {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Token - нормализованное слово, его позиция в исходном тексте
+// и само слово, как оно там написано
+type Token struct {
+	Word     string
+	Pos      int
+	Original string
 }
 
 type XKCDInfo struct {
@@ -31,4 +88,5 @@ type XKCDInfo struct {
 	URL         string
 	Title       string
 	Description string
+	Meta        ComicMeta
 }
@@ -15,7 +15,17 @@ type DB interface {
 	Add(context.Context, Comics) error
 	Stats(context.Context) (DBStats, error)
 	Drop(context.Context) error
-	IDs(context.Context) ([]int, error)
+	// ScanIDs по одному передаёт в fn id всех комиксов базы,
+	// ошибка fn прерывает обход и возвращается из ScanIDs
+	ScanIDs(ctx context.Context, fn func(id int) error) error
+	// ScanStaleIDs - то же для комиксов, нормализованных не при этой версии словарей
+	ScanStaleIDs(ctx context.Context, version string, fn func(id int) error) error
+	// Source - сохранённый текст комикса, из которого получаются его слова
+	Source(ctx context.Context, id int) (XKCDInfo, error)
+	// UpdateWords заменяет нормализованные слова комикса и их версию
+	UpdateWords(ctx context.Context, comics Comics) error
+	// Replace заменяет сохранённый комикс целиком: текст, метаданные и слова
+	Replace(ctx context.Context, comics Comics) error
 }
 
 type XKCD interface {
@@ -24,9 +34,12 @@ type XKCD interface {
 }
 
 type Words interface {
-	Norm(ctx context.Context, phrase string) ([]string, error)
+	// NormBatch нормализует фразы одним запросом, ответ на каждую - на том же месте
+	NormBatch(ctx context.Context, phrases []string) ([][]Token, error)
+	// Version - версия словарей words, с её сменой слова комиксов устаревают
+	Version(ctx context.Context) (string, error)
 }
 
 type EventPublisher interface {
-	NotifyDBChanged(ctx context.Context) error
+	NotifyDBChanged(ctx context.Context, changes DBChanges) error
 }
@@ -2,10 +2,14 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Service struct {
@@ -51,7 +55,9 @@ func (s *Service) unlockRun() {
 	s.running.Store(false)
 }
 
-func (s *Service) worker(ctx context.Context, jobs <-chan int) {
+// worker скачивает и сохраняет комиксы, возвращает id сохранённых
+func (s *Service) worker(ctx context.Context, jobs <-chan int, version string) []int {
+	var added []int
 	for id := range jobs {
 		info, err := s.xkcd.Get(ctx, id)
 		if err != nil {
@@ -59,25 +65,91 @@ func (s *Service) worker(ctx context.Context, jobs <-chan int) {
 			continue
 		}
 
-		// отдаем на нормализацию заголовок и описание
-		norm, err := s.words.Norm(ctx, info.Title+" "+info.Description)
+		c, err := s.normComics(ctx, info, version)
 		if err != nil {
 			s.log.Error("words norm failed", "id", id, "err", err)
 			continue
 		}
 
-		c := Comics{
-			ID:          info.ID,
-			URL:         info.URL,
-			Title:       info.Title,
-			Description: info.Description,
-			Words:       norm,
-		}
-
 		if err = s.db.Add(ctx, c); err != nil {
 			s.log.Error("db add failed", "id", id, "err", err)
+			continue
+		}
+		added = append(added, c.ID)
+	}
+	return added
+}
+
+// normComics нормализует название, расшифровку и alt одним запросом к words.
+// Слова комикса целиком собираются из слов полей: позиции идут сквозь поля подряд.
+func (s *Service) normComics(ctx context.Context, info XKCDInfo, version string) (Comics, error) {
+	var fields FieldWords
+	dsts := []*[]string{&fields.Title, &fields.Transcript, &fields.Alt}
+	var phrases []string
+	var filled []*[]string
+	for i, text := range []string{info.Title, info.Meta.Transcript, info.Meta.Alt} {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		phrases = append(phrases, text)
+		filled = append(filled, dsts[i])
+	}
+
+	var all []Token
+	if len(phrases) > 0 {
+		results, err := s.words.NormBatch(ctx, phrases)
+		if err != nil {
+			return Comics{}, err
+		}
+		offset := 0
+		for i, tokens := range results {
+			*filled[i] = make([]string, 0, len(tokens))
+			for _, t := range tokens {
+				*filled[i] = append(*filled[i], t.Word)
+				t.Pos += offset
+				all = append(all, t)
+			}
+			if len(all) > 0 {
+				offset = all[len(all)-1].Pos + 1
+			}
+		}
+	}
+
+	words, positions, surfaces := groupTokens(all)
+	return Comics{
+		ID:           info.ID,
+		URL:          info.URL,
+		Title:        info.Title,
+		Description:  info.Description,
+		Words:        words,
+		Positions:    positions,
+		Surfaces:     surfaces,
+		Fields:       fields,
+		Meta:         info.Meta,
+		WordsVersion: version,
+	}, nil
+}
+
+// groupTokens собирает уникальные слова в порядке появления, позиции каждого
+// из них и все их написания в тексте, чтобы поиск мог подсветить совпадения
+func groupTokens(tokens []Token) ([]string, map[string][]int, map[string][]string) {
+	words := make([]string, 0, len(tokens))
+	positions := make(map[string][]int, len(tokens))
+	surfaces := make(map[string][]string, len(tokens))
+	for _, t := range tokens {
+		if _, ok := positions[t.Word]; !ok {
+			words = append(words, t.Word)
+		}
+		positions[t.Word] = append(positions[t.Word], t.Pos)
+
+		if t.Original == "" {
+			continue
+		}
+		if surface := strings.ToLower(t.Original); !slices.Contains(surfaces[t.Word], surface) {
+			surfaces[t.Word] = append(surfaces[t.Word], surface)
 		}
 	}
+	return words, positions, surfaces
 }
 
 func (s *Service) Update(ctx context.Context) (err error) {
@@ -91,16 +163,23 @@ func (s *Service) Update(ctx context.Context) (err error) {
 		return err
 	}
 
-	// какие у нас уже есть в бд
-	have, err := s.db.IDs(ctx)
+	// словари могут смениться во время обновления, тогда такие комиксы
+	// попадут под следующую Renormalize, а не останутся со старыми словами
+	version, err := s.words.Version(ctx)
 	if err != nil {
 		return err
 	}
 
-	// множество уже имеющихся id
-	haveSet := make(map[int]bool, len(have))
-	for _, id := range have {
-		haveSet[id] = true
+	// какие у нас уже есть в бд, id больше последнего нам не интересны
+	haveSet := make([]bool, last+1)
+	err = s.db.ScanIDs(ctx, func(id int) error {
+		if id > 0 && id <= last {
+			haveSet[id] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// список недостающих id
@@ -120,35 +199,181 @@ func (s *Service) Update(ctx context.Context) (err error) {
 		return nil
 	}
 
+	added, err := s.runWorkers(ctx, missing, func(jobs <-chan int) []int {
+		return s.worker(ctx, jobs, version)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(added) == 0 {
+		s.log.Info("no comics were added")
+		return nil
+	}
+
+	if err := s.events.NotifyDBChanged(ctx, DBChanges{Added: added}); err != nil {
+		s.log.Error("failed to send db-changed event", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// runWorkers раздаёт ids concurrency воркерам и собирает отсортированные
+// id, которые они вернули
+func (s *Service) runWorkers(ctx context.Context, ids []int, worker func(jobs <-chan int) []int) ([]int, error) {
 	jobs := make(chan int, s.concurrency*2)
 	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var done []int
 
 	for i := 0; i < s.concurrency; i++ {
 		wg.Go(func() {
-			s.worker(ctx, jobs)
+			res := worker(jobs)
+			mu.Lock()
+			done = append(done, res...)
+			mu.Unlock()
 		})
 	}
 
-	for _, id := range missing {
+	for _, id := range ids {
 		select {
 		case <-ctx.Done():
 			close(jobs)
 			wg.Wait()
-			return ctx.Err()
+			return nil, ctx.Err()
 		case jobs <- id:
 		}
 	}
 	close(jobs)
 	wg.Wait()
 
-	if err := s.events.NotifyDBChanged(ctx); err != nil {
-		s.log.Error("failed to send db-changed event", "error", err)
+	slices.Sort(done)
+	return done, nil
+}
+
+// Renormalize заново нормализует комиксы, сохранённые при другой версии
+// словарей words, и сообщает поиску об изменённых. Не идёт вместе с Update.
+func (s *Service) Renormalize(ctx context.Context) error {
+	if err := s.lockRun(); err != nil {
+		return err
+	}
+	defer s.unlockRun()
+
+	version, err := s.words.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	var stale []int
+	err = s.db.ScanStaleIDs(ctx, version, func(id int) error {
+		stale = append(stale, id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	s.log.Info("renormalizing comics", "count", len(stale), "version", version)
+
+	changed, err := s.runWorkers(ctx, stale, func(jobs <-chan int) []int {
+		return s.renormWorker(ctx, jobs, version)
+	})
+	if err != nil {
 		return err
 	}
 
+	if len(changed) > 0 {
+		if err := s.events.NotifyDBChanged(ctx, DBChanges{Changed: changed}); err != nil {
+			s.log.Error("failed to send db-changed event", "error", err)
+			return err
+		}
+	}
+	// оставшиеся со старой версией подберёт следующий вызов
+	if failed := len(stale) - len(changed); failed > 0 {
+		return fmt.Errorf("%d of %d comics were not renormalized", failed, len(stale))
+	}
 	return nil
 }
 
+// renormWorker нормализует сохранённые комиксы, возвращает id обновлённых
+func (s *Service) renormWorker(ctx context.Context, jobs <-chan int, version string) []int {
+	var changed []int
+	for id := range jobs {
+		info, err := s.db.Source(ctx, id)
+		if err != nil {
+			s.log.Error("db source failed", "id", id, "err", err)
+			continue
+		}
+		// записи из времён до метаданных хранят только слова, текст берём с xkcd
+		// и сохраняем вместе со словами, чтобы не ходить за ним при каждой смене словарей
+		legacy := info.Title == "" && info.Description == ""
+		if legacy {
+			if info, err = s.xkcd.Get(ctx, id); err != nil {
+				s.log.Error("xkcd get failed", "id", id, "err", err)
+				continue
+			}
+		}
+
+		c, err := s.normComics(ctx, info, version)
+		if err != nil {
+			s.log.Error("words norm failed", "id", id, "err", err)
+			continue
+		}
+
+		if legacy {
+			err = s.db.Replace(ctx, c)
+		} else {
+			err = s.db.UpdateWords(ctx, c)
+		}
+		if err != nil {
+			s.log.Error("db update words failed", "id", id, "err", err)
+			continue
+		}
+		changed = append(changed, id)
+	}
+	return changed
+}
+
+// WatchWords раз в interval сверяет версию словарей words и при её смене
+// нормализует комиксы заново. Первая проверка - сразу при запуске:
+// словари могли смениться, пока update не работал.
+func (s *Service) WatchWords(ctx context.Context, interval time.Duration) {
+	var done string
+	check := func() {
+		version, err := s.words.Version(ctx)
+		if err != nil {
+			s.log.Error("failed to get words version", "error", err)
+			return
+		}
+		if version == done {
+			return
+		}
+		switch err := s.Renormalize(ctx); {
+		case errors.Is(err, ErrAlreadyExists):
+			// идёт Update, проверим в следующий раз
+		case err != nil:
+			s.log.Error("failed to renormalize comics", "error", err)
+		default:
+			done = version
+		}
+	}
+
+	check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
 func (s *Service) Stats(ctx context.Context) (ServiceStats, error) {
 	dbStat, err := s.db.Stats(ctx)
 	if err != nil {
@@ -182,7 +407,7 @@ func (s *Service) Drop(ctx context.Context) error {
 	if err := s.db.Drop(ctx); err != nil {
 		return err
 	}
-	if err := s.events.NotifyDBChanged(ctx); err != nil {
+	if err := s.events.NotifyDBChanged(ctx, DBChanges{Dropped: true}); err != nil {
 		s.log.Error("failed to send db-changed event after drop", "error", err)
 		return err
 	}
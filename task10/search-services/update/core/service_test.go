@@ -5,8 +5,11 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,10 +20,14 @@ func newTestLogger() *slog.Logger {
 }
 
 type mockDB struct {
-	addFn   func(ctx context.Context, c Comics) error
-	statsFn func(ctx context.Context) (DBStats, error)
-	dropFn  func(ctx context.Context) error
-	idsFn   func(ctx context.Context) ([]int, error)
+	addFn         func(ctx context.Context, c Comics) error
+	statsFn       func(ctx context.Context) (DBStats, error)
+	dropFn        func(ctx context.Context) error
+	idsFn         func(ctx context.Context) ([]int, error)
+	staleFn       func(ctx context.Context, version string) ([]int, error)
+	sourceFn      func(ctx context.Context, id int) (XKCDInfo, error)
+	updateWordsFn func(ctx context.Context, c Comics) error
+	replaceFn     func(ctx context.Context, c Comics) error
 }
 
 func (m *mockDB) Add(ctx context.Context, c Comics) error {
@@ -44,11 +51,57 @@ func (m *mockDB) Drop(ctx context.Context) error {
 	return m.dropFn(ctx)
 }
 
-func (m *mockDB) IDs(ctx context.Context) ([]int, error) {
+func (m *mockDB) ScanIDs(ctx context.Context, fn func(id int) error) error {
 	if m.idsFn == nil {
-		return nil, nil
+		return nil
+	}
+	ids, err := m.idsFn(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockDB) ScanStaleIDs(ctx context.Context, version string, fn func(id int) error) error {
+	if m.staleFn == nil {
+		return nil
 	}
-	return m.idsFn(ctx)
+	ids, err := m.staleFn(ctx, version)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockDB) Source(ctx context.Context, id int) (XKCDInfo, error) {
+	if m.sourceFn == nil {
+		return XKCDInfo{}, nil
+	}
+	return m.sourceFn(ctx, id)
+}
+
+func (m *mockDB) UpdateWords(ctx context.Context, c Comics) error {
+	if m.updateWordsFn == nil {
+		return nil
+	}
+	return m.updateWordsFn(ctx, c)
+}
+
+func (m *mockDB) Replace(ctx context.Context, c Comics) error {
+	if m.replaceFn == nil {
+		return nil
+	}
+	return m.replaceFn(ctx, c)
 }
 
 type mockXKCD struct {
@@ -71,22 +124,48 @@ func (m *mockXKCD) LastID(ctx context.Context) (int, error) {
 }
 
 type mockWords struct {
-	normFn func(ctx context.Context, phrase string) ([]string, error)
+	normFn    func(ctx context.Context, phrase string) ([]string, error)
+	versionFn func(ctx context.Context) (string, error)
+	batches   atomic.Int32 // вызовы NormBatch
 }
 
-func (m *mockWords) Norm(ctx context.Context, phrase string) ([]string, error) {
-	return m.normFn(ctx, phrase)
+func (m *mockWords) Version(ctx context.Context) (string, error) {
+	if m.versionFn == nil {
+		return "", nil
+	}
+	return m.versionFn(ctx)
+}
+
+func (m *mockWords) NormBatch(ctx context.Context, phrases []string) ([][]Token, error) {
+	m.batches.Add(1)
+	res := make([][]Token, 0, len(phrases))
+	for _, phrase := range phrases {
+		if m.normFn == nil {
+			res = append(res, nil)
+			continue
+		}
+		words, err := m.normFn(ctx, phrase)
+		if err != nil {
+			return nil, err
+		}
+		tokens := make([]Token, 0, len(words))
+		for i, w := range words {
+			tokens = append(tokens, Token{Word: w, Pos: i})
+		}
+		res = append(res, tokens)
+	}
+	return res, nil
 }
 
 type mockEvents struct {
-	notifyFn func(ctx context.Context) error
+	notifyFn func(ctx context.Context, changes DBChanges) error
 }
 
-func (m *mockEvents) NotifyDBChanged(ctx context.Context) error {
+func (m *mockEvents) NotifyDBChanged(ctx context.Context, changes DBChanges) error {
 	if m.notifyFn == nil {
 		return nil
 	}
-	return m.notifyFn(ctx)
+	return m.notifyFn(ctx, changes)
 }
 
 func newUpdateService(
@@ -213,7 +292,7 @@ func TestServiceUpdate_NoMissing_Comics(t *testing.T) {
 	}
 	notifyCalls := 0
 	events := &mockEvents{
-		notifyFn: func(ctx context.Context) error {
+		notifyFn: func(ctx context.Context, changes DBChanges) error {
 			notifyCalls++
 			return nil
 		},
@@ -242,6 +321,11 @@ func TestServiceUpdate_Success_Simple(t *testing.T) {
 	var mu sync.Mutex
 	var fetchedIDs []int
 	var addedIDs []int
+	meta := ComicMeta{
+		SafeTitle: "safe title",
+		Alt:       "alt",
+		Published: time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
 
 	db := &mockDB{
 		idsFn: func(ctx context.Context) ([]int, error) {
@@ -251,6 +335,8 @@ func TestServiceUpdate_Success_Simple(t *testing.T) {
 			mu.Lock()
 			defer mu.Unlock()
 			addedIDs = append(addedIDs, c.ID)
+			// сведения для показа сохраняются как есть
+			assert.Equal(t, meta, c.Meta)
 			return nil
 		},
 	}
@@ -268,6 +354,7 @@ func TestServiceUpdate_Success_Simple(t *testing.T) {
 				URL:         "http://example.com",
 				Title:       "title",
 				Description: "desc",
+				Meta:        meta,
 			}, nil
 		},
 	}
@@ -280,9 +367,11 @@ func TestServiceUpdate_Success_Simple(t *testing.T) {
 	}
 
 	notifyCalls := 0
+	var notified DBChanges
 	events := &mockEvents{
-		notifyFn: func(ctx context.Context) error {
+		notifyFn: func(ctx context.Context, changes DBChanges) error {
 			notifyCalls++
+			notified = changes
 			return nil
 		},
 	}
@@ -296,8 +385,44 @@ func TestServiceUpdate_Success_Simple(t *testing.T) {
 	assert.ElementsMatch(t, []int{1, 4, 5}, fetchedIDs)
 	assert.ElementsMatch(t, []int{1, 4, 5}, addedIDs)
 
-	// Уведомление о смене БД должно быть отправлено один раз
+	// Уведомление о смене БД должно быть отправлено один раз и со списком добавленных
 	assert.Equal(t, 1, notifyCalls)
+	assert.Equal(t, DBChanges{Added: []int{1, 4, 5}}, notified)
+}
+
+func TestServiceUpdate_NothingAdded(t *testing.T) {
+	// все комиксы упали на сохранении -> поиску обновлять нечего
+	db := &mockDB{
+		idsFn: func(ctx context.Context) ([]int, error) {
+			return nil, nil
+		},
+		addFn: func(ctx context.Context, c Comics) error {
+			return errors.New("add failed")
+		},
+	}
+	xkcd := &mockXKCD{
+		lastIDFn: func(ctx context.Context) (int, error) {
+			return 2, nil
+		},
+		getFn: func(ctx context.Context, id int) (XKCDInfo, error) {
+			return XKCDInfo{ID: id}, nil
+		},
+	}
+	words := &mockWords{
+		normFn: func(ctx context.Context, phrase string) ([]string, error) {
+			return []string{"token"}, nil
+		},
+	}
+	events := &mockEvents{
+		notifyFn: func(ctx context.Context, changes DBChanges) error {
+			t.Fatalf("NotifyDBChanged should not be called when nothing was added")
+			return nil
+		},
+	}
+
+	svc := newUpdateService(t, db, xkcd, words, 1, events)
+
+	require.NoError(t, svc.Update(context.Background()))
 }
 
 func TestServiceUpdate_Skip404(t *testing.T) {
@@ -365,7 +490,7 @@ func TestServiceUpdate_CancelledContext(t *testing.T) {
 	}
 	notifyCalls := 0
 	events := &mockEvents{
-		notifyFn: func(ctx context.Context) error {
+		notifyFn: func(ctx context.Context, changes DBChanges) error {
 			notifyCalls++
 			return nil
 		},
@@ -416,7 +541,7 @@ func TestServiceUpdate_NotifyDBChangedError(t *testing.T) {
 
 	// падение: "failed to send db-changed event"
 	events := &mockEvents{
-		notifyFn: func(ctx context.Context) error {
+		notifyFn: func(ctx context.Context, changes DBChanges) error {
 			return expErr
 		},
 	}
@@ -459,7 +584,7 @@ func TestServiceWorker_XKCDError(t *testing.T) {
 	jobs <- 1
 	close(jobs)
 
-	svc.worker(context.Background(), jobs)
+	svc.worker(context.Background(), jobs, "")
 }
 
 func TestServiceWorker_WordsError(t *testing.T) {
@@ -498,7 +623,7 @@ func TestServiceWorker_WordsError(t *testing.T) {
 	jobs <- 1
 	close(jobs)
 
-	svc.worker(context.Background(), jobs)
+	svc.worker(context.Background(), jobs, "")
 }
 
 func TestServiceWorker_DBError(t *testing.T) {
@@ -539,7 +664,7 @@ func TestServiceWorker_DBError(t *testing.T) {
 	jobs <- 1
 	close(jobs)
 
-	svc.worker(context.Background(), jobs)
+	svc.worker(context.Background(), jobs, "")
 	assert.Equal(t, 1, dbCalls)
 }
 
@@ -658,7 +783,7 @@ func TestServiceDrop_DBError(t *testing.T) {
 		},
 	}
 	events := &mockEvents{
-		notifyFn: func(ctx context.Context) error {
+		notifyFn: func(ctx context.Context, changes DBChanges) error {
 			t.Fatalf("NotifyDBChanged should not be called when Drop fails")
 			return nil
 		},
@@ -680,7 +805,7 @@ func TestServiceDrop_NotifyErrorIgnored(t *testing.T) {
 	}
 	notifyCalls := 0
 	events := &mockEvents{
-		notifyFn: func(ctx context.Context) error {
+		notifyFn: func(ctx context.Context, changes DBChanges) error {
 			notifyCalls++
 			return expErr
 		},
@@ -701,8 +826,10 @@ func TestServiceDrop_Success(t *testing.T) {
 	}
 	notifyCalls := 0
 	events := &mockEvents{
-		notifyFn: func(ctx context.Context) error {
+		notifyFn: func(ctx context.Context, changes DBChanges) error {
 			notifyCalls++
+			// после очистки поиск должен перестроить индекс целиком
+			assert.True(t, changes.Dropped)
 			return nil
 		},
 	}
@@ -713,3 +840,193 @@ func TestServiceDrop_Success(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 1, notifyCalls)
 }
+
+func TestGroupTokens(t *testing.T) {
+	// повторы схлопываются в одно слово, позиции копятся
+	words, positions, surfaces := groupTokens([]Token{
+		{Word: "bobbi", Pos: 0, Original: "Bobby"},
+		{Word: "tabl", Pos: 1, Original: "Tables"},
+		{Word: "bobbi", Pos: 5, Original: "bobby"},
+		{Word: "tabl", Pos: 6, Original: "table"},
+	})
+
+	assert.Equal(t, []string{"bobbi", "tabl"}, words)
+	assert.Equal(t, map[string][]int{"bobbi": {0, 5}, "tabl": {1, 6}}, positions)
+	// написания без учёта регистра, без повторов
+	assert.Equal(t, map[string][]string{"bobbi": {"bobby"}, "tabl": {"tables", "table"}}, surfaces)
+}
+
+func TestServiceWorker_FieldWords(t *testing.T) {
+	var added Comics
+	db := &mockDB{
+		addFn: func(ctx context.Context, c Comics) error {
+			added = c
+			return nil
+		},
+	}
+	xkcd := &mockXKCD{
+		getFn: func(ctx context.Context, id int) (XKCDInfo, error) {
+			return XKCDInfo{
+				ID:          id,
+				URL:         "url",
+				Title:       "Bobby Tables",
+				Description: "Bobby Tables little bobby",
+				Meta:        ComicMeta{Alt: "little bobby"},
+			}, nil
+		},
+	}
+	words := &mockWords{
+		normFn: func(ctx context.Context, phrase string) ([]string, error) {
+			var res []string
+			for _, w := range strings.Fields(phrase) {
+				res = append(res, strings.ToLower(w))
+			}
+			return res, nil
+		},
+	}
+	svc := &Service{log: newTestLogger(), db: db, xkcd: xkcd, words: words}
+
+	jobs := make(chan int, 1)
+	jobs <- 1
+	close(jobs)
+	require.Equal(t, []int{1}, svc.worker(context.Background(), jobs, ""))
+	// все поля комикса - одним запросом к words
+	assert.EqualValues(t, 1, words.batches.Load())
+
+	// слова полей идут по порядку и с повторами, пустая расшифровка не нормализуется
+	assert.Equal(t, FieldWords{
+		Title: []string{"bobby", "tables"},
+		Alt:   []string{"little", "bobby"},
+	}, added.Fields)
+	assert.Equal(t, []string{"bobby", "tables", "little"}, added.Words)
+	// позиции alt идут после названия
+	assert.Equal(t, map[string][]int{"bobby": {0, 3}, "tables": {1}, "little": {2}}, added.Positions)
+}
+
+func TestServiceRenormalize(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		updated  = map[int]Comics{}
+		replaced = map[int]Comics{}
+		changes  DBChanges
+	)
+	db := &mockDB{
+		staleFn: func(ctx context.Context, version string) ([]int, error) {
+			assert.Equal(t, "v2", version)
+			return []int{3, 1, 2}, nil
+		},
+		sourceFn: func(ctx context.Context, id int) (XKCDInfo, error) {
+			switch id {
+			case 1:
+				return XKCDInfo{ID: 1, Title: "Bobby", Description: "tables", Meta: ComicMeta{Alt: "tables"}}, nil
+			case 2:
+				// старая запись без текста
+				return XKCDInfo{ID: 2}, nil
+			}
+			return XKCDInfo{}, errors.New("db down")
+		},
+		updateWordsFn: func(ctx context.Context, c Comics) error {
+			mu.Lock()
+			updated[c.ID] = c
+			mu.Unlock()
+			return nil
+		},
+		replaceFn: func(ctx context.Context, c Comics) error {
+			mu.Lock()
+			replaced[c.ID] = c
+			mu.Unlock()
+			return nil
+		},
+	}
+	xkcd := &mockXKCD{getFn: func(ctx context.Context, id int) (XKCDInfo, error) {
+		return XKCDInfo{ID: id, Title: "Fetched", Meta: ComicMeta{SafeTitle: "Fetched"}}, nil
+	}}
+	words := &mockWords{
+		normFn: func(ctx context.Context, phrase string) ([]string, error) {
+			return strings.Fields(strings.ToLower(phrase)), nil
+		},
+		versionFn: func(ctx context.Context) (string, error) { return "v2", nil },
+	}
+	events := &mockEvents{notifyFn: func(ctx context.Context, c DBChanges) error {
+		changes = c
+		return nil
+	}}
+	svc := newUpdateService(t, db, xkcd, words, 2, events)
+
+	// третий комикс не прочитался - ошибка, чтобы его подобрал следующий вызов
+	err := svc.Renormalize(context.Background())
+	require.ErrorContains(t, err, "1 of 3")
+
+	assert.Equal(t, DBChanges{Changed: []int{1, 2}}, changes)
+	require.Len(t, updated, 1)
+	assert.Equal(t, "v2", updated[1].WordsVersion)
+	assert.Equal(t, []string{"bobby", "tables"}, updated[1].Words)
+	// текст старой записи взят с xkcd и сохранён вместе со словами
+	require.Len(t, replaced, 1)
+	assert.Equal(t, []string{"fetched"}, replaced[2].Words)
+	assert.Equal(t, "Fetched", replaced[2].Title)
+	assert.Equal(t, "Fetched", replaced[2].Meta.SafeTitle)
+}
+
+func TestServiceRenormalize_NothingStale(t *testing.T) {
+	notified := false
+	svc := newUpdateService(t, &mockDB{}, &mockXKCD{}, &mockWords{}, 1, &mockEvents{
+		notifyFn: func(ctx context.Context, c DBChanges) error {
+			notified = true
+			return nil
+		},
+	})
+	require.NoError(t, svc.Renormalize(context.Background()))
+	assert.False(t, notified)
+}
+
+func TestServiceRenormalize_AlreadyRunning(t *testing.T) {
+	svc := newUpdateService(t, &mockDB{}, &mockXKCD{}, &mockWords{}, 1, &mockEvents{})
+	require.NoError(t, svc.lockRun())
+	assert.ErrorIs(t, svc.Renormalize(context.Background()), ErrAlreadyExists)
+}
+
+func TestServiceUpdate_WordsVersion(t *testing.T) {
+	var added Comics
+	db := &mockDB{addFn: func(ctx context.Context, c Comics) error {
+		added = c
+		return nil
+	}}
+	xkcd := &mockXKCD{
+		lastIDFn: func(ctx context.Context) (int, error) { return 1, nil },
+		getFn: func(ctx context.Context, id int) (XKCDInfo, error) {
+			return XKCDInfo{ID: id, Title: "t"}, nil
+		},
+	}
+	words := &mockWords{versionFn: func(ctx context.Context) (string, error) { return "v1", nil }}
+	svc := newUpdateService(t, db, xkcd, words, 1, &mockEvents{})
+
+	require.NoError(t, svc.Update(context.Background()))
+	assert.Equal(t, "v1", added.WordsVersion)
+
+	words.versionFn = func(ctx context.Context) (string, error) { return "", errors.New("words down") }
+	assert.Error(t, svc.Update(context.Background()))
+}
+
+func TestServiceWatchWords(t *testing.T) {
+	var mu sync.Mutex
+	var scans []string
+	db := &mockDB{staleFn: func(ctx context.Context, version string) ([]int, error) {
+		mu.Lock()
+		scans = append(scans, version)
+		mu.Unlock()
+		return nil, nil
+	}}
+	svc := newUpdateService(t, db, &mockXKCD{}, &mockWords{
+		versionFn: func(ctx context.Context) (string, error) { return "v1", nil },
+	}, 1, &mockEvents{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.WatchWords(ctx, 5*time.Millisecond)
+
+	// версия не менялась - в базу сходили только при запуске
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"v1"}, scans)
+}
@@ -58,10 +58,15 @@ func run(cfg config.Config, log *slog.Logger) error {
 	}
 
 	// words adapter
-	words, err := words.NewClient(cfg.WordsAddress, log)
+	words, err := words.NewClient(cfg.WordsAddress, cfg.WordsAnalyzer, log)
 	if err != nil {
 		return fmt.Errorf("failed create Words client: %v", err)
 	}
+	defer func() {
+		if err := words.Close(); err != nil {
+			log.Error("failed to close words client", "error", err)
+		}
+	}()
 
 	// event adapter
 	ev, err := events.NewNatsPublisher(cfg.BrokerAddress, log)
@@ -100,6 +105,9 @@ func run(cfg config.Config, log *slog.Logger) error {
 		s.GracefulStop()
 	}()
 
+	// смена словарей words - повод нормализовать комиксы заново
+	go updater.WatchWords(ctx, cfg.WordsCheckPeriod)
+
 	if err := s.Serve(listener); err != nil {
 		return fmt.Errorf("failed to serve: %v", err)
 	}
@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"os"
 	"os/signal"
+	"slices"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,22 +26,84 @@ import (
 )
 
 const (
-	maxPhraseLen    = 20 << 10
+	maxPhraseLen = 20 << 10
+	// ответ с позициями в несколько раз больше фразы, а пачка из maxBatchItems
+	// самых длинных фраз должна уложиться в 4 МиБ сообщения gRPC
+	maxBatchItems   = 32
 	maxShutdownTime = 5 * time.Second
 )
 
 type Config struct {
-	Address string `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"80"`
+	Address      string                               `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"80"`
+	Analyzers    map[string]normalizer.AnalyzerConfig `yaml:"analyzers"`
+	Dictionaries normalizer.DictionaryFiles           `yaml:"dictionaries"`
 }
 type server struct {
 	wordspb.UnimplementedWordsServer
+	analyzers map[string]*normalizer.Analyzer
+	// словари подменяются целиком, фраза нормализуется по одной версии
+	dicts atomic.Pointer[normalizer.Dictionaries]
 }
 
 func (s *server) Ping(_ context.Context, in *emptypb.Empty) (*emptypb.Empty, error) {
 	return &emptypb.Empty{}, nil
 }
 
+func (s *server) Info(_ context.Context, _ *emptypb.Empty) (*wordspb.InfoReply, error) {
+	reply := &wordspb.InfoReply{Analyzers: make([]string, 0, len(s.analyzers))}
+	if d := s.dicts.Load(); d != nil {
+		reply.DictionaryVersion = d.Version
+	}
+	for name := range s.analyzers {
+		reply.Analyzers = append(reply.Analyzers, name)
+	}
+	slices.Sort(reply.Analyzers)
+	return reply, nil
+}
+
 func (s *server) Norm(ctx context.Context, req *wordspb.WordsRequest) (*wordspb.WordsReply, error) {
+	return s.norm(req)
+}
+
+// NormBatch отвечает на каждую фразу отдельно: ошибка одной не роняет остальные
+func (s *server) NormBatch(ctx context.Context, req *wordspb.WordsBatchRequest) (*wordspb.WordsBatchReply, error) {
+
+	if len(req.GetItems()) > maxBatchItems {
+		return nil, status.Errorf(codes.InvalidArgument, "batch too large: %d items, max %d", len(req.GetItems()), maxBatchItems)
+	}
+
+	reply := &wordspb.WordsBatchReply{Items: make([]*wordspb.WordsItem, 0, len(req.GetItems()))}
+	for _, item := range req.GetItems() {
+		reply.Items = append(reply.Items, s.normItem(item))
+	}
+	return reply, nil
+}
+
+func (s *server) NormStream(stream wordspb.Words_NormStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(s.normItem(req)); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) normItem(req *wordspb.WordsRequest) *wordspb.WordsItem {
+	reply, err := s.norm(req)
+	if err != nil {
+		st := status.Convert(err)
+		return &wordspb.WordsItem{Code: int32(st.Code()), Error: st.Message()}
+	}
+	return &wordspb.WordsItem{Reply: reply}
+}
+
+func (s *server) norm(req *wordspb.WordsRequest) (*wordspb.WordsReply, error) {
 
 	phrase := req.GetPhrase()
 
@@ -45,9 +111,36 @@ func (s *server) Norm(ctx context.Context, req *wordspb.WordsRequest) (*wordspb.
 		return nil, status.Error(codes.ResourceExhausted, "phrase too large")
 	}
 
-	words := normalizer.Normalize(phrase)
+	lang, err := normalizer.ParseLanguage(req.GetLanguage())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	name := req.GetAnalyzer()
+	if name == "" {
+		name = normalizer.DefaultAnalyzerName
+	}
+	analyzer, ok := s.analyzers[name]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown analyzer %q", name)
+	}
+
+	// фраза разбирается один раз: и слова, и позиции - из тех же токенов
+	tokens, detected := analyzer.Tokenize(phrase, lang, s.dicts.Load())
+	reply := &wordspb.WordsReply{Words: normalizer.UniqueWords(tokens), Language: detected}
+
+	if req.GetPositions() {
+		reply.Tokens = make([]*wordspb.Token, 0, len(tokens))
+		for _, t := range tokens {
+			reply.Tokens = append(reply.Tokens, &wordspb.Token{
+				Word:     t.Word,
+				Position: int32(t.Pos),
+				Original: t.Original,
+			})
+		}
+	}
 
-	return &wordspb.WordsReply{Words: words}, nil
+	return reply, nil
 
 }
 func main() {
@@ -77,6 +170,23 @@ func main() {
 }
 
 func run(cfg Config, log *slog.Logger) error {
+	analyzers, err := normalizer.NewAnalyzers(cfg.Analyzers)
+	if err != nil {
+		return fmt.Errorf("wrong analyzers config: %v", err)
+	}
+	log.Info("analyzers loaded", "count", len(analyzers))
+
+	// смена анализаторов тоже меняет версию словарей: комиксы нормализуются заново
+	analyzersConfig, err := normalizer.MarshalAnalyzers(cfg.Analyzers)
+	if err != nil {
+		return fmt.Errorf("wrong analyzers config: %v", err)
+	}
+	dicts, err := normalizer.LoadDictionaries(cfg.Dictionaries, analyzersConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionaries: %v", err)
+	}
+	log.Info("dictionaries loaded", "version", dicts.Version)
+
 	addr := cfg.Address
 	listener, err := net.Listen("tcp", addr)
 
@@ -84,12 +194,20 @@ func run(cfg Config, log *slog.Logger) error {
 		return fmt.Errorf("failed to listen port %s: %v", cfg.Address, err)
 	}
 
+	srv := &server{analyzers: analyzers}
+	srv.dicts.Store(dicts)
+
 	grpcServer := grpc.NewServer()
-	wordspb.RegisterWordsServer(grpcServer, &server{})
+	wordspb.RegisterWordsServer(grpcServer, srv)
 	reflection.Register(grpcServer)
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 
+	go normalizer.WatchDictionaries(ctx, log, cfg.Dictionaries, analyzersConfig, dicts, func(d *normalizer.Dictionaries) {
+		srv.dicts.Store(d)
+		log.Info("dictionaries reloaded", "version", d.Version)
+	})
+
 	go func() {
 		log.Info("starting server", "addr", addr)
 		if err = grpcServer.Serve(listener); err != nil {
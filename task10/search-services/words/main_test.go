@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	wordspb "yadro.com/course/proto/words"
+	normalizer "yadro.com/course/words/words"
+)
+
+func newTestServer(t *testing.T, cfgs map[string]normalizer.AnalyzerConfig) *server {
+	t.Helper()
+	analyzers, err := normalizer.NewAnalyzers(cfgs)
+	require.NoError(t, err)
+	return &server{analyzers: analyzers}
+}
+
+func TestServer_NormBatch(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	resp, err := s.NormBatch(context.Background(), &wordspb.WordsBatchRequest{Items: []*wordspb.WordsRequest{
+		{Phrase: "running computers"},
+		{Phrase: strings.Repeat("a", maxPhraseLen+1)},
+		{Phrase: "книги", Language: "klingon"},
+		{Phrase: "книги", Positions: true},
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 4)
+
+	assert.Equal(t, []string{"run", "comput"}, resp.Items[0].Reply.Words)
+	assert.Equal(t, "english", resp.Items[0].Reply.Language)
+	// ошибки у каждой фразы свои
+	assert.Equal(t, int32(codes.ResourceExhausted), resp.Items[1].Code)
+	assert.Nil(t, resp.Items[1].Reply)
+	assert.Equal(t, int32(codes.InvalidArgument), resp.Items[2].Code)
+	assert.NotEmpty(t, resp.Items[2].Error)
+	assert.Equal(t, "russian", resp.Items[3].Reply.Language)
+	assert.Equal(t, "книги", resp.Items[3].Reply.Tokens[0].Original)
+	// слова и позиции - из одного разбора фразы
+	assert.Equal(t, []string{"книг"}, resp.Items[3].Reply.Words)
+	assert.Equal(t, "книг", resp.Items[3].Reply.Tokens[0].Word)
+
+	_, err = s.NormBatch(context.Background(), &wordspb.WordsBatchRequest{Items: make([]*wordspb.WordsRequest, maxBatchItems+1)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// mockNormStream - поток с заранее заданными запросами
+type mockNormStream struct {
+	grpc.ServerStream
+	in  []*wordspb.WordsRequest
+	out []*wordspb.WordsItem
+}
+
+func (m *mockNormStream) Recv() (*wordspb.WordsRequest, error) {
+	if len(m.in) == 0 {
+		return nil, io.EOF
+	}
+	req := m.in[0]
+	m.in = m.in[1:]
+	return req, nil
+}
+
+func (m *mockNormStream) Send(item *wordspb.WordsItem) error {
+	m.out = append(m.out, item)
+	return nil
+}
+
+func TestServer_NormStream(t *testing.T) {
+	stream := &mockNormStream{in: []*wordspb.WordsRequest{
+		{Phrase: strings.Repeat("a", maxPhraseLen+1)},
+		{Phrase: "linux kernels"},
+	}}
+
+	require.NoError(t, newTestServer(t, nil).NormStream(stream))
+	require.Len(t, stream.out, 2)
+	// слишком длинная фраза не обрывает поток
+	assert.Equal(t, int32(codes.ResourceExhausted), stream.out[0].Code)
+	assert.Equal(t, []string{"linux", "kernel"}, stream.out[1].Reply.Words)
+}
+
+func TestServer_NormAnalyzer(t *testing.T) {
+	s := newTestServer(t, map[string]normalizer.AnalyzerConfig{
+		"prefix": {Filters: []normalizer.FilterConfig{{Type: "lowercase"}, {Type: "ngram", Min: 2, Max: 3, Edge: true}}},
+	})
+
+	resp, err := s.Norm(context.Background(), &wordspb.WordsRequest{Phrase: "Linux", Analyzer: "prefix"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"li", "lin"}, resp.Words)
+
+	// без имени - default, даже если он не описан в конфиге
+	resp, err = s.Norm(context.Background(), &wordspb.WordsRequest{Phrase: "Linux kernels"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux", "kernel"}, resp.Words)
+
+	_, err = s.Norm(context.Background(), &wordspb.WordsRequest{Phrase: "Linux", Analyzer: "missing"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Info(t *testing.T) {
+	s := newTestServer(t, map[string]normalizer.AnalyzerConfig{"raw": {}})
+	dicts, err := normalizer.ParseDictionaries(nil, []byte("comics"), nil, nil)
+	require.NoError(t, err)
+	s.dicts.Store(dicts)
+
+	info, err := s.Info(context.Background(), &emptypb.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, dicts.Version, info.DictionaryVersion)
+	assert.Equal(t, []string{"default", "raw"}, info.Analyzers)
+
+	// словари применяются к нормализации
+	resp, err := s.Norm(context.Background(), &wordspb.WordsRequest{Phrase: "xkcd comics"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"xkcd", "comics"}, resp.Words)
+}
@@ -0,0 +1,357 @@
+package words
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kljensen/snowball"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Анализатор разбивает фразу на слова в три шага: фильтры символов правят
+// исходный текст, токенизатор режет его на слова, фильтры слов по очереди
+// меняют, выкидывают или размножают слова. Анализаторы описываются
+// в words/config.yaml и выбираются по имени в WordsRequest.
+//
+//	analyzers:
+//	  default:
+//	    tokenizer: unicode
+//	    filters:
+//	      - type: lowercase
+//	      - type: stop
+//	      - type: stem
+
+// DefaultAnalyzerName - анализатор для запросов без имени
+const DefaultAnalyzerName = "default"
+
+type AnalyzerConfig struct {
+	CharFilters []FilterConfig `yaml:"char_filters"`
+	Tokenizer   string         `yaml:"tokenizer"` // unicode (по умолчанию) или whitespace
+	Filters     []FilterConfig `yaml:"filters"`
+}
+
+// FilterConfig - фильтр и его параметры, какие нужны типу:
+//
+//	html_strip              - убрать теги и раскрыть сущности
+//	mapping: mappings       - заменить подстроки
+//	lowercase               - нижний регистр
+//	stop: language          - выкинуть стоп-слова
+//	stem: language          - основа слова по snowball
+//	length: min, max        - выкинуть слова длиной вне [min, max] символов, max 0 - без предела
+//	ascii_folding           - убрать диакритику латиницы: café -> cafe
+//	ngram: min, max, edge   - заменить слово его n-граммами, с edge - только началами
+//
+// Пустой language - язык запроса, для auto свой у каждого слова.
+type FilterConfig struct {
+	Type     string            `yaml:"type"`
+	Language string            `yaml:"language"`
+	Min      int               `yaml:"min"`
+	Max      int               `yaml:"max"`
+	Edge     bool              `yaml:"edge"`
+	Mappings map[string]string `yaml:"mappings"`
+}
+
+// DefaultAnalyzerConfig - нормализация, как до настраиваемых анализаторов
+var DefaultAnalyzerConfig = AnalyzerConfig{
+	Tokenizer: "unicode",
+	Filters:   []FilterConfig{{Type: "lowercase"}, {Type: "stop"}, {Type: "stem"}},
+}
+
+type charFilter func(string) string
+
+// tokenFilter получает слова фразы, язык запроса и текущие словари
+type tokenFilter func(tokens []Token, lang string, dict *Dictionaries) []Token
+
+type Analyzer struct {
+	charFilters []charFilter
+	tokenize    func(string) []string
+	filters     []tokenFilter
+}
+
+func NewAnalyzer(cfg AnalyzerConfig) (*Analyzer, error) {
+	a := &Analyzer{}
+
+	for _, fc := range cfg.CharFilters {
+		f, err := newCharFilter(fc)
+		if err != nil {
+			return nil, err
+		}
+		a.charFilters = append(a.charFilters, f)
+	}
+
+	switch cfg.Tokenizer {
+	case "", "unicode":
+		a.tokenize = func(s string) []string { return availableCharacters.FindAllString(s, -1) }
+	case "whitespace":
+		a.tokenize = strings.Fields
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q", cfg.Tokenizer)
+	}
+
+	for _, fc := range cfg.Filters {
+		f, err := newTokenFilter(fc)
+		if err != nil {
+			return nil, err
+		}
+		a.filters = append(a.filters, f)
+	}
+	return a, nil
+}
+
+// NewAnalyzers собирает анализаторы из конфига. Если default не описан,
+// им становится DefaultAnalyzerConfig.
+// MarshalAnalyzers сериализует настройки анализаторов вместе со встроенным
+// default, если его не переопределили. Порядок ключей постоянный.
+func MarshalAnalyzers(cfgs map[string]AnalyzerConfig) ([]byte, error) {
+	all := make(map[string]AnalyzerConfig, len(cfgs)+1)
+	maps.Copy(all, cfgs)
+	if _, ok := all[DefaultAnalyzerName]; !ok {
+		all[DefaultAnalyzerName] = DefaultAnalyzerConfig
+	}
+	return json.Marshal(all)
+}
+
+func NewAnalyzers(cfgs map[string]AnalyzerConfig) (map[string]*Analyzer, error) {
+	res := make(map[string]*Analyzer, len(cfgs)+1)
+	for _, name := range slices.Sorted(maps.Keys(cfgs)) {
+		a, err := NewAnalyzer(cfgs[name])
+		if err != nil {
+			return nil, fmt.Errorf("analyzer %q: %w", name, err)
+		}
+		res[name] = a
+	}
+	if _, ok := res[DefaultAnalyzerName]; !ok {
+		res[DefaultAnalyzerName] = defaultAnalyzer
+	}
+	return res, nil
+}
+
+var defaultAnalyzer = mustAnalyzer(DefaultAnalyzerConfig)
+
+func mustAnalyzer(cfg AnalyzerConfig) *Analyzer {
+	a, err := NewAnalyzer(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Tokenize нормализует все слова фразы с повторами. Выкинутые слова
+// занимают свои позиции, чтобы расстояние между словами соответствовало
+// исходному тексту. Вторым значением возвращается язык фразы: для auto -
+// язык большинства слов, без слов - английский. Словари dict правят
+// фильтры stop и stem, nil - только встроенные правила.
+func (a *Analyzer) Tokenize(phrase, lang string, dict *Dictionaries) ([]Token, string) {
+	for _, f := range a.charFilters {
+		phrase = f(phrase)
+	}
+	raw := a.tokenize(phrase)
+	if len(raw) == 0 {
+		return []Token{}, detected(lang, 0, 0)
+	}
+
+	tokens := make([]Token, 0, len(raw))
+	var english, russian int
+	for pos, word := range raw {
+		tokens = append(tokens, Token{Word: word, Pos: pos, Original: word})
+		if isDigits(word) {
+			continue
+		}
+		if wordLanguage(word, lang) == LangRussian {
+			russian++
+		} else {
+			english++
+		}
+	}
+
+	for _, f := range a.filters {
+		tokens = f(tokens, lang, dict)
+	}
+	return tokens, detected(lang, english, russian)
+}
+
+// Normalize - различные нормализованные слова фразы и её язык
+func (a *Analyzer) Normalize(phrase, lang string, dict *Dictionaries) ([]string, string) {
+	tokens, detectedLang := a.Tokenize(phrase, lang, dict)
+	return UniqueWords(tokens), detectedLang
+}
+
+// UniqueWords - различные слова токенов в порядке первого появления
+func UniqueWords(tokens []Token) []string {
+	out := make([]string, 0, len(tokens))
+	seen := make(map[string]bool)
+	for _, t := range tokens {
+		if !seen[t.Word] {
+			out = append(out, t.Word)
+			seen[t.Word] = true
+		}
+	}
+	return out
+}
+
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+func newCharFilter(fc FilterConfig) (charFilter, error) {
+	switch fc.Type {
+	case "html_strip":
+		return func(s string) string {
+			return html.UnescapeString(htmlTags.ReplaceAllString(s, " "))
+		}, nil
+	case "mapping":
+		if len(fc.Mappings) == 0 {
+			return nil, fmt.Errorf("mapping filter without mappings")
+		}
+		// ключи по порядку, чтобы при пересечениях замена не зависела от map
+		var pairs []string
+		for _, from := range slices.Sorted(maps.Keys(fc.Mappings)) {
+			pairs = append(pairs, from, fc.Mappings[from])
+		}
+		return strings.NewReplacer(pairs...).Replace, nil
+	}
+	return nil, fmt.Errorf("unknown char filter %q", fc.Type)
+}
+
+func newTokenFilter(fc FilterConfig) (tokenFilter, error) {
+	if fc.Language != "" {
+		if _, err := ParseLanguage(fc.Language); err != nil {
+			return nil, err
+		}
+	}
+	// язык фильтра, если задан, важнее языка запроса
+	language := func(lang string) string {
+		if fc.Language != "" {
+			return strings.ToLower(fc.Language)
+		}
+		return lang
+	}
+
+	switch fc.Type {
+	case "lowercase":
+		return mapWords(func(w, _ string, _ *Dictionaries) string { return strings.ToLower(w) }), nil
+	case "stop":
+		return func(tokens []Token, lang string, dict *Dictionaries) []Token {
+			return slices.DeleteFunc(tokens, func(t Token) bool {
+				if dict.isStopWord(t.Word) {
+					return true
+				}
+				if dict.isProtected(t.Word) {
+					return false
+				}
+				wl := wordLanguage(t.Word, language(lang))
+				return isStopWord(foldYo(t.Word, wl), wl)
+			})
+		}, nil
+	case "stem":
+		return mapWords(func(w, lang string, dict *Dictionaries) string {
+			if stem, ok := dict.stem(w); ok {
+				return stem
+			}
+			if isDigits(w) || dict.isProtected(w) {
+				return w
+			}
+			wl := wordLanguage(w, language(lang))
+			w = foldYo(w, wl)
+			stem, err := snowball.Stem(w, wl, true)
+			if err != nil && stem == "" {
+				return w
+			}
+			return stem
+		}), nil
+	case "length":
+		if fc.Min < 0 || fc.Max < 0 || (fc.Max > 0 && fc.Max < fc.Min) {
+			return nil, fmt.Errorf("wrong length filter bounds [%d, %d]", fc.Min, fc.Max)
+		}
+		return func(tokens []Token, _ string, _ *Dictionaries) []Token {
+			return slices.DeleteFunc(tokens, func(t Token) bool {
+				n := utf8.RuneCountInString(t.Word)
+				return n < fc.Min || (fc.Max > 0 && n > fc.Max)
+			})
+		}, nil
+	case "ascii_folding":
+		return mapWords(func(w, _ string, _ *Dictionaries) string { return foldASCII(w) }), nil
+	case "ngram":
+		if fc.Min <= 0 || fc.Max < fc.Min {
+			return nil, fmt.Errorf("wrong ngram filter bounds [%d, %d]", fc.Min, fc.Max)
+		}
+		return func(tokens []Token, _ string, _ *Dictionaries) []Token {
+			out := make([]Token, 0, len(tokens))
+			for _, t := range tokens {
+				out = append(out, ngrams(t, fc.Min, fc.Max, fc.Edge)...)
+			}
+			return out
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown token filter %q", fc.Type)
+}
+
+// mapWords - фильтр, меняющий каждое слово по отдельности
+func mapWords(f func(word, lang string, dict *Dictionaries) string) tokenFilter {
+	return func(tokens []Token, lang string, dict *Dictionaries) []Token {
+		for i := range tokens {
+			tokens[i].Word = f(tokens[i].Word, lang, dict)
+		}
+		return tokens
+	}
+}
+
+// foldYo - ё и е в русских текстах пишут вперемешку
+func foldYo(word, lang string) string {
+	if lang != LangRussian {
+		return word
+	}
+	return strings.ReplaceAll(word, "ё", "е")
+}
+
+// лигатуры и буквы, которые не раскладываются на основу и знак
+var asciiLetters = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE",
+	'ø': "o", 'Ø': "O", 'ł': "l", 'Ł': "L", 'đ': "d", 'Đ': "D",
+}
+
+// foldASCII убирает диакритику только у латиницы: й и ё
+// в кириллице - отдельные буквы, их трогать нельзя
+func foldASCII(word string) string {
+	var b strings.Builder
+	latin := false
+	for _, r := range norm.NFD.String(word) {
+		if unicode.Is(unicode.Mn, r) {
+			if !latin {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		latin = unicode.Is(unicode.Latin, r)
+		if s, ok := asciiLetters[r]; ok {
+			b.WriteString(s)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
+
+// ngrams - все куски слова длиной от min до max символов на позиции слова.
+// Слово короче min остаётся как есть, иначе его бы было не найти.
+func ngrams(t Token, min, max int, edge bool) []Token {
+	runes := []rune(t.Word)
+	if len(runes) < min {
+		return []Token{t}
+	}
+	var out []Token
+	for start := 0; start < len(runes); start++ {
+		if edge && start > 0 {
+			break
+		}
+		for n := min; n <= max && start+n <= len(runes); n++ {
+			out = append(out, Token{Word: string(runes[start : start+n]), Pos: t.Pos, Original: t.Original})
+		}
+	}
+	return out
+}
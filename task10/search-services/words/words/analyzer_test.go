@@ -0,0 +1,122 @@
+package words
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzer_Filters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		cfg    AnalyzerConfig
+		phrase string
+		words  []string
+	}{
+		{
+			name:   "html strip",
+			cfg:    AnalyzerConfig{CharFilters: []FilterConfig{{Type: "html_strip"}}, Filters: []FilterConfig{{Type: "lowercase"}}},
+			phrase: "<b>Tom</b> &amp; Jerry",
+			words:  []string{"tom", "jerry"},
+		},
+		{
+			name: "mapping",
+			cfg: AnalyzerConfig{
+				CharFilters: []FilterConfig{{Type: "mapping", Mappings: map[string]string{"c++": "cpp"}}},
+				Tokenizer:   "whitespace",
+			},
+			phrase: "c++ rocks",
+			words:  []string{"cpp", "rocks"},
+		},
+		{
+			name:   "whitespace tokenizer keeps punctuation",
+			cfg:    AnalyzerConfig{Tokenizer: "whitespace"},
+			phrase: "e-mail, please",
+			words:  []string{"e-mail,", "please"},
+		},
+		{
+			name:   "length",
+			cfg:    AnalyzerConfig{Filters: []FilterConfig{{Type: "length", Min: 2, Max: 4}}},
+			phrase: "a ab abcd abcde",
+			words:  []string{"ab", "abcd"},
+		},
+		{
+			name:   "ascii folding leaves cyrillic",
+			cfg:    AnalyzerConfig{Filters: []FilterConfig{{Type: "lowercase"}, {Type: "ascii_folding"}}},
+			phrase: "Café Straße йод ёж",
+			words:  []string{"cafe", "strasse", "йод", "ёж"},
+		},
+		{
+			name:   "ngram",
+			cfg:    AnalyzerConfig{Filters: []FilterConfig{{Type: "ngram", Min: 2, Max: 3}}},
+			phrase: "abcd x",
+			words:  []string{"ab", "abc", "bc", "bcd", "cd", "x"},
+		},
+		{
+			name:   "edge ngram",
+			cfg:    AnalyzerConfig{Filters: []FilterConfig{{Type: "ngram", Min: 1, Max: 3, Edge: true}}},
+			phrase: "abcd",
+			words:  []string{"a", "ab", "abc"},
+		},
+		{
+			name:   "filter language overrides request",
+			cfg:    AnalyzerConfig{Filters: []FilterConfig{{Type: "stem", Language: LangRussian}}},
+			phrase: "книги",
+			words:  []string{"книг"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := NewAnalyzer(tc.cfg)
+			require.NoError(t, err)
+			words, _ := a.Normalize(tc.phrase, LangEnglish, nil)
+			assert.Equal(t, tc.words, words)
+		})
+	}
+}
+
+func TestAnalyzer_NgramPositions(t *testing.T) {
+	a, err := NewAnalyzer(AnalyzerConfig{Filters: []FilterConfig{{Type: "ngram", Min: 2, Max: 2, Edge: true}}})
+	require.NoError(t, err)
+
+	tokens, _ := a.Tokenize("foo bar", LangAuto, nil)
+	assert.Equal(t, []Token{
+		{Word: "fo", Pos: 0, Original: "foo"},
+		{Word: "ba", Pos: 1, Original: "bar"},
+	}, tokens)
+}
+
+func TestNewAnalyzer_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  AnalyzerConfig
+	}{
+		{"unknown tokenizer", AnalyzerConfig{Tokenizer: "regexp"}},
+		{"unknown char filter", AnalyzerConfig{CharFilters: []FilterConfig{{Type: "lowercase"}}}},
+		{"unknown token filter", AnalyzerConfig{Filters: []FilterConfig{{Type: "synonyms"}}}},
+		{"empty mapping", AnalyzerConfig{CharFilters: []FilterConfig{{Type: "mapping"}}}},
+		{"unknown language", AnalyzerConfig{Filters: []FilterConfig{{Type: "stem", Language: "klingon"}}}},
+		{"bad length", AnalyzerConfig{Filters: []FilterConfig{{Type: "length", Min: 5, Max: 2}}}},
+		{"bad ngram", AnalyzerConfig{Filters: []FilterConfig{{Type: "ngram"}}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewAnalyzer(tc.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewAnalyzers_Default(t *testing.T) {
+	analyzers, err := NewAnalyzers(map[string]AnalyzerConfig{"raw": {}})
+	require.NoError(t, err)
+	require.Contains(t, analyzers, DefaultAnalyzerName)
+	require.Contains(t, analyzers, "raw")
+
+	words, _ := analyzers["raw"].Normalize("The Running", LangAuto, nil)
+	assert.Equal(t, []string{"The", "Running"}, words)
+
+	_, err = NewAnalyzers(map[string]AnalyzerConfig{"bad": {Tokenizer: "regexp"}})
+	assert.ErrorContains(t, err, `analyzer "bad"`)
+}
@@ -0,0 +1,164 @@
+package words
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Словари правят встроенные правила stop и stem во всех анализаторах,
+// для слов любого языка. Файлы - по слову в строке, пустые строки
+// и всё после # пропускаются:
+//
+//	stop_words      - дополнительные стоп-слова
+//	protected_words - слова, которые не выкидываются и не обрезаются до основы
+//	stem_overrides  - слово и его основа через пробел: cueball cueball
+
+// DictionaryFiles - пути к словарям, пустой путь - словаря нет.
+// ReloadInterval 0 - словари читаются только при запуске.
+type DictionaryFiles struct {
+	StopWords      string        `yaml:"stop_words" env:"STOP_WORDS"`
+	ProtectedWords string        `yaml:"protected_words" env:"PROTECTED_WORDS"`
+	StemOverrides  string        `yaml:"stem_overrides" env:"STEM_OVERRIDES"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"DICTIONARIES_RELOAD_INTERVAL" env-default:"10s"`
+}
+
+// Dictionaries - разобранные словари. Version меняется с любой правкой
+// словарей или настроек анализаторов: по ней update понимает, что комиксы
+// пора нормализовать заново.
+// nil - только встроенные правила.
+type Dictionaries struct {
+	Version   string
+	stop      map[string]bool
+	protected map[string]bool
+	stems     map[string]string
+}
+
+// ParseDictionaries разбирает содержимое словарей, analyzers - сериализованные
+// настройки анализаторов из MarshalAnalyzers, они входят в версию
+func ParseDictionaries(stop, protected, stems, analyzers []byte) (*Dictionaries, error) {
+	d := &Dictionaries{
+		stop:      make(map[string]bool),
+		protected: make(map[string]bool),
+		stems:     make(map[string]string),
+	}
+
+	for n, fields := range dictionaryLines(stop) {
+		if len(fields) != 1 {
+			return nil, fmt.Errorf("stop words: line %d: one word expected", n)
+		}
+		d.stop[fields[0]] = true
+	}
+	for n, fields := range dictionaryLines(protected) {
+		if len(fields) != 1 {
+			return nil, fmt.Errorf("protected words: line %d: one word expected", n)
+		}
+		d.protected[fields[0]] = true
+	}
+	for n, fields := range dictionaryLines(stems) {
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("stem overrides: line %d: word and stem expected", n)
+		}
+		d.stems[fields[0]] = fields[1]
+	}
+
+	h := sha256.New()
+	for _, data := range [][]byte{stop, protected, stems, analyzers} {
+		_, _ = fmt.Fprintf(h, "%d:", len(data))
+		h.Write(data)
+	}
+	d.Version = hex.EncodeToString(h.Sum(nil))[:16]
+	return d, nil
+}
+
+// dictionaryLines - номера непустых строк словаря и их слова
+func dictionaryLines(data []byte) iter.Seq2[int, []string] {
+	return func(yield func(int, []string) bool) {
+		for n, line := range strings.Split(string(data), "\n") {
+			line, _, _ = strings.Cut(line, "#")
+			fields := strings.Fields(dictionaryWord(line))
+			if len(fields) > 0 && !yield(n+1, fields) {
+				return
+			}
+		}
+	}
+}
+
+// dictionaryWord - слово в том виде, в каком его ищут в словарях
+func dictionaryWord(word string) string {
+	return strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+}
+
+func (d *Dictionaries) isStopWord(word string) bool {
+	return d != nil && d.stop[dictionaryWord(word)]
+}
+
+func (d *Dictionaries) isProtected(word string) bool {
+	return d != nil && d.protected[dictionaryWord(word)]
+}
+
+func (d *Dictionaries) stem(word string) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+	stem, ok := d.stems[dictionaryWord(word)]
+	return stem, ok
+}
+
+// LoadDictionaries читает словари из файлов. Файла ещё нет - словарь пустой,
+// его можно создать позже, WatchDictionaries подхватит.
+func LoadDictionaries(files DictionaryFiles, analyzers []byte) (*Dictionaries, error) {
+	var contents [3][]byte
+	for i, path := range []string{files.StopWords, files.ProtectedWords, files.StemOverrides} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		contents[i] = data
+	}
+	return ParseDictionaries(contents[0], contents[1], contents[2], analyzers)
+}
+
+// WatchDictionaries раз в ReloadInterval перечитывает словари и передаёт в apply,
+// если они изменились. Словари с ошибкой не применяются, остаются прежние.
+// Нулевой ReloadInterval отключает перечитывание.
+func WatchDictionaries(ctx context.Context, log *slog.Logger, files DictionaryFiles, analyzers []byte, current *Dictionaries, apply func(*Dictionaries)) {
+	if files.ReloadInterval <= 0 {
+		log.Info("dictionaries reload disabled")
+		return
+	}
+	ticker := time.NewTicker(files.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d, err := LoadDictionaries(files, analyzers)
+			if err != nil {
+				log.Error("failed to reload dictionaries", "error", err)
+				continue
+			}
+			if d.Version == current.Version {
+				continue
+			}
+			current = d
+			apply(d)
+		}
+	}
+}
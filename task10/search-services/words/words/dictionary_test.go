@@ -0,0 +1,130 @@
+package words
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDictionaries_Analyzer(t *testing.T) {
+	dict, err := ParseDictionaries(
+		[]byte("# шум\ncomic\n"),
+		[]byte("LaTeX\nthe # вопреки встроенным стоп-словам\n"),
+		[]byte("geese goose\nёжики ёж\n"),
+		nil,
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		phrase string
+		words  []string
+	}{
+		{"extra stop word", "comic strips", []string{"strip"}},
+		{"protected word is not stemmed", "LaTeX documents", []string{"latex", "document"}},
+		{"protected stop word is kept", "the end", []string{"the", "end"}},
+		{"stem override", "geese flying", []string{"goose", "fli"}},
+		{"yo in dictionary", "ежики", []string{"еж"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			words, _ := defaultAnalyzer.Normalize(tc.phrase, LangAuto, dict)
+			assert.Equal(t, tc.words, words)
+		})
+	}
+}
+
+func TestParseDictionaries_Errors(t *testing.T) {
+	_, err := ParseDictionaries([]byte("two words"), nil, nil, nil)
+	assert.ErrorContains(t, err, "stop words: line 1")
+
+	_, err = ParseDictionaries(nil, []byte("ok\n\nnot ok"), nil, nil)
+	assert.ErrorContains(t, err, "protected words: line 3")
+
+	_, err = ParseDictionaries(nil, nil, []byte("geese"), nil)
+	assert.ErrorContains(t, err, "stem overrides: line 1")
+}
+
+func TestParseDictionaries_Version(t *testing.T) {
+	a, err := ParseDictionaries([]byte("foo"), nil, nil, nil)
+	require.NoError(t, err)
+	b, err := ParseDictionaries([]byte("foo"), nil, nil, nil)
+	require.NoError(t, err)
+	c, err := ParseDictionaries(nil, []byte("foo"), nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, a.Version, b.Version)
+	// то же слово в другом словаре - другая версия
+	assert.NotEqual(t, a.Version, c.Version)
+
+	// те же словари при других анализаторах - тоже
+	raw, err := MarshalAnalyzers(map[string]AnalyzerConfig{"raw": {}})
+	require.NoError(t, err)
+	folded, err := MarshalAnalyzers(map[string]AnalyzerConfig{"raw": {Filters: []FilterConfig{{Type: "lowercase"}}}})
+	require.NoError(t, err)
+	d, err := ParseDictionaries([]byte("foo"), nil, nil, raw)
+	require.NoError(t, err)
+	e, err := ParseDictionaries([]byte("foo"), nil, nil, folded)
+	require.NoError(t, err)
+	assert.NotEqual(t, a.Version, d.Version)
+	assert.NotEqual(t, d.Version, e.Version)
+}
+
+func TestWatchDictionaries(t *testing.T) {
+	dir := t.TempDir()
+	files := DictionaryFiles{
+		StopWords:      filepath.Join(dir, "stop.txt"),
+		ProtectedWords: filepath.Join(dir, "protected.txt"), // файла нет - словарь пустой
+		ReloadInterval: 5 * time.Millisecond,
+	}
+	require.NoError(t, os.WriteFile(files.StopWords, []byte("foo\n"), 0o644))
+
+	current, err := LoadDictionaries(files, nil)
+	require.NoError(t, err)
+	assert.True(t, current.isStopWord("foo"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	applied := make(chan *Dictionaries, 1)
+	go WatchDictionaries(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), files, nil, current, func(d *Dictionaries) {
+		applied <- d
+	})
+
+	// словарь с ошибкой не применяется, следующая правка - да
+	require.NoError(t, os.WriteFile(files.StopWords, []byte("foo bar\n"), 0o644))
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, os.WriteFile(files.StopWords, []byte("bar\n"), 0o644))
+
+	select {
+	case d := <-applied:
+		assert.True(t, d.isStopWord("bar"))
+		assert.False(t, d.isStopWord("foo"))
+		assert.NotEqual(t, current.Version, d.Version)
+	case <-ctx.Done():
+		t.Fatal("dictionaries were not reloaded")
+	}
+}
+
+func TestWatchDictionaries_Disabled(t *testing.T) {
+	// нулевой интервал - словари не перечитываются, а не паника тикера
+	done := make(chan struct{})
+	go func() {
+		WatchDictionaries(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), DictionaryFiles{}, nil, &Dictionaries{}, func(*Dictionaries) {
+			t.Error("dictionaries should not be applied")
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not return")
+	}
+}
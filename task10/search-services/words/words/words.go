@@ -1,15 +1,44 @@
 package words
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"unicode"
 
-	"github.com/kljensen/snowball"
 	"github.com/kljensen/snowball/english"
+	"github.com/kljensen/snowball/russian"
 )
 
-var availableCharacters = regexp.MustCompile("[A-Za-z0-9]+")
+// слово - буквы любого алфавита с диакритикой и цифры
+var availableCharacters = regexp.MustCompile(`[\p{L}\p{M}\p{N}]+`)
+
+// Языки нормализации. С auto язык выбирается для каждого слова по алфавиту:
+// кириллица - русский, остальное - английский, как было до поддержки языков.
+const (
+	LangAuto    = "auto"
+	LangEnglish = "english"
+	LangRussian = "russian"
+)
+
+// ParseLanguage проверяет язык запроса, пустой - auto
+func ParseLanguage(lang string) (string, error) {
+	switch lang = strings.ToLower(lang); lang {
+	case "":
+		return LangAuto, nil
+	case LangAuto, LangEnglish, LangRussian:
+		return lang, nil
+	}
+	return "", fmt.Errorf("unknown language %q", lang)
+}
+
+// Token - нормализованное слово, его номер среди всех слов исходной фразы
+// и само слово, как оно было написано
+type Token struct {
+	Word     string
+	Pos      int
+	Original string
+}
 
 func isDigits(s string) bool {
 	for _, r := range s {
@@ -20,46 +49,49 @@ func isDigits(s string) bool {
 	return true
 }
 
-func Normalize(phrase string) []string {
-	if phrase == "" {
-		return []string{}
+func isCyrillic(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
 	}
+	return false
+}
 
-	raw := availableCharacters.FindAllString(phrase, -1)
-
-	if len(raw) == 0 {
-		return []string{}
+// wordLanguage - язык, по правилам которого нормализуется слово
+func wordLanguage(word, lang string) string {
+	if lang != LangAuto {
+		return lang
 	}
+	if isCyrillic(word) {
+		return LangRussian
+	}
+	return LangEnglish
+}
 
-	out := make([]string, 0, len(raw))
-
-	seen := make(map[string]bool)
-
-	for _, word := range raw {
-
-		w := strings.ToLower(word)
-
-		if isDigits(w) {
-			if !seen[w] {
-				out = append(out, w)
-				seen[w] = true
-			}
-			continue
-		}
-
-		if english.IsStopWord(w) {
-			continue
-		}
+func isStopWord(word, lang string) bool {
+	if lang == LangRussian {
+		return russian.IsStopWord(word)
+	}
+	return english.IsStopWord(word)
+}
 
-		stem, err := snowball.Stem(w, "english", true)
-		if err != nil && stem == "" {
-			stem = w
-		}
+// Tokenize - слова фразы по анализатору default без словарей, см. Analyzer.Tokenize
+func Tokenize(phrase, lang string) ([]Token, string) {
+	return defaultAnalyzer.Tokenize(phrase, lang, nil)
+}
 
-		if !seen[stem] {
-			out = append(out, stem)
-			seen[stem] = true
-		}
+func detected(lang string, english, russian int) string {
+	switch {
+	case lang != LangAuto:
+		return lang
+	case russian > english:
+		return LangRussian
 	}
-	return out
+	return LangEnglish
+}
+
+// Normalize - различные нормализованные слова фразы по анализатору default без словарей и её язык
+func Normalize(phrase, lang string) ([]string, string) {
+	return defaultAnalyzer.Normalize(phrase, lang, nil)
 }
@@ -0,0 +1,53 @@
+package words
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize_Languages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		phrase   string
+		lang     string
+		words    []string
+		detected string
+	}{
+		{"english as before", "The running computers, 42!", LangAuto, []string{"run", "comput", "42"}, LangEnglish},
+		{"russian", "Он читает интересные книги", LangAuto, []string{"чита", "интересн", "книг"}, LangRussian},
+		{"yo is e", "Ещё ёлка", LangAuto, []string{"елк"}, LangRussian},
+		{"mixed by word", "ядро linux kernels", LangAuto, []string{"ядр", "linux", "kernel"}, LangEnglish},
+		{"other scripts are kept", "Café naïve Ελλάδα", LangAuto, []string{"café", "naïv", "ελλάδα"}, LangEnglish},
+		{"forced language", "книги", LangEnglish, []string{"книги"}, LangEnglish},
+		{"empty", "", LangRussian, []string{}, LangRussian},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			words, detected := Normalize(tc.phrase, tc.lang)
+			assert.Equal(t, tc.words, words)
+			assert.Equal(t, tc.detected, detected)
+		})
+	}
+}
+
+func TestTokenize_RussianPositions(t *testing.T) {
+	// стоп-слово «и» выкинуто, но позицию занимает
+	tokens, lang := Tokenize("кошки и собаки", LangAuto)
+	assert.Equal(t, LangRussian, lang)
+	assert.Equal(t, []Token{
+		{Word: "кошк", Pos: 0, Original: "кошки"},
+		{Word: "собак", Pos: 2, Original: "собаки"},
+	}, tokens)
+}
+
+func TestParseLanguage(t *testing.T) {
+	for in, want := range map[string]string{"": LangAuto, "AUTO": LangAuto, "russian": LangRussian, "english": LangEnglish} {
+		got, err := ParseLanguage(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseLanguage("klingon")
+	assert.Error(t, err)
+}
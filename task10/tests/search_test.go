@@ -61,8 +61,9 @@ func SearchLimit2(t *testing.T) {
 	require.Equal(t, http.StatusOK, resp.StatusCode, "need OK status")
 	var comics ComicsReply
 	require.NoError(t, json.NewDecoder(resp.Body).Decode(&comics), "decode failed")
-	require.Equal(t, 2, comics.Total)
 	require.Equal(t, 2, len(comics.Comics))
+	// total - все найденные комиксы, не только страница
+	require.GreaterOrEqual(t, comics.Total, 2)
 }
 
 func SearchLimitDefault(t *testing.T) {
@@ -73,8 +74,9 @@ func SearchLimitDefault(t *testing.T) {
 	require.Equal(t, http.StatusOK, resp.StatusCode, "need OK status")
 	var comics ComicsReply
 	require.NoError(t, json.NewDecoder(resp.Body).Decode(&comics), "decode failed")
-	require.Equal(t, 10, comics.Total)
 	require.Equal(t, 10, len(comics.Comics))
+	// total - все найденные комиксы, не только страница
+	require.GreaterOrEqual(t, comics.Total, 10)
 }
 
 func SearchPhrases(t *testing.T) {